@@ -1,10 +1,13 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -18,15 +21,37 @@ type api struct {
 
 // Dependencies contains all the dependencies that the API needs.
 type Dependencies struct {
-	Metadata         MetadataService
-	Products         ProductsService
-	Auth             AuthService
+	Metadata        MetadataService
+	Products        ProductsService
+	Auth            AuthService
+	Users           UsersService
+	Export          ExportService
+	RateLimiter     RateLimiter
+	LoginRateLimits LoginRateLimits
+
+	// ExportRateLimit limits how many bulk exports can be downloaded from each client IP address.
+	ExportRateLimit model.RateLimit
+
+	// ModerationAPIKey gives full moderation access without logging in, meant for emergencies and for appointing the first admin.
+	// If it's empty, the API key can't be used at all.
 	ModerationAPIKey string
+
+	// TrustProxyHeaders makes the API take client IPs from the X-Forwarded-For and X-Real-IP headers.
+	// Only enable it behind a reverse proxy which sets them, otherwise clients can dodge IP rate limits.
+	TrustProxyHeaders bool
+}
+
+// RateLimiter rejects requests over a limit with a model.RateLimitError.
+type RateLimiter interface {
+	Take(c context.Context, key string, limit model.RateLimit) error
 }
 
 // New returns an http.Handler that responds to API requests.
 func New(deps Dependencies) http.Handler {
 	r := chi.NewRouter()
+	if deps.TrustProxyHeaders {
+		r.Use(middleware.RealIP)
+	}
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.StripSlashes)
@@ -34,27 +59,35 @@ func New(deps Dependencies) http.Handler {
 		// AllowedOrigins:   []string{"https://foo.com"}, // Use this to allow specific origin hosts
 		AllowedOrigins: []string{"https://*", "http://*"},
 		// AllowOriginFunc:  func(r *http.Request, origin string) bool { return true },
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: false,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	}))
 
-	auth := newAuthAPI(deps.Auth)
+	auth := newAuthAPI(deps.Auth, deps.Users, deps.RateLimiter, deps.LoginRateLimits)
 	r.Use(auth.addAuthInfoToContext)
 
-	r.Mount("/auth", auth)
+	r.Mount("/auth", auth.r)
 
-	cat := newCategoriesAPI(deps.Metadata)
+	cat := newCategoriesAPI(deps.Metadata, deps.Products)
 	r.Mount("/categories", cat)
 
-	prod := newProductsAPI(deps.Products)
+	schemas := newSchemasAPI(deps.Metadata)
+	r.Mount("/schemas", schemas)
+
+	prod := newProductsAPI(deps.Products, deps.Users)
 	r.Mount("/products", prod)
 
-	mod := newModerationAPI(deps.Products, deps.ModerationAPIKey)
+	mod := newModerationAPI(deps.Products, deps.Users, deps.ModerationAPIKey)
 	r.Mount("/moderation", mod)
 
+	export := newExportAPI(deps.Export, deps.RateLimiter, deps.ExportRateLimit)
+	r.Mount("/export", export.r)
+
+	r.Get("/openapi.json", serveOpenAPIDocument)
+
 	return &api{r}
 }
 
@@ -76,6 +109,11 @@ func errorResponse(w http.ResponseWriter, err error) {
 	if !errors.As(err, &uf) {
 		uf = model.NewInternalServerError(err)
 	}
+
+	var rl model.RateLimitError
+	if errors.As(err, &rl) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(rl.RetryAfter.Seconds()))))
+	}
 	log.Printf("Error: %s", err)
 	jsonResponse(w, uf.HTTPStatusCode, map[string]any{
 		"type":    "error",
@@ -4,30 +4,62 @@ import (
 	"context"
 	"encoding/json"
 	"io"
-	"log"
+	"net"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/mikolysz/enably/model"
 )
 
 type authApi struct {
-	svc AuthService
-	r   *chi.Mux
+	svc         AuthService
+	users       UsersService
+	limiter     RateLimiter
+	loginLimits LoginRateLimits
+	r           *chi.Mux
+}
+
+// LoginRateLimits limit how many login emails can be requested, so that the API can't be used to flood someone's inbox.
+type LoginRateLimits struct {
+	PerEmail model.RateLimit // per recipient address.
+	PerIP    model.RateLimit // per client IP address.
 }
 
 type AuthService interface {
 	SendLoginEmail(c context.Context, email, redirectURI string) error
-	AuthenticateUser(c context.Context, token string) (email string, err error)
+	ExchangeLoginToken(c context.Context, token, userAgent string) (model.Login, error)
+	AuthenticateUser(c context.Context, token string) (model.Session, error)
+	Logout(c context.Context, session model.Session) error
+	ListSessions(c context.Context, current model.Session) ([]model.Session, error)
+	RevokeSession(c context.Context, current model.Session, id int64) error
+	RevokeOtherSessions(c context.Context, current model.Session) error
+}
+
+type UsersService interface {
+	GetUser(c context.Context, email string) (model.User, error)
+	UpdateProfile(c context.Context, email string, update model.ProfileUpdate) (model.User, error)
+	SetRole(c context.Context, by model.Moderator, email string, role model.Role) (model.User, error)
 }
 
-func newAuthAPI(svc AuthService) *authApi {
+func newAuthAPI(svc AuthService, users UsersService, limiter RateLimiter, loginLimits LoginRateLimits) *authApi {
 	a := &authApi{
-		svc: svc,
-		r:   chi.NewRouter(),
+		svc:         svc,
+		users:       users,
+		limiter:     limiter,
+		loginLimits: loginLimits,
+		r:           chi.NewRouter(),
 	}
 
 	a.r.Post("/login", a.SendLoginEmail)
-	a.r.Get("/me", a.GetUserInfo)
+	a.r.Post("/exchange", a.ExchangeLoginToken)
+	a.r.Post("/logout", a.Logout)
+	a.r.Get("/sessions", a.ListSessions)
+	a.r.Delete("/sessions", a.RevokeOtherSessions)
+	a.r.Delete("/sessions/{session_id}", a.RevokeSession)
+	a.r.Get("/me", a.GetProfile)
+	a.r.Patch("/me", a.UpdateProfile)
 	return a
 }
 
@@ -53,6 +85,11 @@ func (a *authApi) SendLoginEmail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := a.checkLoginRateLimits(r, data.Email); err != nil {
+		errorResponse(w, err)
+		return
+	}
+
 	if err := a.svc.SendLoginEmail(r.Context(), data.Email, data.RedirectURI); err != nil {
 		errorResponse(w, err)
 		return
@@ -61,31 +98,219 @@ func (a *authApi) SendLoginEmail(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(w, http.StatusCreated, map[string]string{"status": "ok"})
 }
 
-func (a *authApi) GetUserInfo(w http.ResponseWriter, r *http.Request) {
-	// TODO: implement
+// ExchangeLoginToken starts a session using the token from a login link.
+// The returned session token is used to authenticate further requests, the login token can't be used again.
+// The response also says where to send the user, as requested when the login link was sent,
+// and whether they still need to choose a display name first.
+func (a *authApi) ExchangeLoginToken(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		Token string `json:"token"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil || data.Token == "" {
+		errorResponse(w, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: "the request must be a JSON object containing the login token",
+		})
+		return
+	}
+
+	login, err := a.svc.ExchangeLoginToken(r.Context(), data.Token, r.UserAgent())
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, struct {
+		Token           string    `json:"token"`
+		ExpiresAt       time.Time `json:"expires_at"`
+		RedirectURI     string    `json:"redirect_uri"`
+		NeedsOnboarding bool      `json:"needs_onboarding"`
+	}{login.Session.Token, login.Session.ExpiresAt, login.RedirectURI, login.User.NeedsOnboarding()})
+}
+
+// checkLoginRateLimits counts a login email request towards the limits for the client's IP and the recipient address.
+func (a *authApi) checkLoginRateLimits(r *http.Request, emailAddress string) error {
+	if err := a.limiter.Take(r.Context(), "login:ip:"+clientIP(r), a.loginLimits.PerIP); err != nil {
+		return err
+	}
+
+	// Invalid addresses are rejected when sending the email, so there's nothing to count them towards.
+	emailAddress, err := model.NormalizeEmail(emailAddress)
+	if err != nil {
+		return nil
+	}
+	return a.limiter.Take(r.Context(), "login:email:"+emailAddress, a.loginLimits.PerEmail)
+}
+
+// clientIP returns the IP address the request came from, which may be taken from proxy headers, see Dependencies.TrustProxyHeaders.
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		// RealIP sets RemoteAddr without a port.
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// Logout ends the session used to make the request.
+func (a *authApi) Logout(w http.ResponseWriter, r *http.Request) {
+	session, ok := requireSession(w, r)
+	if !ok {
+		return
+	}
+
+	if err := a.svc.Logout(r.Context(), session); err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// ListSessions lists the active sessions of the logged-in user.
+func (a *authApi) ListSessions(w http.ResponseWriter, r *http.Request) {
+	session, ok := requireSession(w, r)
+	if !ok {
+		return
+	}
+
+	sessions, err := a.svc.ListSessions(r.Context(), session)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, sessions)
+}
+
+// RevokeSession ends one of the logged-in user's sessions.
+func (a *authApi) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := requireSession(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "session_id"), 10, 64)
+	if err != nil {
+		errorResponse(w, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: "invalid session ID",
+		})
+		return
+	}
+
+	if err := a.svc.RevokeSession(r.Context(), session, id); err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// RevokeOtherSessions ends all of the logged-in user's sessions, except the one used to make the request.
+func (a *authApi) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	session, ok := requireSession(w, r)
+	if !ok {
+		return
+	}
+
+	if err := a.svc.RevokeOtherSessions(r.Context(), session); err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// userResponse is how users are returned from the API.
+type userResponse struct {
+	model.User
+	NeedsOnboarding bool `json:"needs_onboarding"`
+}
+
+// GetProfile returns the account of the logged-in user.
+func (a *authApi) GetProfile(w http.ResponseWriter, r *http.Request) {
+	session, ok := requireSession(w, r)
+	if !ok {
+		return
+	}
+
+	u, err := a.users.GetUser(r.Context(), session.Email)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, userResponse{u, u.NeedsOnboarding()})
+}
+
+// UpdateProfile changes the display name or email preferences of the logged-in user.
+// New users call it to finish onboarding.
+func (a *authApi) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	session, ok := requireSession(w, r)
+	if !ok {
+		return
+	}
+
+	var update model.ProfileUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		errorResponse(w, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: "the request must be a JSON object containing the fields to change",
+			SecretMessage:     err.Error(),
+		})
+		return
+	}
+
+	u, err := a.users.UpdateProfile(r.Context(), session.Email, update)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, userResponse{u, u.NeedsOnboarding()})
 }
 
 // middleware
 
-type emailContextKey string
+type sessionContextKey struct{}
+
+// sessionFromContext returns the session the request was authenticated with, if any.
+func sessionFromContext(c context.Context) (model.Session, bool) {
+	session, ok := c.Value(sessionContextKey{}).(model.Session)
+	return session, ok
+}
+
+// requireSession returns the session the request was authenticated with.
+// If the request isn't authenticated, it responds with an error and returns false.
+func requireSession(w http.ResponseWriter, r *http.Request) (model.Session, bool) {
+	session, ok := sessionFromContext(r.Context())
+	if !ok {
+		errorResponse(w, model.UserFacingError{
+			HTTPStatusCode:    http.StatusUnauthorized,
+			UserFacingMessage: "you need to log in first",
+		})
+	}
+	return session, ok
+}
 
 func (a *authApi) addAuthInfoToContext(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		Header := r.Header.Get("Authorization")
-		log.Printf(Header)
 		if len(Header) < 8 || Header[:7] != "Bearer " {
 			next.ServeHTTP(w, r)
 			return
 		}
 
 		token := Header[7:]
-		email, err := a.svc.AuthenticateUser(r.Context(), token)
+		session, err := a.svc.AuthenticateUser(r.Context(), token)
 		if err != nil {
 			errorResponse(w, err)
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), emailContextKey("email"), email)
+		ctx := context.WithValue(r.Context(), sessionContextKey{}, session)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
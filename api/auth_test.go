@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mikolysz/enably/app"
+	"github.com/mikolysz/enably/model"
+	"github.com/mikolysz/enably/store"
+)
+
+// testAuthService sends login emails to nobody. Its other methods aren't implemented.
+type testAuthService struct {
+	AuthService
+}
+
+func (testAuthService) SendLoginEmail(c context.Context, email, redirectURI string) error {
+	_, err := model.NormalizeEmail(email)
+	return err
+}
+
+func TestLoginRateLimits(t *testing.T) {
+	type request struct {
+		ip, email string
+		wantCode  int
+	}
+	tests := []struct {
+		name     string
+		requests []request
+	}{
+		{"per address", []request{
+			{"192.0.2.1", "user@example.com", http.StatusCreated},
+			{"192.0.2.2", "user@example.com", http.StatusCreated},
+			{"192.0.2.3", " User@Example.com", http.StatusTooManyRequests},
+			{"192.0.2.3", "other@example.com", http.StatusCreated},
+		}},
+		{"per IP", []request{
+			{"192.0.2.1", "first@example.com", http.StatusCreated},
+			{"192.0.2.1", "second@example.com", http.StatusCreated},
+			{"192.0.2.1", "third@example.com", http.StatusCreated},
+			{"192.0.2.1", "fourth@example.com", http.StatusTooManyRequests},
+			{"192.0.2.2", "fourth@example.com", http.StatusCreated},
+		}},
+
+		// Addresses are only normalized after the IP limit is checked, so invalid ones count towards it too.
+		{"per IP with invalid addresses", []request{
+			{"192.0.2.1", "not an address", http.StatusBadRequest},
+			{"192.0.2.1", "", http.StatusBadRequest},
+			{"192.0.2.1", "@", http.StatusBadRequest},
+			{"192.0.2.1", "user@example.com", http.StatusTooManyRequests},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New(Dependencies{
+				Auth:        testAuthService{},
+				RateLimiter: app.NewRateLimiter(store.NewMemoryRateLimitStore()),
+				LoginRateLimits: LoginRateLimits{
+					PerEmail: model.RateLimit{Requests: 2, Window: time.Hour},
+					PerIP:    model.RateLimit{Requests: 3, Window: time.Hour},
+				},
+			})
+
+			for i, req := range tt.requests {
+				body := strings.NewReader(`{"email": "` + req.email + `"}`)
+				r := httptest.NewRequest(http.MethodPost, "/auth/login", body)
+				r.RemoteAddr = req.ip + ":1234"
+				w := httptest.NewRecorder()
+				a.ServeHTTP(w, r)
+
+				if w.Code != req.wantCode {
+					t.Fatalf("request %d, for %q from %s, returned %d, want %d", i, req.email, req.ip, w.Code, req.wantCode)
+				}
+				if w.Code != http.StatusTooManyRequests {
+					continue
+				}
+				retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+				if err != nil || retryAfter <= 0 || retryAfter > int(time.Hour.Seconds()) {
+					t.Errorf("request %d returned Retry-After %q, want the seconds until the limit resets", i, w.Header().Get("Retry-After"))
+				}
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/mikolysz/enably/model"
@@ -10,21 +11,29 @@ import (
 type MetadataService interface {
 	GetRootCategory() *model.Category
 	GetCategory(slug string) (*model.Category, error)
-	GetSchemasForCategory(category *model.Category) (map[string]any, error)
+	GetCategoryPath(slug string) ([]*model.Breadcrumb, error)
+	GetSchemasForCategory(category *model.Category) (*model.OrderedMap, error)
+	GetUISchemaForCategory(category *model.Category) (*model.OrderedMap, error)
+	GetFieldsetSchema(slug string) (map[string]any, error)
+	GetProductSchema(categorySlug string) (map[string]any, error)
 }
 
 type categoriesAPI struct {
 	*chi.Mux
-	meta MetadataService
+	meta     MetadataService
+	products ProductsService
 }
 
-func newCategoriesAPI(metadata MetadataService) *categoriesAPI {
+func newCategoriesAPI(metadata MetadataService, products ProductsService) *categoriesAPI {
 	r := chi.NewRouter()
-	c := &categoriesAPI{r, metadata}
+	c := &categoriesAPI{r, metadata, products}
 
 	r.Get("/", c.getRootCategory)
+	r.Get("/tree", c.GetCategoryTree)
 	r.Get("/{slug}", c.GetCategory)
+	r.Get("/{slug}/path", c.GetCategoryPath)
 	r.Get("/{slug}/schemas", c.GetSchemasForCategory)
+	r.Get("/{slug}/ui-schema", c.GetUISchemaForCategory)
 
 	return c
 }
@@ -35,6 +44,31 @@ func (c *categoriesAPI) getRootCategory(w http.ResponseWriter, r *http.Request)
 	jsonResponse(w, http.StatusOK, cat)
 }
 
+// GetCategoryTree returns all categories along with their product counts.
+// Pending products are counted too if the include_pending query parameter is true.
+func (c *categoriesAPI) GetCategoryTree(w http.ResponseWriter, r *http.Request) {
+	includePending := false
+	if v := r.URL.Query().Get("include_pending"); v != "" {
+		var err error
+		includePending, err = strconv.ParseBool(v)
+		if err != nil {
+			errorResponse(w, model.UserFacingError{
+				HTTPStatusCode:    http.StatusBadRequest,
+				UserFacingMessage: "include_pending must be true or false",
+			})
+			return
+		}
+	}
+
+	tree, err := c.products.GetCategoryTree(includePending)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, tree)
+}
+
 func (c *categoriesAPI) GetCategory(w http.ResponseWriter, r *http.Request) {
 	slug := chi.URLParam(r, "slug")
 	cat, err := c.meta.GetCategory(slug)
@@ -46,6 +80,17 @@ func (c *categoriesAPI) GetCategory(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(w, http.StatusOK, cat)
 }
 
+func (c *categoriesAPI) GetCategoryPath(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+	path, err := c.meta.GetCategoryPath(slug)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, path)
+}
+
 func (c *categoriesAPI) GetSchemasForCategory(w http.ResponseWriter, r *http.Request) {
 	slug := chi.URLParam(r, "slug")
 	cat, err := c.meta.GetCategory(slug)
@@ -60,3 +105,18 @@ func (c *categoriesAPI) GetSchemasForCategory(w http.ResponseWriter, r *http.Req
 	}
 	jsonResponse(w, http.StatusOK, schemas)
 }
+
+func (c *categoriesAPI) GetUISchemaForCategory(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+	cat, err := c.meta.GetCategory(slug)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+	uiSchema, err := c.meta.GetUISchemaForCategory(cat)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+	jsonResponse(w, http.StatusOK, uiSchema)
+}
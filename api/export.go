@@ -0,0 +1,122 @@
+package api
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mikolysz/enably/model"
+)
+
+// ExportService exports all approved products in bulk.
+type ExportService interface {
+	GetExportMetadata() model.ExportMetadata
+	ExportJSONLines(w io.Writer) error
+	ExportCSV(categorySlug string, w io.Writer) error
+	OpenSQLiteExport() (io.ReadSeekCloser, time.Time, error)
+}
+
+type exportAPI struct {
+	svc     ExportService
+	limiter RateLimiter
+	limit   model.RateLimit // downloads per client IP.
+	r       *chi.Mux
+}
+
+func newExportAPI(svc ExportService, limiter RateLimiter, limit model.RateLimit) *exportAPI {
+	e := &exportAPI{
+		svc:     svc,
+		limiter: limiter,
+		limit:   limit,
+		r:       chi.NewRouter(),
+	}
+
+	e.r.Get("/metadata", e.GetExportMetadata)
+	e.r.Group(func(r chi.Router) {
+		r.Use(e.limitDownloads)
+		r.Get("/products.jsonl", e.ExportJSONLines)
+		r.Get("/categories/{category_slug}/products.csv", e.ExportCSV)
+		r.Get("/enably.sqlite", e.ExportSQLite)
+	})
+
+	return e
+}
+
+func (e *exportAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.r.ServeHTTP(w, r)
+}
+
+// limitDownloads counts a download towards the limit for the client's IP, since every export reads all the products.
+func (e *exportAPI) limitDownloads(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := e.limiter.Take(r.Context(), "export:ip:"+clientIP(r), e.limit); err != nil {
+			errorResponse(w, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (e *exportAPI) GetExportMetadata(w http.ResponseWriter, r *http.Request) {
+	jsonResponse(w, http.StatusOK, e.svc.GetExportMetadata())
+}
+
+func (e *exportAPI) ExportJSONLines(w http.ResponseWriter, r *http.Request) {
+	startDownload(w, "application/x-ndjson", "enably.jsonl")
+	if err := e.svc.ExportJSONLines(w); err != nil {
+		// The response has already started, so all we can do is to cut it short.
+		log.Printf("Error when exporting JSON Lines: %s", err)
+	}
+}
+
+func (e *exportAPI) ExportCSV(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "category_slug")
+
+	// Errors like nonexistent categories happen before anything is written, so we buffer until the first write.
+	dw := &deferredDownload{w: w, contentType: "text/csv; charset=utf-8", filename: slug + ".csv"}
+	if err := e.svc.ExportCSV(slug, dw); err != nil {
+		if !dw.started {
+			errorResponse(w, err)
+			return
+		}
+		log.Printf("Error when exporting CSV: %s", err)
+	}
+}
+
+// ExportSQLite serves the latest SQLite export, which is built periodically rather than for each request.
+func (e *exportAPI) ExportSQLite(w http.ResponseWriter, r *http.Request) {
+	f, generatedAt, err := e.svc.OpenSQLiteExport()
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+	defer f.Close()
+
+	startDownload(w, "application/vnd.sqlite3", "enably.sqlite")
+	http.ServeContent(w, r, "enably.sqlite", generatedAt, f)
+}
+
+// startDownload sets the headers that make browsers save the response as a file.
+func startDownload(w http.ResponseWriter, contentType, filename string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+}
+
+// deferredDownload only sets the download headers when the first byte of the file is written,
+// so that errors which happen earlier can still be reported as JSON.
+type deferredDownload struct {
+	w           http.ResponseWriter
+	contentType string
+	filename    string
+	started     bool
+}
+
+func (d *deferredDownload) Write(p []byte) (int, error) {
+	if !d.started {
+		startDownload(d.w, d.contentType, d.filename)
+		d.started = true
+	}
+	return d.w.Write(p)
+}
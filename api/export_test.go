@@ -0,0 +1,69 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mikolysz/enably/app"
+	"github.com/mikolysz/enably/model"
+	"github.com/mikolysz/enably/store"
+)
+
+// testExportService exports a single line of text in every format.
+type testExportService struct{}
+
+func (testExportService) GetExportMetadata() model.ExportMetadata { return model.ExportMetadata{} }
+
+func (testExportService) ExportJSONLines(w io.Writer) error {
+	_, err := io.WriteString(w, "{}\n")
+	return err
+}
+
+func (testExportService) ExportCSV(categorySlug string, w io.Writer) error {
+	_, err := io.WriteString(w, "id\n")
+	return err
+}
+
+func (testExportService) OpenSQLiteExport() (io.ReadSeekCloser, time.Time, error) {
+	return nopCloser{bytes.NewReader([]byte("SQLite format 3\x00"))}, time.Now(), nil
+}
+
+type nopCloser struct {
+	io.ReadSeeker
+}
+
+func (nopCloser) Close() error { return nil }
+
+func TestExportRateLimit(t *testing.T) {
+	a := New(Dependencies{
+		Export:          testExportService{},
+		RateLimiter:     app.NewRateLimiter(store.NewMemoryRateLimitStore()),
+		ExportRateLimit: model.RateLimit{Requests: 3, Window: time.Hour},
+	})
+
+	requests := []struct {
+		ip, path string
+		wantCode int
+	}{
+		{"192.0.2.1", "/export/products.jsonl", http.StatusOK},
+		{"192.0.2.1", "/export/categories/games/products.csv", http.StatusOK},
+		{"192.0.2.1", "/export/enably.sqlite", http.StatusOK},
+		{"192.0.2.1", "/export/enably.sqlite", http.StatusTooManyRequests},
+		{"192.0.2.1", "/export/metadata", http.StatusOK},
+		{"192.0.2.2", "/export/enably.sqlite", http.StatusOK},
+	}
+	for i, req := range requests {
+		r := httptest.NewRequest(http.MethodGet, req.path, nil)
+		r.RemoteAddr = req.ip + ":1234"
+		w := httptest.NewRecorder()
+		a.ServeHTTP(w, r)
+
+		if w.Code != req.wantCode {
+			t.Errorf("request %d, for %s from %s, returned %d, want %d", i, req.path, req.ip, w.Code, req.wantCode)
+		}
+	}
+}
@@ -1,7 +1,15 @@
 package api
 
 import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
@@ -10,87 +18,571 @@ import (
 
 type moderationAPI struct {
 	svc    ProductsService
+	users  UsersService
 	apiKey string
 	r      *chi.Mux
 }
 
 // NewModerationAPI returns a new ModerationAPI.
-func newModerationAPI(svc ProductsService, apiKey string) http.Handler {
+func newModerationAPI(svc ProductsService, users UsersService, apiKey string) http.Handler {
 	a := &moderationAPI{
 		svc:    svc,
+		users:  users,
 		apiKey: apiKey,
 		r:      chi.NewRouter(),
 	}
 
-	a.r.Use(a.checkAPIKey)
-	a.r.Get("/pending", a.GetPendingProducts)
+	a.r.Use(a.authorize)
+	a.r.Get("/pending", a.GetModerationQueue)
+	a.r.Post("/products/bulk", a.BulkModerate)
+	a.r.Get("/products/{product_id}", a.GetProduct)
+	a.r.Post("/products/{product_id}/claim", a.ClaimProduct)
+	a.r.Delete("/products/{product_id}/claim", a.ReleaseProductClaim)
+	a.r.Get("/products/{product_id}/notes", a.GetModeratorNotes)
+	a.r.Post("/products/{product_id}/notes", a.AddModeratorNote)
 	a.r.Post("/products/{product_id}/approve", a.ApproveProduct)
+	a.r.Get("/products/{product_id}/edits", a.GetModeratorEdits)
 	a.r.Post("/products/{product_id}/reject", a.RejectProduct)
+	a.r.Post("/products/{product_id}/request-changes", a.RequestProductChanges)
+	a.r.Post("/products/{product_id}/discontinue", a.DiscontinueProduct)
+	a.r.Get("/products/{product_id}/comments", a.GetProductComments)
+	a.r.Post("/products/{product_id}/comments", a.AddProductComment)
+	a.r.Get("/rejection-reasons", a.GetRejectionReasons)
+	a.r.Post("/import/{category_slug}", a.ImportProducts)
+	a.r.Get("/pending-translations", a.GetPendingTranslations)
+	a.r.Post("/translations/{translation_id}/approve", a.ApproveTranslation)
+	a.r.Post("/translations/{translation_id}/reject", a.RejectTranslation)
+	a.r.Get("/actions", a.GetModerationActions)
+	a.r.Put("/users/{email}/role", a.SetUserRole)
 	return a.r
 }
 
-// checkAPIKey is a middleware that returns 403 if the moderation API key is wrong or missing.
-func (a *moderationAPI) checkAPIKey(next http.Handler) http.Handler {
+// moderatorContextKey is the key of the moderatorInfo in the request context.
+type moderatorContextKey struct{}
+
+// moderatorInfo identifies who is making a moderation request, and what they're allowed to do.
+type moderatorInfo struct {
+	moderator model.Moderator
+	role      model.Role
+}
+
+// moderatorFromContext returns the moderator making the request. authorize makes sure there is one.
+func moderatorFromContext(c context.Context) moderatorInfo {
+	info, _ := c.Value(moderatorContextKey{}).(moderatorInfo)
+	return info
+}
+
+// authorize is a middleware that only lets moderators and admins through, based on the session the request was made with.
+// The moderation API key, if configured, can be used instead of a session and grants admin access.
+func (a *moderationAPI) authorize(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		key := r.Header.Get("X-Moderation-API-Key")
-		if key == "" {
+		var info moderatorInfo
+
+		if key := r.Header.Get("X-Moderation-API-Key"); key != "" {
+			if a.apiKey == "" || subtle.ConstantTimeCompare([]byte(key), []byte(a.apiKey)) != 1 {
+				errorResponse(w, model.UserFacingError{
+					HTTPStatusCode:    http.StatusForbidden,
+					UserFacingMessage: "wrong moderation API key",
+				})
+				return
+			}
+
+			// The key is only meant for emergencies, so every use is worth knowing about.
+			log.Printf("Moderation API key used for %s %s", r.Method, r.URL.Path)
+			info = moderatorInfo{role: model.RoleAdmin}
+		} else {
+			session, ok := requireSession(w, r)
+			if !ok {
+				return
+			}
+
+			u, err := a.users.GetUser(r.Context(), session.Email)
+			if err != nil {
+				errorResponse(w, err)
+				return
+			}
+
+			if !u.Role.CanModerate() {
+				errorResponse(w, model.UserFacingError{
+					HTTPStatusCode:    http.StatusForbidden,
+					UserFacingMessage: "only moderators can do this",
+				})
+				return
+			}
+			info = moderatorInfo{moderator: model.Moderator{UserID: u.ID}, role: u.Role}
+		}
+
+		ctx := context.WithValue(r.Context(), moderatorContextKey{}, info)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetModerationQueue lists the submissions waiting for a moderator's decision.
+// They can be filtered by category and submission type, sorted by age and paginated with query parameters.
+func (a *moderationAPI) GetModerationQueue(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := model.QueueQuery{SubmissionType: query.Get("type")}
+
+	switch query.Get("sort") {
+	case "", "oldest":
+	case "newest":
+		q.NewestFirst = true
+	default:
+		errorResponse(w, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: "sort must be oldest or newest",
+		})
+		return
+	}
+
+	for name, dest := range map[string]*int{"limit": &q.Limit, "offset": &q.Offset} {
+		v := query.Get(name)
+		if v == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(v)
+		if err != nil {
 			errorResponse(w, model.UserFacingError{
-				HTTPStatusCode:    http.StatusForbidden,
-				UserFacingMessage: "missing moderation API key.\n\nPlease provide the X-Moderation-API-Key header",
+				HTTPStatusCode:    http.StatusBadRequest,
+				UserFacingMessage: name + " must be a number",
 			})
 			return
 		}
-		if key != a.apiKey {
+		*dest = n
+	}
+
+	page, err := a.svc.GetModerationQueue(query.Get("category"), q)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	// If the page is empty, we want an empty array, not null.
+	if page.Items == nil {
+		page.Items = []model.QueueItem{}
+	}
+	jsonResponse(w, http.StatusOK, page)
+}
+
+// GetProduct returns a product whatever its status, unlike the public endpoint.
+func (a *moderationAPI) GetProduct(w http.ResponseWriter, r *http.Request) {
+	id, ok := productID(w, r)
+	if !ok {
+		return
+	}
+
+	prod, err := a.svc.GetProductForModeration(id)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+	jsonResponse(w, http.StatusOK, prod)
+}
+
+// ClaimProduct marks a product as being reviewed by the moderator making the request.
+func (a *moderationAPI) ClaimProduct(w http.ResponseWriter, r *http.Request) {
+	id, ok := productID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := a.svc.ClaimProduct(id, moderatorFromContext(r.Context()).moderator); err != nil {
+		errorResponse(w, err)
+		return
+	}
+}
+
+// ReleaseProductClaim lets other moderators review a product claimed by the moderator making the request.
+func (a *moderationAPI) ReleaseProductClaim(w http.ResponseWriter, r *http.Request) {
+	id, ok := productID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := a.svc.ReleaseProductClaim(id, moderatorFromContext(r.Context()).moderator); err != nil {
+		errorResponse(w, err)
+		return
+	}
+}
+
+// GetModeratorNotes returns the internal notes about a product.
+func (a *moderationAPI) GetModeratorNotes(w http.ResponseWriter, r *http.Request) {
+	id, ok := productID(w, r)
+	if !ok {
+		return
+	}
+
+	notes, err := a.svc.GetModeratorNotes(id)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	// If there are no notes, we want an empty array, not null.
+	if notes == nil {
+		notes = []model.ModeratorNote{}
+	}
+	jsonResponse(w, http.StatusOK, notes)
+}
+
+// AddModeratorNote adds an internal note about a product, which the submitter doesn't see.
+func (a *moderationAPI) AddModeratorNote(w http.ResponseWriter, r *http.Request) {
+	id, ok := productID(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		errorResponse(w, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: "the request must be a JSON object containing the text of the note",
+			SecretMessage:     err.Error(),
+		})
+		return
+	}
+
+	n, err := a.svc.AddModeratorNote(id, body.Text, moderatorFromContext(r.Context()).moderator)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+	jsonResponse(w, http.StatusCreated, n)
+}
+
+// BulkModerate approves or rejects several products at once, and reports the outcome for each of them.
+func (a *moderationAPI) BulkModerate(w http.ResponseWriter, r *http.Request) {
+	var action model.BulkAction
+	if err := json.NewDecoder(r.Body).Decode(&action); err != nil {
+		errorResponse(w, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: "the request must be a JSON object containing the action and the product IDs",
+			SecretMessage:     err.Error(),
+		})
+		return
+	}
+
+	results, err := a.svc.BulkModerate(action, moderatorFromContext(r.Context()).moderator)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+	jsonResponse(w, http.StatusOK, results)
+}
+
+func (a *moderationAPI) ApproveProduct(w http.ResponseWriter, r *http.Request) {
+	id, ok := productID(w, r)
+	if !ok {
+		return
+	}
+
+	// The body is optional, moderators only send one when they want to change something before approving.
+	var approval model.Approval
+	if err := json.NewDecoder(r.Body).Decode(&approval); err != nil && !errors.Is(err, io.EOF) {
+		errorResponse(w, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: "the request must be empty or a JSON object containing the changes to make before approving",
+			SecretMessage:     err.Error(),
+		})
+		return
+	}
+
+	if err := a.svc.ApproveProduct(id, approval, moderatorFromContext(r.Context()).moderator); err != nil {
+		errorResponse(w, err)
+		return
+	}
+}
+
+// GetModeratorEdits lists the changes moderators made to a product when approving it.
+func (a *moderationAPI) GetModeratorEdits(w http.ResponseWriter, r *http.Request) {
+	id, ok := productID(w, r)
+	if !ok {
+		return
+	}
+
+	edits, err := a.svc.GetModeratorEdits(id)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	// If there are no edits, we want an empty array, not null.
+	if edits == nil {
+		edits = []model.ModeratorEdit{}
+	}
+	jsonResponse(w, http.StatusOK, edits)
+}
+
+func (a *moderationAPI) RejectProduct(w http.ResponseWriter, r *http.Request) {
+	id, ok := productID(w, r)
+	if !ok {
+		return
+	}
+
+	var rejection model.Rejection
+	if err := json.NewDecoder(r.Body).Decode(&rejection); err != nil {
+		errorResponse(w, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: "the request must be a JSON object containing the reason for the rejection",
+			SecretMessage:     err.Error(),
+		})
+		return
+	}
+
+	if err := a.svc.RejectProduct(id, rejection, moderatorFromContext(r.Context()).moderator); err != nil {
+		errorResponse(w, err)
+		return
+	}
+}
+
+// RequestProductChanges sends a pending product back to its submitter with comments on what to change.
+func (a *moderationAPI) RequestProductChanges(w http.ResponseWriter, r *http.Request) {
+	id, ok := productID(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.ChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errorResponse(w, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: "the request must be a JSON object containing a message or field comments",
+			SecretMessage:     err.Error(),
+		})
+		return
+	}
+
+	if err := a.svc.RequestProductChanges(id, req, moderatorFromContext(r.Context()).moderator); err != nil {
+		errorResponse(w, err)
+		return
+	}
+}
+
+// DiscontinueProduct marks a published product as no longer being made or sold.
+func (a *moderationAPI) DiscontinueProduct(w http.ResponseWriter, r *http.Request) {
+	id, ok := productID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := a.svc.DiscontinueProduct(id, moderatorFromContext(r.Context()).moderator); err != nil {
+		errorResponse(w, err)
+		return
+	}
+}
+
+// GetProductComments returns the conversation between the moderators and the submitter of a product.
+func (a *moderationAPI) GetProductComments(w http.ResponseWriter, r *http.Request) {
+	id, ok := productID(w, r)
+	if !ok {
+		return
+	}
+
+	comments, err := a.svc.GetProductComments(id)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	// If there are no comments, we want an empty array, not null.
+	if comments == nil {
+		comments = []model.Comment{}
+	}
+
+	jsonResponse(w, http.StatusOK, comments)
+}
+
+// AddProductComment adds a moderator's comment to the conversation about a product.
+func (a *moderationAPI) AddProductComment(w http.ResponseWriter, r *http.Request) {
+	id, ok := productID(w, r)
+	if !ok {
+		return
+	}
+
+	fc, ok := decodeComment(w, r)
+	if !ok {
+		return
+	}
+
+	cm, err := a.svc.AddModeratorComment(id, fc, moderatorFromContext(r.Context()).moderator)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusCreated, cm)
+}
+
+// productID returns the product ID from the URL.
+// If ok is false, an error response has already been written.
+func productID(w http.ResponseWriter, r *http.Request) (id int, ok bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "product_id"))
+	if err != nil {
+		errorResponse(w, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: "invalid product ID",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// GetRejectionReasons lists the canned reasons moderators can pick when rejecting a product.
+func (a *moderationAPI) GetRejectionReasons(w http.ResponseWriter, r *http.Request) {
+	jsonResponse(w, http.StatusOK, model.CannedRejectionReasons)
+}
+
+// maxImportSize is the largest file that can be imported at once.
+const maxImportSize = 32 << 20
+
+// importFormats maps the media types of import files to the formats ProductsService understands.
+var importFormats = map[string]string{
+	"text/csv":             "csv",
+	"application/jsonl":    "jsonl",
+	"application/x-ndjson": "jsonl",
+}
+
+// ImportProducts imports products from the request body.
+// The format is given by the format query parameter, or by the Content-Type header if the parameter is missing.
+// If the dry_run query parameter is true, the products are only validated.
+func (a *moderationAPI) ImportProducts(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		format = importFormats[mediaType]
+	}
+
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(v)
+		if err != nil {
 			errorResponse(w, model.UserFacingError{
-				HTTPStatusCode:    http.StatusForbidden,
-				UserFacingMessage: "wrong moderation API key",
+				HTTPStatusCode:    http.StatusBadRequest,
+				UserFacingMessage: "dry_run must be true or false",
 			})
 			return
 		}
-		next.ServeHTTP(w, r)
-	})
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxImportSize)
+	res, err := a.svc.ImportProducts(chi.URLParam(r, "category_slug"), format, body, dryRun)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	status := http.StatusCreated
+	if dryRun {
+		status = http.StatusOK
+	}
+	jsonResponse(w, status, res)
 }
 
-func (a *moderationAPI) GetPendingProducts(w http.ResponseWriter, r *http.Request) {
-	prods, err := a.svc.GetProductsNeedingApproval()
+func (a *moderationAPI) GetPendingTranslations(w http.ResponseWriter, r *http.Request) {
+	ts, err := a.svc.GetTranslationsNeedingApproval()
 	if err != nil {
 		errorResponse(w, err)
 		return
 	}
 
-	jsonResponse(w, http.StatusOK, prods)
+	// If there are no translations, we want an empty array, not null.
+	if ts == nil {
+		ts = []model.Translation{}
+	}
+
+	jsonResponse(w, http.StatusOK, ts)
 }
 
-func (a *moderationAPI) ApproveProduct(w http.ResponseWriter, r *http.Request) {
-	idStr := chi.URLParam(r, "product_id")
-	id, err := strconv.Atoi(idStr)
+func (a *moderationAPI) ApproveTranslation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "translation_id"))
 	if err != nil {
 		errorResponse(w, model.UserFacingError{
 			HTTPStatusCode:    http.StatusBadRequest,
-			UserFacingMessage: "invalid product ID",
+			UserFacingMessage: "invalid translation ID",
 		})
 		return
 	}
 
-	if err := a.svc.ApproveProduct(id); err != nil {
+	if err := a.svc.ApproveTranslation(id, moderatorFromContext(r.Context()).moderator); err != nil {
 		errorResponse(w, err)
 		return
 	}
 }
 
-func (a *moderationAPI) RejectProduct(w http.ResponseWriter, r *http.Request) {
-	idStr := chi.URLParam(r, "product_id")
-	id, err := strconv.Atoi(idStr)
+func (a *moderationAPI) RejectTranslation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "translation_id"))
 	if err != nil {
 		errorResponse(w, model.UserFacingError{
 			HTTPStatusCode:    http.StatusBadRequest,
-			UserFacingMessage: "invalid product ID",
+			UserFacingMessage: "invalid translation ID",
+		})
+		return
+	}
+
+	if err := a.svc.RejectTranslation(id, moderatorFromContext(r.Context()).moderator); err != nil {
+		errorResponse(w, err)
+		return
+	}
+}
+
+// GetModerationActions lists the most recent moderation decisions, and who made them.
+func (a *moderationAPI) GetModerationActions(w http.ResponseWriter, r *http.Request) {
+	actions, err := a.svc.GetModerationActions()
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	if actions == nil {
+		actions = []model.ModerationAction{}
+	}
+
+	jsonResponse(w, http.StatusOK, actions)
+}
+
+// SetUserRole changes the role of a user, e.g. to make them a moderator. Only admins can do this.
+func (a *moderationAPI) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	info := moderatorFromContext(r.Context())
+	if info.role != model.RoleAdmin {
+		errorResponse(w, model.UserFacingError{
+			HTTPStatusCode:    http.StatusForbidden,
+			UserFacingMessage: "only admins can change roles",
 		})
 		return
 	}
 
-	if err := a.svc.RejectProduct(id); err != nil {
+	var data struct {
+		Role model.Role `json:"role"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		errorResponse(w, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: "the request must be a JSON object containing the new role",
+			SecretMessage:     err.Error(),
+		})
+		return
+	}
+
+	// chi doesn't unescape URL parameters if the path contains escaped characters, e.g. "%40" instead of "@".
+	email, err := url.PathUnescape(chi.URLParam(r, "email"))
+	if err != nil {
+		errorResponse(w, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: "invalid email address",
+		})
+		return
+	}
+
+	u, err := a.users.SetRole(r.Context(), info.moderator, email, data.Role)
+	if err != nil {
 		errorResponse(w, err)
 		return
 	}
+
+	jsonResponse(w, http.StatusOK, userResponse{u, u.NeedsOnboarding()})
 }
@@ -0,0 +1,80 @@
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// openAPIDocument describes every route of the API in the OpenAPI 3 format.
+// It is written by hand, and checked against the router by TestOpenAPIDocument.
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+func serveOpenAPIDocument(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPIDocument)
+}
+
+// pathParam matches path parameters, which use the same syntax in chi and OpenAPI.
+var pathParam = regexp.MustCompile(`\{[^}]*\}`)
+
+// routeKey identifies a route regardless of the names of its path parameters and of trailing slashes.
+func routeKey(method, path string) string {
+	path = pathParam.ReplaceAllString(path, "{}")
+	if path != "/" {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return strings.ToUpper(method) + " " + path
+}
+
+// checkOpenAPIDocument returns an error if any route of r is missing from the OpenAPI document,
+// or if the document describes a route that doesn't exist.
+func checkOpenAPIDocument(r chi.Routes) error {
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openAPIDocument, &doc); err != nil {
+		return fmt.Errorf("error when decoding the OpenAPI document: %w", err)
+	}
+
+	documented := make(map[string]bool)
+	for path, ops := range doc.Paths {
+		for method := range ops {
+			switch method {
+			case "get", "put", "post", "delete", "options", "head", "patch", "trace":
+				documented[routeKey(method, path)] = true
+			}
+		}
+	}
+
+	var problems []string
+	err := chi.Walk(r, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		key := routeKey(method, route)
+		if !documented[key] {
+			problems = append(problems, fmt.Sprintf("route %s %s is not documented", method, route))
+		}
+		delete(documented, key)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error when walking routes: %w", err)
+	}
+
+	for key := range documented {
+		problems = append(problems, fmt.Sprintf("route %s is documented, but doesn't exist", key))
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("the OpenAPI document in api/openapi.json is out of date:\n%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
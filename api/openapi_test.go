@@ -0,0 +1,10 @@
+package api
+
+import "testing"
+
+func TestOpenAPIDocument(t *testing.T) {
+	a := New(Dependencies{}).(*api)
+	if err := checkOpenAPIDocument(a.r); err != nil {
+		t.Error(err)
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"encoding/json"
 	"io"
 	"net/http"
 	"strconv"
@@ -11,29 +12,64 @@ import (
 
 // ProductsAPI provides operations to retrieve, create and update products.
 type ProductsAPI struct {
-	svc ProductsService
-	r   *chi.Mux
+	svc   ProductsService
+	users UsersService
+	r     *chi.Mux
 }
 
 type ProductsService interface {
-	CreateProduct(categorySlug string, jsonData []byte) (model.Product, error)
-	GetProductsByCategory(categorySlug string) ([]model.Product, error)
-	GetProductByID(id int) (model.Product, error)
-	GetProductsNeedingApproval() ([]model.Product, error)
-	ApproveProduct(id int) error
-	RejectProduct(id int) error
+	CreateProduct(categorySlug string, jsonData []byte, submittedBy int64) (model.Product, error)
+	GetProductsByCategory(categorySlug, locale string) ([]model.Product, error)
+	GetProductByID(id int, locale string) (model.Product, error)
+	GetProductForModeration(id int) (model.Product, error)
+	GetSubmittedProducts(userID int64) ([]model.Product, error)
+	ApproveProduct(id int, approval model.Approval, by model.Moderator) error
+	GetModeratorEdits(id int) ([]model.ModeratorEdit, error)
+	RejectProduct(id int, rejection model.Rejection, by model.Moderator) error
+	DiscontinueProduct(id int, by model.Moderator) error
+	WithdrawProduct(id int, userID int64) (model.Product, error)
+	ImportProducts(categorySlug, format string, r io.Reader, dryRun bool) (model.ImportResult, error)
+	GetCategoryTree(includePending bool) (*model.CategoryTreeNode, error)
+
+	SubmitTranslation(productID int, locale string, jsonData []byte, submittedBy int64) (model.Translation, error)
+	GetTranslationsNeedingApproval() ([]model.Translation, error)
+	ApproveTranslation(id int, by model.Moderator) error
+	RejectTranslation(id int, by model.Moderator) error
+
+	GetModerationActions() ([]model.ModerationAction, error)
+
+	GetModerationQueue(categorySlug string, q model.QueueQuery) (model.QueuePage, error)
+	ClaimProduct(id int, by model.Moderator) error
+	ReleaseProductClaim(id int, by model.Moderator) error
+	AddModeratorNote(id int, text string, by model.Moderator) (model.ModeratorNote, error)
+	GetModeratorNotes(id int) ([]model.ModeratorNote, error)
+	BulkModerate(a model.BulkAction, by model.Moderator) ([]model.BulkResult, error)
+
+	RequestProductChanges(id int, req model.ChangeRequest, by model.Moderator) error
+	ResubmitProduct(id int, jsonData []byte, userID int64) (model.Product, error)
+	GetProductComments(id int) ([]model.Comment, error)
+	GetSubmissionComments(id int, userID int64) ([]model.Comment, error)
+	AddModeratorComment(id int, fc model.FieldComment, by model.Moderator) (model.Comment, error)
+	AddSubmitterComment(id int, fc model.FieldComment, userID int64) (model.Comment, error)
 }
 
 // NewProductsAPI returns a new ProductsAPI.
-func newProductsAPI(svc ProductsService) http.Handler {
+func newProductsAPI(svc ProductsService, users UsersService) http.Handler {
 	a := &ProductsAPI{
-		svc: svc,
-		r:   chi.NewRouter(),
+		svc:   svc,
+		users: users,
+		r:     chi.NewRouter(),
 	}
 
 	a.r.Post("/{category_slug}", a.CreateProduct)
+	a.r.Get("/mine", a.GetSubmittedProducts)
 	a.r.Get("/by-category/{category_slug}", a.GetProductsByCategory)
 	a.r.Get("/{product_id}", a.GetProductByID)
+	a.r.Put("/{product_id}", a.ResubmitProduct)
+	a.r.Post("/{product_id}/withdraw", a.WithdrawProduct)
+	a.r.Get("/{product_id}/comments", a.GetComments)
+	a.r.Post("/{product_id}/comments", a.AddComment)
+	a.r.Post("/{product_id}/translations/{locale}", a.SubmitTranslation)
 	return a.r
 }
 
@@ -46,7 +82,13 @@ func (a *ProductsAPI) CreateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	prod, err := a.svc.CreateProduct(categorySlug, jsonData)
+	submittedBy, err := a.submitterID(r)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	prod, err := a.svc.CreateProduct(categorySlug, jsonData, submittedBy)
 	if err != nil {
 		errorResponse(w, err)
 		return
@@ -55,10 +97,37 @@ func (a *ProductsAPI) CreateProduct(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(w, http.StatusCreated, prod)
 }
 
+// GetSubmittedProducts lists the products submitted by the logged-in user, whatever their status.
+func (a *ProductsAPI) GetSubmittedProducts(w http.ResponseWriter, r *http.Request) {
+	session, ok := requireSession(w, r)
+	if !ok {
+		return
+	}
+
+	u, err := a.users.GetUser(r.Context(), session.Email)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	prods, err := a.svc.GetSubmittedProducts(u.ID)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	// If there are no products, we want an empty array, not null.
+	if prods == nil {
+		prods = []model.Product{}
+	}
+
+	jsonResponse(w, http.StatusOK, prods)
+}
+
 func (a *ProductsAPI) GetProductsByCategory(w http.ResponseWriter, r *http.Request) {
 	categorySlug := chi.URLParam(r, "category_slug")
 
-	prods, err := a.svc.GetProductsByCategory(categorySlug)
+	prods, err := a.svc.GetProductsByCategory(categorySlug, requestedLocale(r))
 	if err != nil {
 		errorResponse(w, err)
 		return
@@ -81,9 +150,10 @@ func (a *ProductsAPI) GetProductByID(w http.ResponseWriter, r *http.Request) {
 			HTTPStatusCode:    http.StatusBadRequest,
 			UserFacingMessage: "Invalid product ID",
 		})
+		return
 	}
 
-	product, err := a.svc.GetProductByID(id)
+	product, err := a.svc.GetProductByID(id, requestedLocale(r))
 	if err != nil {
 		errorResponse(w, err)
 		return
@@ -91,3 +161,173 @@ func (a *ProductsAPI) GetProductByID(w http.ResponseWriter, r *http.Request) {
 
 	jsonResponse(w, http.StatusOK, product)
 }
+
+// ResubmitProduct replaces the data of one of the logged-in user's products which a moderator has sent back for changes.
+func (a *ProductsAPI) ResubmitProduct(w http.ResponseWriter, r *http.Request) {
+	id, u, ok := a.submission(w, r)
+	if !ok {
+		return
+	}
+
+	jsonData, err := io.ReadAll(r.Body)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	prod, err := a.svc.ResubmitProduct(id, jsonData, u.ID)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, prod)
+}
+
+// WithdrawProduct takes back one of the logged-in user's products which hasn't been published or rejected yet.
+func (a *ProductsAPI) WithdrawProduct(w http.ResponseWriter, r *http.Request) {
+	id, u, ok := a.submission(w, r)
+	if !ok {
+		return
+	}
+
+	prod, err := a.svc.WithdrawProduct(id, u.ID)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, prod)
+}
+
+// GetComments returns the conversation between the moderators and the logged-in user about one of their products.
+func (a *ProductsAPI) GetComments(w http.ResponseWriter, r *http.Request) {
+	id, u, ok := a.submission(w, r)
+	if !ok {
+		return
+	}
+
+	comments, err := a.svc.GetSubmissionComments(id, u.ID)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	// If there are no comments, we want an empty array, not null.
+	if comments == nil {
+		comments = []model.Comment{}
+	}
+
+	jsonResponse(w, http.StatusOK, comments)
+}
+
+// AddComment adds the logged-in user's reply to the conversation about one of their products.
+func (a *ProductsAPI) AddComment(w http.ResponseWriter, r *http.Request) {
+	id, u, ok := a.submission(w, r)
+	if !ok {
+		return
+	}
+
+	fc, ok := decodeComment(w, r)
+	if !ok {
+		return
+	}
+
+	cm, err := a.svc.AddSubmitterComment(id, fc, u.ID)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusCreated, cm)
+}
+
+// submission returns the product ID from the URL and the logged-in user, for requests about the user's own submissions.
+// If ok is false, an error response has already been written.
+func (a *ProductsAPI) submission(w http.ResponseWriter, r *http.Request) (id int, u model.User, ok bool) {
+	id, ok = productID(w, r)
+	if !ok {
+		return 0, model.User{}, false
+	}
+
+	session, ok := requireSession(w, r)
+	if !ok {
+		return 0, model.User{}, false
+	}
+
+	u, err := a.users.GetUser(r.Context(), session.Email)
+	if err != nil {
+		errorResponse(w, err)
+		return 0, model.User{}, false
+	}
+	return id, u, true
+}
+
+// decodeComment decodes a comment from the request body.
+// If ok is false, an error response has already been written.
+func decodeComment(w http.ResponseWriter, r *http.Request) (fc model.FieldComment, ok bool) {
+	if err := json.NewDecoder(r.Body).Decode(&fc); err != nil {
+		errorResponse(w, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: "the request must be a JSON object containing the text of the comment",
+			SecretMessage:     err.Error(),
+		})
+		return model.FieldComment{}, false
+	}
+	return fc, true
+}
+
+func (a *ProductsAPI) SubmitTranslation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "product_id"))
+	if err != nil {
+		errorResponse(w, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: "Invalid product ID",
+		})
+		return
+	}
+
+	jsonData, err := io.ReadAll(r.Body)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	submittedBy, err := a.submitterID(r)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	t, err := a.svc.SubmitTranslation(id, chi.URLParam(r, "locale"), jsonData, submittedBy)
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	jsonResponse(w, http.StatusCreated, t)
+}
+
+// submitterID returns the ID of the logged-in user, or zero if there's no session.
+// Products and translations can still be submitted without logging in, but then nobody is told what happened to them.
+func (a *ProductsAPI) submitterID(r *http.Request) (int64, error) {
+	session, ok := sessionFromContext(r.Context())
+	if !ok {
+		return 0, nil
+	}
+
+	u, err := a.users.GetUser(r.Context(), session.Email)
+	if err != nil {
+		return 0, err
+	}
+	return u.ID, nil
+}
+
+// requestedLocale returns the language the client wants product texts in.
+// The "lang" query parameter takes precedence over the Accept-Language header.
+func requestedLocale(r *http.Request) string {
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		return lang
+	}
+	return r.Header.Get("Accept-Language")
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// schemasAPI publishes our JSON schemas, so that they can be referenced by their IDs.
+type schemasAPI struct {
+	*chi.Mux
+	meta MetadataService
+}
+
+func newSchemasAPI(metadata MetadataService) *schemasAPI {
+	r := chi.NewRouter()
+	s := &schemasAPI{r, metadata}
+
+	r.Get("/{fieldset_slug}", s.GetFieldsetSchema)
+	r.Get("/categories/{category_slug}", s.GetProductSchema)
+
+	return s
+}
+
+func (s *schemasAPI) GetFieldsetSchema(w http.ResponseWriter, r *http.Request) {
+	schema, err := s.meta.GetFieldsetSchema(chi.URLParam(r, "fieldset_slug"))
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	schemaResponse(w, schema)
+}
+
+func (s *schemasAPI) GetProductSchema(w http.ResponseWriter, r *http.Request) {
+	schema, err := s.meta.GetProductSchema(chi.URLParam(r, "category_slug"))
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	schemaResponse(w, schema)
+}
+
+// schemaResponse responds with a JSON schema.
+// Schemas only change when we deploy, so we let clients cache them for a while.
+func schemaResponse(w http.ResponseWriter, schema map[string]any) {
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	jsonResponse(w, http.StatusOK, schema)
+}
@@ -3,70 +3,250 @@ package app
 import (
 	"context"
 	"fmt"
+	"log"
+	"net/http"
 	"net/url"
+	"strings"
+	"time"
 
 	"github.com/mikolysz/enably/model"
 	"github.com/mikolysz/enably/pkg/email"
 )
 
+// LoginTokenLifetime is how long a login link stays valid.
+const LoginTokenLifetime = 15 * time.Minute
+
+// sessionRenewalInterval is how often the expiry of a session in use is pushed back.
+// It keeps us from writing to the database on every single request.
+const sessionRenewalInterval = 5 * time.Minute
+
 // AuthenticationService manages user authentication
 type AuthenticationService struct {
 	store       TokenStore
+	users       *UsersService
 	emailSender email.Sender
-	frontendURL *url.URL
+	config      AuthConfig
+}
+
+// AuthConfig contains the settings of an AuthenticationService.
+type AuthConfig struct {
+	// FrontendURL is where login links point to. Users can always be redirected back to it after logging in.
+	FrontendURL *url.URL
+
+	// AllowedRedirectOrigins are the other origins, e.g. "https://beta.enably.me", users can be redirected to after logging in.
+	AllowedRedirectOrigins []*url.URL
+
+	// SessionLifetime is how long a session lasts without being used.
+	SessionLifetime time.Duration
 }
 
 type TokenStore interface {
-	AddToken(c context.Context, t model.Token) (model.Token, error)
-	GetByTokenContents(c context.Context, token string) (model.Token, error)
+	AddLoginToken(c context.Context, t model.LoginToken) (model.LoginToken, error)
+
+	// TakeLoginToken deletes the login token with the given contents and returns it,
+	// so that it can't be used again, whether it has expired or not.
+	TakeLoginToken(c context.Context, token string) (model.LoginToken, error)
+
+	AddSession(c context.Context, s model.Session) (model.Session, error)
+	GetSessionByToken(c context.Context, token string) (model.Session, error)
+	ExtendSession(c context.Context, id int64, lastUsedAt, expiresAt time.Time) error
+
+	// ListSessions returns the sessions of the given user which are neither expired nor revoked.
+	ListSessions(c context.Context, email string, now time.Time) ([]model.Session, error)
+
+	// RevokeSession fails with model.ErrSessionNotFound unless the session belongs to the given user.
+	RevokeSession(c context.Context, id int64, email string, now time.Time) error
+	RevokeOtherSessions(c context.Context, email string, exceptID int64, now time.Time) error
+
+	// DeleteExpiredTokens deletes all login tokens and sessions which expired before the given time, and all revoked sessions.
+	DeleteExpiredTokens(c context.Context, now time.Time) error
 }
 
 // NewAuthenticationService creates a new AuthenticationService.
-func NewAuthenticationService(store TokenStore, emailSender email.Sender, frontendURL *url.URL) AuthenticationService {
+func NewAuthenticationService(store TokenStore, users *UsersService, emailSender email.Sender, config AuthConfig) AuthenticationService {
 	return AuthenticationService{
 		store:       store,
+		users:       users,
 		emailSender: emailSender,
-		frontendURL: frontendURL,
+		config:      config,
 	}
 }
 
-// SendLoginEmail creates an authentication token and emails the user with a "magic link" which logs them in.
+// SendLoginEmail creates a login token and emails the user with a "magic link" which logs them in.
 // redirectURI is the URL to redirect to after the login is successful.
+// It must point to the frontend or one of the allowed origins, and is stored with the token rather than included in the link.
 func (s AuthenticationService) SendLoginEmail(c context.Context, emailAddress, redirectURI string) error {
-	token := model.NewToken(emailAddress)
-	if _, err := s.store.AddToken(c, *token); err != nil {
+	emailAddress, err := model.NormalizeEmail(emailAddress)
+	if err != nil {
+		return err
+	}
+
+	redirect, err := s.checkRedirectURI(redirectURI)
+	if err != nil {
+		return err
+	}
+
+	token := model.NewLoginToken(emailAddress, LoginTokenLifetime)
+	token.RedirectURI = redirect
+	if _, err := s.store.AddLoginToken(c, *token); err != nil {
 		return err
 	}
 
 	authValues := url.Values{}
 	authValues.Set("token", token.Token)
-	// FIXME: Use different tokens for initial authentication and keeping the session, that's more secure.
-	authValues.Set("redirect_uri", redirectURI)
 
-	url := *s.frontendURL
+	url := *s.config.FrontendURL
 	url.Path = "authorize"
 	url.RawQuery = authValues.Encode()
 	urlStr := url.String()
+	expiry := fmt.Sprintf("The link expires in %d minutes and can only be used once.", int(LoginTokenLifetime.Minutes()))
 	msg := email.Message{
 		Recipient:        emailAddress,
 		Subject:          "Login to Enably",
-		PlainTextContent: "Click this link to login: " + urlStr,
-		HTMLContent:      "<p>Click this link to login: <a href=\"" + urlStr + "\">Login</a></p>",
+		PlainTextContent: "Click this link to login: " + urlStr + "\n\n" + expiry,
+		HTMLContent:      "<p>Click this link to login: <a href=\"" + urlStr + "\">Login</a></p><p>" + expiry + "</p>",
 	}
 
 	if err := s.emailSender.Send(msg); err != nil {
 		return fmt.Errorf("error when sending login: %w", err)
 	}
-	// FIXME: security: ensure redirectURI points to our own domain just in case.
 
 	return nil
 }
 
-// AuthenticateUser checks if the token is valid and returns the email address of the user.
-func (s AuthenticationService) AuthenticateUser(c context.Context, token string) (email string, err error) {
-	t, err := s.store.GetByTokenContents(c, token)
+// checkRedirectURI makes sure users can only be redirected to the frontend or one of the allowed origins after logging in,
+// so that login links can't be used to send people to phishing sites.
+// Relative URIs are resolved against the frontend URL, and an empty one means the frontend's home page.
+func (s AuthenticationService) checkRedirectURI(redirectURI string) (string, error) {
+	origins := append([]*url.URL{s.config.FrontendURL}, s.config.AllowedRedirectOrigins...)
+	names := make([]string, len(origins))
+	for i, origin := range origins {
+		names[i] = origin.Scheme + "://" + origin.Host
+	}
+	invalid := model.UserFacingError{
+		HTTPStatusCode:    http.StatusBadRequest,
+		UserFacingMessage: "redirect_uri must be a relative path or a URL on one of: " + strings.Join(names, ", "),
+	}
+
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		invalid.SecretMessage = err.Error()
+		return "", invalid
+	}
+
+	u = s.config.FrontendURL.ResolveReference(u)
+	if u.User != nil {
+		return "", invalid
+	}
+
+	for _, origin := range origins {
+		if u.Scheme == origin.Scheme && u.Host == origin.Host {
+			return u.String(), nil
+		}
+	}
+	return "", invalid
+}
+
+// ExchangeLoginToken uses up a login token from an emailed link, and starts a new session for its owner.
+// The user's account is created if this is their first login.
+// userAgent identifies the user's device in the list of their sessions.
+func (s AuthenticationService) ExchangeLoginToken(c context.Context, token, userAgent string) (model.Login, error) {
+	t, err := s.store.TakeLoginToken(c, token)
+	if err != nil {
+		return model.Login{}, err
+	}
+
+	if time.Now().After(t.ExpiresAt) {
+		return model.Login{}, model.ErrExpiredLoginToken
+	}
+
+	user, err := s.users.GetOrCreateUser(c, t.Email)
 	if err != nil {
-		return "", err
+		return model.Login{}, err
+	}
+
+	session, err := s.store.AddSession(c, *model.NewSession(t.Email, userAgent, s.config.SessionLifetime))
+	if err != nil {
+		return model.Login{}, fmt.Errorf("error when creating session: %w", err)
+	}
+	return model.Login{Session: session, User: user, RedirectURI: t.RedirectURI}, nil
+}
+
+// AuthenticateUser checks if the session token is valid and returns the session, which contains the email address of the user.
+// Using a session keeps it from expiring.
+func (s AuthenticationService) AuthenticateUser(c context.Context, token string) (model.Session, error) {
+	session, err := s.store.GetSessionByToken(c, token)
+	if err != nil {
+		return model.Session{}, err
+	}
+
+	now := time.Now()
+	if now.After(session.ExpiresAt) || session.RevokedAt != nil {
+		return model.Session{}, model.ErrInvalidToken
+	}
+
+	if now.Sub(session.LastUsedAt) > sessionRenewalInterval {
+		session.LastUsedAt = now
+		session.ExpiresAt = now.Add(s.config.SessionLifetime)
+		if err := s.store.ExtendSession(c, session.ID, session.LastUsedAt, session.ExpiresAt); err != nil {
+			return model.Session{}, fmt.Errorf("error when extending session: %w", err)
+		}
+	}
+	return session, nil
+}
+
+// Logout ends the given session, so that its token can no longer be used.
+func (s AuthenticationService) Logout(c context.Context, session model.Session) error {
+	if err := s.store.RevokeSession(c, session.ID, session.Email, time.Now()); err != nil {
+		return fmt.Errorf("error when revoking session: %w", err)
+	}
+	return nil
+}
+
+// ListSessions returns the active sessions of the user the given session belongs to, marking that one as current.
+func (s AuthenticationService) ListSessions(c context.Context, current model.Session) ([]model.Session, error) {
+	sessions, err := s.store.ListSessions(c, current.Email, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error when listing sessions: %w", err)
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current.ID
+	}
+	return sessions, nil
+}
+
+// RevokeSession ends another session of the user the given session belongs to, e.g. on a lost device.
+func (s AuthenticationService) RevokeSession(c context.Context, current model.Session, id int64) error {
+	if err := s.store.RevokeSession(c, id, current.Email, time.Now()); err != nil {
+		return fmt.Errorf("error when revoking session %d: %w", id, err)
+	}
+	return nil
+}
+
+// RevokeOtherSessions ends all sessions of the user the given session belongs to, except that one.
+func (s AuthenticationService) RevokeOtherSessions(c context.Context, current model.Session) error {
+	if err := s.store.RevokeOtherSessions(c, current.Email, current.ID, time.Now()); err != nil {
+		return fmt.Errorf("error when revoking sessions: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredTokens deletes login tokens and sessions which can no longer be used every interval,
+// until the context is cancelled.
+func (s AuthenticationService) DeleteExpiredTokens(c context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.store.DeleteExpiredTokens(c, time.Now()); err != nil {
+			log.Printf("Error when deleting expired tokens: %s", err)
+		}
+
+		select {
+		case <-c.Done():
+			return
+		case <-ticker.C:
+		}
 	}
-	return t.Email, nil
 }
@@ -0,0 +1,208 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mikolysz/enably/app"
+	"github.com/mikolysz/enably/model"
+	"github.com/mikolysz/enably/pkg/email"
+	"github.com/mikolysz/enably/store"
+)
+
+func TestLoginRedirects(t *testing.T) {
+	tests := []struct {
+		name        string
+		redirectURI string
+		want        string // empty if the URI must be refused.
+	}{
+		{"empty", "", "https://enably.me"},
+		{"relative path", "/submissions?page=2", "https://enably.me/submissions?page=2"},
+		{"frontend", "https://enably.me/submit", "https://enably.me/submit"},
+		{"allowlisted origin", "https://beta.enably.me/submit", "https://beta.enably.me/submit"},
+		{"other host", "https://evil.com/", ""},
+		{"protocol-relative", "//evil.com/submit", ""},
+		{"user info", "https://enably.me@evil.com/", ""},
+		{"javascript", "javascript:alert(1)", ""},
+		{"scheme mismatch", "http://enably.me/submit", ""},
+		{"port mismatch", "https://enably.me:8443/submit", ""},
+		{"allowlisted host with another scheme", "http://beta.enably.me/submit", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, _, sent := newTestAuthService(t)
+			c := context.Background()
+
+			err := auth.SendLoginEmail(c, "user@example.com", tt.redirectURI)
+			if tt.want == "" {
+				var userErr model.UserFacingError
+				if !errors.As(err, &userErr) || !strings.Contains(userErr.UserFacingMessage, "https://beta.enably.me") {
+					t.Fatalf("SendLoginEmail returned %v, want an error listing the allowed origins", err)
+				}
+				if len(*sent) != 0 {
+					t.Errorf("SendLoginEmail sent %d emails for a refused redirect_uri", len(*sent))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			login, err := auth.ExchangeLoginToken(c, sentLoginToken(t, *sent), "Firefox")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if login.RedirectURI != tt.want {
+				t.Errorf("the login redirected to %q, want %q", login.RedirectURI, tt.want)
+			}
+		})
+	}
+}
+
+func TestExchangeLoginToken(t *testing.T) {
+	auth, tokens, sent := newTestAuthService(t)
+	c := context.Background()
+
+	if err := auth.SendLoginEmail(c, "User@Example.com", ""); err != nil {
+		t.Fatal(err)
+	}
+	expiry := fmt.Sprintf("expires in %d minutes", int(app.LoginTokenLifetime.Minutes()))
+	if text := (*sent)[0].PlainTextContent; !strings.Contains(text, expiry) {
+		t.Errorf("the login email says %q, want it to say the link %s", text, expiry)
+	}
+
+	token := sentLoginToken(t, *sent)
+	login, err := auth.ExchangeLoginToken(c, token, "Firefox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if login.User.Email != "user@example.com" || login.Session.Email != "user@example.com" || login.Session.UserAgent != "Firefox" {
+		t.Errorf("ExchangeLoginToken returned %+v, want a session for user@example.com on Firefox", login)
+	}
+	if lifetime := time.Until(login.Session.ExpiresAt); lifetime < testSessionLifetime-time.Minute || lifetime > testSessionLifetime {
+		t.Errorf("the new session expires in %s, want %s", lifetime, testSessionLifetime)
+	}
+
+	// Login links can only be used once.
+	if _, err := auth.ExchangeLoginToken(c, token, "Firefox"); !errors.Is(err, model.ErrExpiredLoginToken) {
+		t.Errorf("exchanging a login token twice returned %v, want %v", err, model.ErrExpiredLoginToken)
+	}
+
+	expired, err := tokens.AddLoginToken(c, *model.NewLoginToken("user@example.com", -time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := auth.ExchangeLoginToken(c, expired.Token, "Firefox"); !errors.Is(err, model.ErrExpiredLoginToken) {
+		t.Errorf("exchanging an expired login token returned %v, want %v", err, model.ErrExpiredLoginToken)
+	}
+}
+
+func TestSessionRenewal(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name       string
+		lastUsedAt time.Time
+		expiresAt  time.Time
+		revoked    bool
+		wantErr    error
+
+		// renewed means the session's expiry is pushed back to a full testSessionLifetime from now.
+		renewed bool
+	}{
+		{"just used", now.Add(-time.Minute), now.Add(testSessionLifetime - time.Minute), false, nil, false},
+		{"used a while ago", now.Add(-10 * time.Minute), now.Add(testSessionLifetime - 10*time.Minute), false, nil, true},
+		{"expired", now.Add(-2 * testSessionLifetime), now.Add(-testSessionLifetime), false, model.ErrInvalidToken, false},
+		{"revoked", now.Add(-10 * time.Minute), now.Add(testSessionLifetime - 10*time.Minute), true, model.ErrInvalidToken, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, tokens, _ := newTestAuthService(t)
+			c := context.Background()
+
+			session := model.NewSession("user@example.com", "Firefox", testSessionLifetime)
+			session.LastUsedAt, session.ExpiresAt = tt.lastUsedAt, tt.expiresAt
+			added, err := tokens.AddSession(c, *session)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.revoked {
+				if err := tokens.RevokeSession(c, added.ID, added.Email, now); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if _, err := auth.AuthenticateUser(c, added.Token); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AuthenticateUser returned %v, want %v", err, tt.wantErr)
+			}
+
+			stored, err := tokens.GetSessionByToken(c, added.Token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tt.expiresAt
+			if tt.renewed {
+				want = now.Add(testSessionLifetime)
+			}
+			if diff := stored.ExpiresAt.Sub(want); diff < -time.Second || diff > time.Minute {
+				t.Errorf("the session expires at %s, want %s", stored.ExpiresAt, want)
+			}
+		})
+	}
+}
+
+// testEmails is an email.Sender which keeps the emails it sends.
+type testEmails []email.Message
+
+func (e *testEmails) Send(m email.Message) error {
+	*e = append(*e, m)
+	return nil
+}
+
+// testSessionLifetime is the SessionLifetime of the services returned by newTestAuthService.
+const testSessionLifetime = time.Hour
+
+// newTestAuthService returns an AuthenticationService for https://enably.me, which also allows redirects to
+// https://beta.enably.me, along with its token store and the emails it sent.
+func newTestAuthService(t *testing.T) (app.AuthenticationService, *store.MemoryTokenStore, *testEmails) {
+	tokens := store.NewMemoryTokenStore()
+	frontend, err := url.Parse("https://enably.me")
+	if err != nil {
+		t.Fatal(err)
+	}
+	beta, err := url.Parse("https://beta.enably.me")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := &testEmails{}
+	auth := app.NewAuthenticationService(tokens, app.NewUsersService(store.NewMemoryUsersStore()), sent, app.AuthConfig{
+		FrontendURL:            frontend,
+		AllowedRedirectOrigins: []*url.URL{beta},
+		SessionLifetime:        testSessionLifetime,
+	})
+	return auth, tokens, sent
+}
+
+// sentLoginToken returns the token from the login link in the last email sent.
+func sentLoginToken(t *testing.T, sent testEmails) string {
+	if len(sent) == 0 {
+		t.Fatal("no login email was sent")
+	}
+	text := sent[len(sent)-1].PlainTextContent
+	start := strings.Index(text, "https://")
+	if start == -1 {
+		t.Fatalf("the login email doesn't contain a link: %q", text)
+	}
+	link, err := url.Parse(strings.Fields(text[start:])[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return link.Query().Get("token")
+}
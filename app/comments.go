@@ -0,0 +1,267 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mikolysz/enably/model"
+)
+
+// RequestProductChanges sends the pending product with the specified ID back to its submitter on behalf of the given moderator,
+// with a message and comments on the fields which need to be corrected.
+// The product leaves the moderation queue until the submitter resubmits it with ResubmitProduct.
+func (s *ProductsService) RequestProductChanges(id int, req model.ChangeRequest, by model.Moderator) error {
+	p, err := s.checkTransition(id, model.StatusChangesRequested)
+	if err != nil {
+		return err
+	}
+
+	if p.SubmittedBy == nil {
+		return model.UserFacingError{
+			HTTPStatusCode:    http.StatusConflict,
+			UserFacingMessage: "this product was submitted without logging in, so there's nobody to send it back to",
+		}
+	}
+
+	cat, err := s.meta.GetCategory(p.CategorySlug)
+	if err != nil {
+		return fmt.Errorf("error when retrieving category %s: %w", p.CategorySlug, err)
+	}
+
+	fieldComments := req.FieldComments
+	if strings.TrimSpace(req.Message) != "" {
+		fieldComments = append([]model.FieldComment{{Text: req.Message}}, fieldComments...)
+	}
+
+	if len(fieldComments) == 0 {
+		return model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: "please tell the submitter what needs to be changed",
+		}
+	}
+
+	comments := make([]model.Comment, 0, len(fieldComments))
+	for _, fc := range fieldComments {
+		cm, err := newComment(cat, id, fc)
+		if err != nil {
+			return err
+		}
+		cm.FromModerator = true
+		cm.AuthorID = moderatorUserID(by)
+		comments = append(comments, cm)
+	}
+
+	if err := s.store.RequestProductChanges(context.Background(), id, comments, by); err != nil {
+		return fmt.Errorf("error when requesting changes to product %d: %w", id, err)
+	}
+
+	s.invalidateProductCounts()
+	s.notifyAboutProduct(id, func(c context.Context, p model.Product) {
+		s.notifier.ChangesRequested(c, p, describeComments(cat, comments))
+	})
+	return nil
+}
+
+// ResubmitProduct replaces the data of the product with the specified ID, which a moderator has sent back for changes,
+// and returns it to the moderation queue. Only the product's submitter can resubmit it.
+// The new data is validated in the same way as in CreateProduct.
+func (s *ProductsService) ResubmitProduct(id int, jsonData []byte, userID int64) (model.Product, error) {
+	p, err := s.getSubmission(id, userID)
+	if err != nil {
+		return model.Product{}, err
+	}
+
+	if !p.Status.CanBecome(model.StatusPending) {
+		return model.Product{}, model.ErrNoChangesRequested
+	}
+
+	cat, err := s.getLeafCategory(p.CategorySlug)
+	if err != nil {
+		return model.Product{}, err
+	}
+
+	decoded, err := s.decodeProductData(cat, jsonData)
+	if err != nil {
+		return model.Product{}, err
+	}
+
+	if err := s.store.ResubmitProduct(context.Background(), id, decoded); err != nil {
+		return model.Product{}, fmt.Errorf("error when resubmitting product %d: %w", id, err)
+	}
+	s.invalidateProductCounts()
+
+	p, err = s.getSubmission(id, userID)
+	if err != nil {
+		return model.Product{}, err
+	}
+
+	if err := s.SetDerivedFields(&p); err != nil {
+		return model.Product{}, fmt.Errorf("error when setting derived fields for product %d: %w", id, err)
+	}
+	return p, nil
+}
+
+// GetProductComments returns the conversation about the product with the specified ID, oldest first, for moderators.
+func (s *ProductsService) GetProductComments(id int) ([]model.Comment, error) {
+	if _, err := s.store.GetProductByID(context.Background(), id); err != nil {
+		return nil, fmt.Errorf("error when retrieving product %d: %w", id, err)
+	}
+	return s.getComments(id)
+}
+
+// GetSubmissionComments returns the conversation about the product with the specified ID, oldest first,
+// as long as it was submitted by the user with the given ID.
+func (s *ProductsService) GetSubmissionComments(id int, userID int64) ([]model.Comment, error) {
+	if _, err := s.getSubmission(id, userID); err != nil {
+		return nil, err
+	}
+	return s.getComments(id)
+}
+
+func (s *ProductsService) getComments(id int) ([]model.Comment, error) {
+	comments, err := s.store.GetProductComments(context.Background(), id)
+	if err != nil {
+		return nil, fmt.Errorf("error when retrieving comments on product %d: %w", id, err)
+	}
+	return comments, nil
+}
+
+// AddModeratorComment adds a comment by the given moderator to the conversation about the product with the specified ID,
+// and emails it to the product's submitter.
+func (s *ProductsService) AddModeratorComment(id int, fc model.FieldComment, by model.Moderator) (model.Comment, error) {
+	p, err := s.store.GetProductByID(context.Background(), id)
+	if err != nil {
+		return model.Comment{}, fmt.Errorf("error when retrieving product %d: %w", id, err)
+	}
+
+	cat, err := s.meta.GetCategory(p.CategorySlug)
+	if err != nil {
+		return model.Comment{}, fmt.Errorf("error when retrieving category %s: %w", p.CategorySlug, err)
+	}
+
+	cm, err := s.addComment(cat, id, fc, moderatorUserID(by), true)
+	if err != nil {
+		return model.Comment{}, err
+	}
+
+	s.notifyAboutProduct(id, func(c context.Context, p model.Product) {
+		s.notifier.CommentAdded(c, p, describeComments(cat, []model.Comment{cm}))
+	})
+	return cm, nil
+}
+
+// AddSubmitterComment adds a reply by the user with the given ID to the conversation about the product with the specified ID.
+// Only the product's submitter can reply.
+func (s *ProductsService) AddSubmitterComment(id int, fc model.FieldComment, userID int64) (model.Comment, error) {
+	p, err := s.getSubmission(id, userID)
+	if err != nil {
+		return model.Comment{}, err
+	}
+
+	cat, err := s.meta.GetCategory(p.CategorySlug)
+	if err != nil {
+		return model.Comment{}, fmt.Errorf("error when retrieving category %s: %w", p.CategorySlug, err)
+	}
+	return s.addComment(cat, id, fc, &userID, false)
+}
+
+func (s *ProductsService) addComment(cat *model.Category, productID int, fc model.FieldComment, authorID *int64, fromModerator bool) (model.Comment, error) {
+	cm, err := newComment(cat, productID, fc)
+	if err != nil {
+		return model.Comment{}, err
+	}
+	cm.AuthorID = authorID
+	cm.FromModerator = fromModerator
+
+	cm, err = s.store.AddProductComment(context.Background(), cm)
+	if err != nil {
+		return model.Comment{}, fmt.Errorf("error when adding comment to product %d: %w", productID, err)
+	}
+	return cm, nil
+}
+
+// getSubmission returns the product with the given ID if it was submitted by the user with the given ID.
+// Other users get model.ErrProductNotFound.
+func (s *ProductsService) getSubmission(id int, userID int64) (model.Product, error) {
+	p, err := s.store.GetProductByID(context.Background(), id)
+	if err != nil {
+		return model.Product{}, fmt.Errorf("error when retrieving product %d: %w", id, err)
+	}
+
+	if p.SubmittedBy == nil || *p.SubmittedBy != userID {
+		return model.Product{}, model.ErrProductNotFound
+	}
+	return p, nil
+}
+
+// newComment validates the given comment on a product in the given category.
+func newComment(cat *model.Category, productID int, fc model.FieldComment) (model.Comment, error) {
+	text := strings.TrimSpace(fc.Text)
+	if text == "" {
+		return model.Comment{}, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: "comments can't be empty",
+		}
+	}
+
+	if utf8.RuneCountInString(text) > model.MaxCommentLength {
+		return model.Comment{}, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: fmt.Sprintf("comments can be at most %d characters long", model.MaxCommentLength),
+		}
+	}
+
+	if fc.Field != "" && fieldLabel(cat, fc.Field) == "" {
+		return model.Comment{}, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: fmt.Sprintf("category %s has no field %s, use the fieldset_slug.field_name format", cat.Slug, fc.Field),
+		}
+	}
+
+	return model.Comment{ProductID: productID, Field: fc.Field, Text: text}, nil
+}
+
+// fieldLabel returns the label of the given field, in the fieldset_slug.field_name format,
+// or an empty string if the category has no such field.
+func fieldLabel(cat *model.Category, field string) string {
+	fsetSlug, name, ok := strings.Cut(field, ".")
+	if !ok {
+		return ""
+	}
+
+	for _, fset := range cat.Fieldsets {
+		if fset.Slug != fsetSlug {
+			continue
+		}
+
+		if f := fset.FieldByName(name); f != nil {
+			return f.Label
+		}
+	}
+	return ""
+}
+
+// describeComments turns comments into text for an email, with each field comment preceded by the field's label.
+func describeComments(cat *model.Category, comments []model.Comment) string {
+	var paragraphs []string
+	for _, cm := range comments {
+		if cm.Field == "" {
+			paragraphs = append(paragraphs, cm.Text)
+		} else {
+			paragraphs = append(paragraphs, fieldLabel(cat, cm.Field)+": "+cm.Text)
+		}
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// moderatorUserID returns the ID of the given moderator's account, or nil if they used the moderation API key.
+func moderatorUserID(by model.Moderator) *int64 {
+	if by.UserID == 0 {
+		return nil
+	}
+	id := by.UserID
+	return &id
+}
@@ -0,0 +1,137 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/mikolysz/enably/model"
+)
+
+// editAndApproveProduct applies the given changes to the given product and approves it on behalf of the given moderator.
+// The result is validated in the same way as in CreateProduct.
+// Changes are given as fieldset slugs mapped to field names mapped to new values, and a nil value removes the field.
+// Only the changes which actually differ from the product's data are recorded, along with the original data.
+// It returns the changes as text for the submitter, which is empty if nothing was changed.
+func (s *ProductsService) editAndApproveProduct(p model.Product, changes map[string]map[string]any, by model.Moderator) (string, error) {
+	if len(changes) == 0 {
+		return "", s.approve(p.ID, by)
+	}
+
+	cat, err := s.getLeafCategory(p.CategorySlug)
+	if err != nil {
+		return "", err
+	}
+
+	data, changed, err := applyChanges(cat, p.Data, changes)
+	if err != nil {
+		return "", err
+	}
+
+	if len(changed) == 0 {
+		return "", s.approve(p.ID, by)
+	}
+
+	if err := s.validateProductData(cat, data); err != nil {
+		return "", err
+	}
+
+	edit := model.ModeratorEdit{ProductID: p.ID, Original: p.Data, Changes: changed}
+	if err := s.store.EditAndApproveProduct(context.Background(), p.ID, data, edit, by); err != nil {
+		return "", fmt.Errorf("error when approving product %d: %w", p.ID, err)
+	}
+	return describeEdits(cat, changed), nil
+}
+
+// approve approves the product with the specified ID on behalf of the given moderator, without changing it.
+func (s *ProductsService) approve(id int, by model.Moderator) error {
+	if err := s.store.ApproveProduct(context.Background(), id, by); err != nil {
+		return fmt.Errorf("error when approving product %d: %w", id, err)
+	}
+	return nil
+}
+
+// GetModeratorEdits returns the changes moderators made to the product with the specified ID when approving it, oldest first.
+func (s *ProductsService) GetModeratorEdits(id int) ([]model.ModeratorEdit, error) {
+	if _, err := s.store.GetProductByID(context.Background(), id); err != nil {
+		return nil, fmt.Errorf("error when retrieving product %d: %w", id, err)
+	}
+
+	edits, err := s.store.GetModeratorEdits(context.Background(), id)
+	if err != nil {
+		return nil, fmt.Errorf("error when retrieving moderator edits of product %d: %w", id, err)
+	}
+	return edits, nil
+}
+
+// applyChanges returns a copy of the given product data with the changes applied,
+// along with the changes which actually made a difference.
+// It returns an error if a change refers to a field which the category doesn't have.
+func applyChanges(cat *model.Category, original, changes map[string]map[string]any) (data, changed map[string]map[string]any, err error) {
+	data = make(map[string]map[string]any, len(original))
+	for fset, fields := range original {
+		data[fset] = make(map[string]any, len(fields))
+		for name, value := range fields {
+			data[fset][name] = value
+		}
+	}
+
+	changed = map[string]map[string]any{}
+	for fset, fields := range changes {
+		for name, value := range fields {
+			if fieldLabel(cat, fset+"."+name) == "" {
+				return nil, nil, model.UserFacingError{
+					HTTPStatusCode:    http.StatusBadRequest,
+					UserFacingMessage: fmt.Sprintf("category %s has no field %s.%s", cat.Slug, fset, name),
+				}
+			}
+
+			old, ok := data[fset][name]
+			if value == nil && !ok || ok && reflect.DeepEqual(old, value) {
+				continue
+			}
+
+			if data[fset] == nil {
+				data[fset] = map[string]any{}
+			}
+			if value == nil {
+				delete(data[fset], name)
+			} else {
+				data[fset][name] = value
+			}
+
+			if changed[fset] == nil {
+				changed[fset] = map[string]any{}
+			}
+			changed[fset][name] = value
+		}
+	}
+	return data, changed, nil
+}
+
+// describeEdits turns a moderator's changes into text for an email, one field per line, in the order of the category's fields.
+func describeEdits(cat *model.Category, changes map[string]map[string]any) string {
+	var lines []string
+	for _, fset := range cat.Fieldsets {
+		for _, f := range fset.Fields {
+			value, ok := changes[fset.Slug][f.Name]
+			if !ok {
+				continue
+			}
+
+			switch v := value.(type) {
+			case nil:
+				lines = append(lines, f.Label+": removed")
+			case string:
+				lines = append(lines, f.Label+": "+v)
+			default:
+				encoded, _ := json.Marshal(v)
+				lines = append(lines, f.Label+": "+string(encoded))
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
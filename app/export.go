@@ -0,0 +1,288 @@
+package app
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mikolysz/enably/model"
+)
+
+// All content on Enably is contributed by its users, and is published under this licence.
+const (
+	ContentLicence    = "CC BY-SA 4.0"
+	ContentLicenceURL = "https://creativecommons.org/licenses/by-sa/4.0/"
+	Attribution       = "Enably contributors"
+)
+
+// ExportService exports all approved products in bulk, so that the community can reuse them.
+type ExportService struct {
+	products *ProductsService
+	meta     *MetadataService
+	openDB   OpenExportDatabase
+	source   *url.URL
+
+	// sqliteExport is the latest database built by RefreshSQLiteExport, nil until the first one is ready.
+	sqliteMu     sync.Mutex
+	sqliteExport *sqliteSnapshot
+}
+
+// sqliteSnapshot is a complete SQLite export, kept in a temporary file.
+type sqliteSnapshot struct {
+	path        string
+	generatedAt time.Time
+}
+
+// ExportDatabase is a self-contained database of the whole directory, meant for offline browsing.
+type ExportDatabase interface {
+	SetMetadata(m model.ExportMetadata) error
+	AddCategory(cat *model.Category) error
+	AddFieldset(fset *model.Fieldset) error
+	AddProduct(p model.ExportedProduct) error
+
+	// Close finishes writing the database. It must be called even if one of the other methods fails.
+	Close() error
+}
+
+// OpenExportDatabase creates a new, empty ExportDatabase in a file at the given path.
+type OpenExportDatabase func(path string) (ExportDatabase, error)
+
+// NewExportService returns a new ExportService.
+// source is the URL of the site the data comes from, included in the export metadata for attribution.
+func NewExportService(products *ProductsService, openDB OpenExportDatabase, source *url.URL) *ExportService {
+	return &ExportService{
+		products: products,
+		meta:     products.meta,
+		openDB:   openDB,
+		source:   source,
+	}
+}
+
+// GetExportMetadata returns the metadata describing an export generated now.
+func (s *ExportService) GetExportMetadata() model.ExportMetadata {
+	return model.ExportMetadata{
+		SchemaVersion: s.meta.GetSchemaVersion(),
+		GeneratedAt:   time.Now().UTC(),
+		Licence:       ContentLicence,
+		LicenceURL:    ContentLicenceURL,
+		Attribution:   Attribution,
+		Source:        s.source.String(),
+	}
+}
+
+// ExportJSONLines writes all approved products to w in the JSON Lines format.
+// The first line contains the export metadata, with the "type" key set to "metadata".
+// Every other line contains a single product, with the "type" key set to "product".
+func (s *ExportService) ExportJSONLines(w io.Writer) error {
+	enc := json.NewEncoder(w)
+
+	meta := struct {
+		Type string `json:"type"`
+		model.ExportMetadata
+	}{"metadata", s.GetExportMetadata()}
+	if err := enc.Encode(meta); err != nil {
+		return fmt.Errorf("error when writing export metadata: %w", err)
+	}
+
+	return s.eachProduct("", func(p model.ExportedProduct) error {
+		line := struct {
+			Type string `json:"type"`
+			model.ExportedProduct
+		}{"product", p}
+		return enc.Encode(line)
+	})
+}
+
+// ExportCSV writes all approved products in the given leaf category to w in the CSV format.
+// Besides the product ID and category path, there's a column for every field in the category's schema,
+// named in the fieldset_slug.field_name format, so the file can be imported back with ImportProducts.
+func (s *ExportService) ExportCSV(categorySlug string, w io.Writer) error {
+	cat, err := s.products.getLeafCategory(categorySlug)
+	if err != nil {
+		return err
+	}
+
+	header := []string{"id", "category_path"}
+	for _, fset := range cat.Fieldsets {
+		for _, field := range fset.Fields {
+			header = append(header, fset.Slug+"."+field.Name)
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return fmt.Errorf("error when writing CSV header: %w", err)
+	}
+
+	err = s.eachProduct(cat.Slug, func(p model.ExportedProduct) error {
+		names := make([]string, len(p.CategoryPath))
+		for i, b := range p.CategoryPath {
+			names[i] = b.Name
+		}
+
+		record := []string{fmt.Sprint(p.ID), strings.Join(names, " > ")}
+		for _, fset := range cat.Fieldsets {
+			for _, field := range fset.Fields {
+				record = append(record, formatCell(p.Data[fset.Slug][field.Name]))
+			}
+		}
+		return cw.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// formatCell formats a field value the way parseCell expects it.
+func formatCell(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case bool:
+		if v {
+			return "yes"
+		}
+		return "no"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// RefreshSQLiteExport builds a new SQLite export every interval, until the context is cancelled.
+// Building the database reads every product, so it's done in the background rather than for each download.
+func (s *ExportService) RefreshSQLiteExport(c context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.buildSQLiteExport(); err != nil {
+			log.Printf("Error when building SQLite export: %s", err)
+		}
+
+		select {
+		case <-c.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// buildSQLiteExport writes a new SQLite export to a temporary file, and replaces the previous one with it once it's complete.
+func (s *ExportService) buildSQLiteExport() error {
+	f, err := os.CreateTemp("", "enably-export-*.sqlite")
+	if err != nil {
+		return fmt.Errorf("error when creating temporary file: %w", err)
+	}
+	f.Close()
+
+	meta := s.GetExportMetadata()
+	if err := s.writeDatabase(f.Name(), meta); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	s.sqliteMu.Lock()
+	previous := s.sqliteExport
+	s.sqliteExport = &sqliteSnapshot{path: f.Name(), generatedAt: meta.GeneratedAt}
+	s.sqliteMu.Unlock()
+
+	// Downloads which already opened the previous file can keep reading it.
+	if previous != nil {
+		os.Remove(previous.path)
+	}
+	return nil
+}
+
+// OpenSQLiteExport opens the latest self-contained SQLite database containing the categories, fieldsets and all approved products,
+// and returns it along with the time it was generated. The caller must close it.
+// The database is built by RefreshSQLiteExport, so it may be missing the most recent changes.
+func (s *ExportService) OpenSQLiteExport() (io.ReadSeekCloser, time.Time, error) {
+	s.sqliteMu.Lock()
+	defer s.sqliteMu.Unlock()
+
+	if s.sqliteExport == nil {
+		return nil, time.Time{}, model.UserFacingError{
+			HTTPStatusCode:    http.StatusServiceUnavailable,
+			UserFacingMessage: "the SQLite export is still being generated, please try again in a minute",
+		}
+	}
+
+	f, err := os.Open(s.sqliteExport.path)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("error when opening exported database: %w", err)
+	}
+	return f, s.sqliteExport.generatedAt, nil
+}
+
+func (s *ExportService) writeDatabase(path string, meta model.ExportMetadata) (err error) {
+	db, err := s.openDB(path)
+	if err != nil {
+		return fmt.Errorf("error when creating export database: %w", err)
+	}
+	defer func() {
+		if closeErr := db.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("error when closing export database: %w", closeErr)
+		}
+	}()
+
+	if err := db.SetMetadata(meta); err != nil {
+		return fmt.Errorf("error when writing export metadata: %w", err)
+	}
+
+	cats, err := s.meta.GetAllCategories()
+	if err != nil {
+		return fmt.Errorf("error when retrieving categories: %w", err)
+	}
+	for _, cat := range cats {
+		if err := db.AddCategory(cat); err != nil {
+			return fmt.Errorf("error when exporting category %s: %w", cat.Slug, err)
+		}
+	}
+
+	fsets, err := s.meta.GetAllFieldsets()
+	if err != nil {
+		return fmt.Errorf("error when retrieving fieldsets: %w", err)
+	}
+	for _, fset := range fsets {
+		if err := db.AddFieldset(fset); err != nil {
+			return fmt.Errorf("error when exporting fieldset %s: %w", fset.Slug, err)
+		}
+	}
+
+	return s.eachProduct("", db.AddProduct)
+}
+
+// eachProduct calls fn for every published product in the given category, or in all categories if categorySlug is empty.
+// Products are streamed from the store rather than loaded all at once.
+func (s *ExportService) eachProduct(categorySlug string, fn func(model.ExportedProduct) error) error {
+	err := s.products.store.StreamPublishedProducts(context.Background(), categorySlug, func(p model.Product) error {
+		if err := s.products.SetDerivedFields(&p); err != nil {
+			return fmt.Errorf("error when setting derived fields for product %d: %w", p.ID, err)
+		}
+
+		return fn(model.ExportedProduct{
+			ID:           p.ID,
+			CategorySlug: p.CategorySlug,
+			CategoryPath: p.CategoryPath,
+			Name:         p.Name,
+			Description:  p.Description,
+			Data:         p.Data,
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("error when exporting products: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package app_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/mikolysz/enably/app"
+	"github.com/mikolysz/enably/model"
+	"github.com/mikolysz/enably/store"
+)
+
+func TestSQLiteExport(t *testing.T) {
+	// The exports are built in temporary files, which would otherwise be left behind.
+	t.Setenv("TMPDIR", t.TempDir())
+
+	source, err := url.Parse("https://enably.me")
+	if err != nil {
+		t.Fatal(err)
+	}
+	openDB := func(path string) (app.ExportDatabase, error) {
+		return store.NewSQLiteExportDatabase(path)
+	}
+	export := app.NewExportService(newTestProductsService(t, conditionalSchema, nil), openDB, source)
+
+	var userErr model.UserFacingError
+	if _, _, err := export.OpenSQLiteExport(); !errors.As(err, &userErr) || userErr.HTTPStatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("OpenSQLiteExport returned %v before the first export was built, want a 503 error", err)
+	}
+
+	first := openTestSQLiteExport(t, export)
+	defer first.Close()
+
+	second := openTestSQLiteExport(t, export)
+	defer second.Close()
+
+	// Downloads which started before the export was rebuilt can still be finished.
+	for _, f := range []io.ReadSeekCloser{first, second} {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(data, []byte("SQLite format 3\x00")) {
+			t.Errorf("the export isn't an SQLite database, it's %d bytes long", len(data))
+		}
+	}
+}
+
+// openTestSQLiteExport builds a new SQLite export and opens it.
+func openTestSQLiteExport(t *testing.T, export *app.ExportService) io.ReadSeekCloser {
+	// With a cancelled context, RefreshSQLiteExport builds a single export and returns.
+	c, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now().UTC().Truncate(time.Second)
+	export.RefreshSQLiteExport(c, time.Hour)
+
+	f, generatedAt, err := export.OpenSQLiteExport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if generatedAt.Before(start) {
+		t.Errorf("the export was generated at %s, want it built now", generatedAt)
+	}
+	return f
+}
@@ -0,0 +1,262 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mikolysz/enably/model"
+)
+
+// Formats accepted by ImportProducts.
+const (
+	ImportFormatCSV       = "csv"
+	ImportFormatJSONLines = "jsonl"
+)
+
+// importRow is a single product read from an import file.
+type importRow struct {
+	row  int
+	data map[string]map[string]any
+	err  error // set if the row couldn't be parsed.
+}
+
+// ImportProducts reads products from r and adds them to the given category as products awaiting approval.
+//
+// In CSV files, the first record contains the headers, which are field names in the fieldset_slug.field_name format.
+// In JSON Lines files, each line contains a product in the same format CreateProduct accepts.
+//
+// Every product is validated against the category's schemas, and the valid ones are inserted in a single transaction.
+// If dryRun is true, the products are only validated.
+func (s *ProductsService) ImportProducts(categorySlug, format string, r io.Reader, dryRun bool) (model.ImportResult, error) {
+	cat, err := s.getLeafCategory(categorySlug)
+	if err != nil {
+		return model.ImportResult{}, err
+	}
+
+	var rows []importRow
+	switch format {
+	case ImportFormatCSV:
+		rows, err = readCSVImport(cat, r)
+	case ImportFormatJSONLines:
+		rows, err = readJSONLinesImport(r)
+	default:
+		err = model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: fmt.Sprintf("unsupported import format %q, use %q or %q", format, ImportFormatCSV, ImportFormatJSONLines),
+		}
+	}
+	if err != nil {
+		return model.ImportResult{}, err
+	}
+
+	res := model.ImportResult{
+		DryRun:     dryRun,
+		Total:      len(rows),
+		ProductIDs: []int{},
+		Errors:     []model.ImportError{},
+	}
+
+	var valid []model.Product
+	for _, row := range rows {
+		if row.err == nil {
+			row.err = s.validateProductData(cat, row.data)
+		}
+
+		if row.err != nil {
+			res.Errors = append(res.Errors, model.ImportError{Row: row.row, Message: userFacingMessage(row.err)})
+			continue
+		}
+
+		valid = append(valid, model.Product{CategorySlug: cat.Slug, Data: row.data})
+	}
+	res.Valid = len(valid)
+
+	if dryRun || len(valid) == 0 {
+		return res, nil
+	}
+
+	inserted, err := s.store.AddProducts(context.Background(), valid)
+	if err != nil {
+		return model.ImportResult{}, fmt.Errorf("error when inserting imported products: %w", err)
+	}
+	s.invalidateProductCounts()
+
+	for _, p := range inserted {
+		res.ProductIDs = append(res.ProductIDs, p.ID)
+	}
+	return res, nil
+}
+
+func readCSVImport(cat *model.Category, r io.Reader) ([]importRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 0 // all records must have as many fields as the header.
+
+	header, err := cr.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if err != io.EOF && !errors.As(err, &parseErr) {
+			return nil, importReadError("CSV", err)
+		}
+		return nil, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: "the CSV file must start with a header",
+			SecretMessage:     fmt.Sprintf("error when reading CSV header: %s", err),
+		}
+	}
+
+	fields := make([]*model.Field, len(header))
+	fsetSlugs := make([]string, len(header))
+	seen := make(map[string]bool, len(header))
+	for i, name := range header {
+		fsetSlug, field := findField(cat, name)
+		if field == nil {
+			return nil, model.UserFacingError{
+				HTTPStatusCode:    http.StatusBadRequest,
+				UserFacingMessage: fmt.Sprintf("column %q is not a field of category %s, columns must be named fieldset_slug.field_name", name, cat.Slug),
+			}
+		}
+
+		if seen[name] {
+			return nil, model.UserFacingError{
+				HTTPStatusCode:    http.StatusBadRequest,
+				UserFacingMessage: fmt.Sprintf("column %q appears more than once", name),
+			}
+		}
+		seen[name] = true
+		fields[i], fsetSlugs[i] = field, fsetSlug
+	}
+
+	var rows []importRow
+	for rowNum := 2; ; rowNum++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+
+		row := importRow{row: rowNum, data: make(map[string]map[string]any)}
+		for _, fset := range cat.Fieldsets {
+			row.data[fset.Slug] = make(map[string]any)
+		}
+
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, importReadError("CSV", err)
+			}
+			row.err = err
+			rows = append(rows, row)
+			continue
+		}
+
+		for i, cell := range record {
+			// Empty cells mean the field was left blank.
+			if cell == "" {
+				continue
+			}
+
+			value, err := parseCell(fields[i], cell)
+			if err != nil {
+				row.err = fmt.Errorf("%s: %w", header[i], err)
+				break
+			}
+			row.data[fsetSlugs[i]][fields[i].Name] = value
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// findField returns the field with the given name, in the fieldset_slug.field_name format, from the given category.
+func findField(cat *model.Category, name string) (fsetSlug string, field *model.Field) {
+	fsetSlug, fieldName, ok := strings.Cut(name, ".")
+	if !ok {
+		return "", nil
+	}
+
+	for _, fset := range cat.Fieldsets {
+		if fset.Slug == fsetSlug {
+			return fsetSlug, fset.FieldByName(fieldName)
+		}
+	}
+	return "", nil
+}
+
+// parseCell converts the text in a CSV cell to a value of the type the field's schema expects.
+func parseCell(field *model.Field, cell string) (any, error) {
+	if field.Type != "checkbox" {
+		return cell, nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(cell)) {
+	case "yes", "y":
+		return true, nil
+	case "no", "n":
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(strings.TrimSpace(cell))
+	if err != nil {
+		return nil, fmt.Errorf("%q is not yes or no", cell)
+	}
+	return b, nil
+}
+
+func readJSONLinesImport(r io.Reader) ([]importRow, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1024*1024) // product descriptions can be long.
+
+	var rows []importRow
+	for lineNum := 1; sc.Scan(); lineNum++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+
+		row := importRow{row: lineNum, data: map[string]map[string]any{}}
+		if err := json.Unmarshal([]byte(line), &row.data); err != nil {
+			row.err = fmt.Errorf("invalid JSON: %w", err)
+		}
+		rows = append(rows, row)
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, importReadError("JSON Lines", err)
+	}
+	return rows, nil
+}
+
+// importReadError returns the error for an import file in the given format which couldn't be read.
+// Files over the size limit set with http.MaxBytesReader are refused with 413, anything else with 400.
+func importReadError(format string, err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return model.UserFacingError{
+			HTTPStatusCode:    http.StatusRequestEntityTooLarge,
+			UserFacingMessage: fmt.Sprintf("the file is too large, it can't be larger than %d MiB", tooLarge.Limit>>20),
+			SecretMessage:     fmt.Sprintf("error when reading %s: %s", format, err),
+		}
+	}
+
+	return model.UserFacingError{
+		HTTPStatusCode:    http.StatusBadRequest,
+		UserFacingMessage: fmt.Sprintf("the file couldn't be read as %s", format),
+		SecretMessage:     fmt.Sprintf("error when reading %s: %s", format, err),
+	}
+}
+
+// userFacingMessage returns the part of an error that can be shown to users.
+func userFacingMessage(err error) string {
+	var uf model.UserFacingError
+	if errors.As(err, &uf) {
+		return uf.UserFacingMessage
+	}
+	return err.Error()
+}
@@ -1,12 +1,21 @@
 package app
 
 import (
+	"net/url"
+
 	"github.com/mikolysz/enably/model"
 )
 
+// JSONSchemaDraft is the JSON Schema dialect our schemas are written in.
+// We use draft 7, as that's the newest one supported by the validator our frontend uses.
+const JSONSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
 // MetadataService provides information about categories and fieldsets.
 type MetadataService struct {
 	store MetadataStore
+
+	// apiURL is the public URL of the API, used to build the IDs of the JSON schemas we publish.
+	apiURL *url.URL
 }
 
 // MetadataStore lets you retrieve information about categories and fieldsets.
@@ -16,11 +25,13 @@ type MetadataStore interface {
 	FieldsetBySlug(slug string) (*model.Fieldset, error)
 	AllCategories() ([]*model.Category, error)
 	AllFieldsets() ([]*model.Fieldset, error)
+	SchemaVersion() string
 }
 
 // NewMetadataService returns a MetadataService that 	uses the given MetadataStore.
-func NewMetadataService(store MetadataStore) *MetadataService {
-	return &MetadataService{store}
+// apiURL is the public URL of the API, which is where our JSON schemas can be downloaded from.
+func NewMetadataService(store MetadataStore, apiURL *url.URL) *MetadataService {
+	return &MetadataService{store, apiURL}
 }
 
 // GetRootCategory returns a dummy category that contains all top-level categories.
@@ -29,15 +40,61 @@ func (s *MetadataService) GetRootCategory() *model.Category {
 		Slug:          "root",
 		Name:          "Root",
 		Parent:        "",
+		Path:          []*model.Breadcrumb{},
 		Subcategories: s.store.TopLevelCategories(),
 	}
 }
 
+// GetCategoryTree returns a tree of all categories, rooted at a dummy root category.
+// The product counts in the returned nodes are left at zero.
+func (s *MetadataService) GetCategoryTree() (*model.CategoryTreeNode, error) {
+	root := s.GetRootCategory()
+	return s.buildTree(root.Slug, root.Name, root.Subcategories)
+}
+
+func (s *MetadataService) buildTree(slug, name string, subcats []*model.SubcategoryInfo) (*model.CategoryTreeNode, error) {
+	node := &model.CategoryTreeNode{
+		Slug:           slug,
+		Name:           name,
+		IsLeafCategory: len(subcats) == 0,
+		Subcategories:  make([]*model.CategoryTreeNode, 0, len(subcats)),
+	}
+
+	for _, info := range subcats {
+		cat, err := s.store.CategoryBySlug(info.Slug)
+		if err != nil {
+			return nil, err
+		}
+
+		child, err := s.buildTree(cat.Slug, cat.Name, cat.Subcategories)
+		if err != nil {
+			return nil, err
+		}
+		node.Subcategories = append(node.Subcategories, child)
+	}
+	return node, nil
+}
+
 // GetCategory returns the category with the given slug.
 func (s *MetadataService) GetCategory(slug string) (*model.Category, error) {
 	return s.store.CategoryBySlug(slug)
 }
 
+// GetCategoryPath returns the ancestors of the category with the given slug, starting from the top-level one,
+// followed by the category itself.
+func (s *MetadataService) GetCategoryPath(slug string) ([]*model.Breadcrumb, error) {
+	cat, err := s.store.CategoryBySlug(slug)
+	if err != nil {
+		return nil, err
+	}
+	return cat.Path, nil
+}
+
+// GetSchemaVersion returns an identifier which changes whenever the schema does.
+func (s *MetadataService) GetSchemaVersion() string {
+	return s.store.SchemaVersion()
+}
+
 // GetAllCategories returns all categories.
 func (s *MetadataService) GetAllCategories() ([]*model.Category, error) {
 	return s.store.AllCategories()
@@ -49,39 +106,176 @@ func (s *MetadataService) GetAllFieldsets() ([]*model.Fieldset, error) {
 }
 
 // GetSchemasForCategory returns the JSON schemas for all fieldsets in the given category.
-func (s *MetadataService) GetSchemasForCategory(category *model.Category) (map[string]any, error) {
-	fsets := make(map[string]any)
+// The schemas are keyed by fieldset slug, in the order the fieldsets appear in the category.
+func (s *MetadataService) GetSchemasForCategory(category *model.Category) (*model.OrderedMap, error) {
+	fsets := model.NewOrderedMap()
 
 	for _, fieldset := range category.Fieldsets {
 		fieldsetSchema, err := s.getSchemaForFieldset(fieldset)
 		if err != nil {
 			return nil, err
 		}
-		fsets[fieldset.Slug] = fieldsetSchema
+		fsets.Set(fieldset.Slug, fieldsetSchema)
 	}
 	return fsets, nil
 }
 
+// GetFieldsetSchema returns the JSON schema for the fieldset with the given slug.
+func (s *MetadataService) GetFieldsetSchema(slug string) (map[string]any, error) {
+	fset, err := s.store.FieldsetBySlug(slug)
+	if err != nil {
+		return nil, err
+	}
+	return s.getSchemaForFieldset(fset)
+}
+
+// GetProductSchema returns a JSON schema for whole products in the category with the given slug.
+// It references the schemas of the category's fieldsets by their IDs,
+// which are also the URLs the schemas can be retrieved from.
+func (s *MetadataService) GetProductSchema(categorySlug string) (map[string]any, error) {
+	cat, err := s.store.CategoryBySlug(categorySlug)
+	if err != nil {
+		return nil, err
+	}
+
+	props := model.NewOrderedMap()
+	required := make([]string, 0, len(cat.Fieldsets))
+	for _, fset := range cat.Fieldsets {
+		props.Set(fset.Slug, map[string]any{"$ref": s.FieldsetSchemaID(fset.Slug)})
+		required = append(required, fset.Slug)
+	}
+
+	return map[string]any{
+		"$schema":    JSONSchemaDraft,
+		"$id":        s.ProductSchemaID(cat.Slug),
+		"title":      cat.Name,
+		"type":       "object",
+		"properties": props,
+		"required":   required,
+	}, nil
+}
+
+// FieldsetSchemaID returns the ID of the JSON schema for the fieldset with the given slug.
+func (s *MetadataService) FieldsetSchemaID(slug string) string {
+	return s.apiURL.JoinPath("schemas", slug).String()
+}
+
+// ProductSchemaID returns the ID of the JSON schema for products in the category with the given slug.
+func (s *MetadataService) ProductSchemaID(categorySlug string) string {
+	return s.apiURL.JoinPath("schemas", "categories", categorySlug).String()
+}
+
 // GetSchemaForFieldset returns the JSON schema for the given fieldset.
+// Properties are listed in the same order as the fields in the fieldset.
 func (s *MetadataService) getSchemaForFieldset(fieldset *model.Fieldset) (map[string]any, error) {
-	props := make(map[string]any)
+	props := model.NewOrderedMap()
 
 	for _, field := range fieldset.Fields {
-		props[field.Name] = getSchemaForField(field)
+		props.Set(field.Name, getSchemaForField(field))
 	}
 
 	required := make([]string, 0, len(fieldset.Fields))
+	var conditions []any
 	for _, field := range fieldset.Fields {
+		if field.Condition == nil {
+			if !field.Optional {
+				required = append(required, field.Name)
+			}
+			continue
+		}
+
+		// Conditional fields are only required when their condition holds, and must be left out when it doesn't,
+		// so that values of hidden fields don't end up in the data.
+		condition := map[string]any{
+			"if": map[string]any{
+				"properties": map[string]any{
+					field.Condition.Field: map[string]any{"enum": field.Condition.OneOf},
+				},
+				"required": []string{field.Condition.Field},
+			},
+			// A false schema fails for any value, and gives a clearer error message than "not": {"required": [...]}.
+			"else": map[string]any{
+				"properties": map[string]any{field.Name: false},
+			},
+		}
 		if !field.Optional {
-			required = append(required, field.Name)
+			condition["then"] = map[string]any{"required": []string{field.Name}}
 		}
+		conditions = append(conditions, condition)
 	}
 
-	return map[string]any{
+	schema := map[string]any{
+		"$schema":    JSONSchemaDraft,
+		"$id":        s.FieldsetSchemaID(fieldset.Slug),
+		"title":      fieldset.Name,
 		"type":       "object",
 		"properties": props,
 		"required":   required,
-	}, nil
+	}
+
+	if len(conditions) > 0 {
+		schema["allOf"] = conditions
+	}
+	return schema, nil
+}
+
+// widgets maps field types to the names of the form widgets that should be used for them.
+// The names are the ones used by react-jsonschema-form.
+var widgets = map[string]string{
+	"short-text":    "text",
+	"textarea":      "textarea",
+	"url":           "uri",
+	"radio-buttons": "radio",
+	"dropdown":      "select",
+	"checkbox":      "checkbox",
+}
+
+// GetUISchemaForCategory returns a UI schema for each fieldset in the given category, keyed by fieldset slug.
+//
+// UI schemas complement the JSON schemas returned by GetSchemasForCategory. They describe how a form should be rendered,
+// i.e. the section heading, the order of the fields and the widget, help text and autocomplete hint for each field.
+// They use the format understood by react-jsonschema-form.
+func (s *MetadataService) GetUISchemaForCategory(category *model.Category) (*model.OrderedMap, error) {
+	fsets := model.NewOrderedMap()
+	for _, fieldset := range category.Fieldsets {
+		fsets.Set(fieldset.Slug, getUISchemaForFieldset(fieldset))
+	}
+	return fsets, nil
+}
+
+func getUISchemaForFieldset(fieldset *model.Fieldset) *model.OrderedMap {
+	schema := model.NewOrderedMap()
+	schema.Set("ui:title", fieldset.Name)
+
+	order := make([]string, 0, len(fieldset.Fields))
+	for _, field := range fieldset.Fields {
+		order = append(order, field.Name)
+	}
+	schema.Set("ui:order", order)
+
+	for _, field := range fieldset.Fields {
+		schema.Set(field.Name, getUISchemaForField(field))
+	}
+	return schema
+}
+
+func getUISchemaForField(field *model.Field) *model.OrderedMap {
+	schema := model.NewOrderedMap()
+	if widget, ok := widgets[field.Type]; ok {
+		schema.Set("ui:widget", widget)
+	}
+
+	if field.Description != "" {
+		schema.Set("ui:help", field.Description)
+	}
+
+	switch {
+	case field.Autocomplete != "":
+		schema.Set("ui:autocomplete", field.Autocomplete)
+	case field.Type == "short-text" || field.Type == "url":
+		schema.Set("ui:autocomplete", "off")
+	}
+	return schema
 }
 
 // getSchemaForField returns the JSON schema for the given field.
@@ -0,0 +1,104 @@
+package app_test
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/mikolysz/enably/app"
+	"github.com/mikolysz/enably/model"
+	"github.com/mikolysz/enably/store"
+)
+
+// conditionalSchema has a required and an optional field which only apply to paid software.
+const conditionalSchema = `
+[categories.apps]
+name = "Apps"
+fieldsets = ["software"]
+name_field = "software.name"
+description_field = "software.name"
+featured_fields = ["software.name"]
+
+[fieldsets.software]
+name = "Software"
+
+[[fields.software]]
+name = "name"
+label = "Name"
+type = "short-text"
+
+[[fields.software]]
+name = "free_or_paid"
+label = "Free or paid"
+type = "radio-buttons"
+options = ["Free", "Paid"]
+optional = true
+
+[[fields.software]]
+name = "price"
+label = "Price"
+type = "short-text"
+condition = { field = "free_or_paid", one_of = ["Paid"] }
+
+[[fields.software]]
+name = "trial"
+label = "Free trial"
+type = "short-text"
+optional = true
+condition = { field = "free_or_paid", one_of = ["Paid"] }
+`
+
+func TestConditionalFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string // a part of the error message, or empty if the product is valid.
+	}{
+		{"paid with a price", `{"name": "JAWS", "free_or_paid": "Paid", "price": "$95"}`, ""},
+		{"paid with a price and a trial", `{"name": "JAWS", "free_or_paid": "Paid", "price": "$95", "trial": "40 minutes"}`, ""},
+		{"paid without a price", `{"name": "JAWS", "free_or_paid": "Paid"}`, "price"},
+		{"free", `{"name": "NVDA", "free_or_paid": "Free"}`, ""},
+		{"free with a price", `{"name": "NVDA", "free_or_paid": "Free", "price": "$0"}`, "price"},
+		{"free with a trial", `{"name": "NVDA", "free_or_paid": "Free", "trial": "forever"}`, "trial"},
+		{"unknown", `{"name": "Narrator"}`, ""},
+		{"unknown with a price", `{"name": "Narrator", "price": "Included in Windows"}`, "price"},
+	}
+
+	prod := newTestProductsService(t, conditionalSchema, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := prod.CreateProduct("apps", []byte(`{"software": `+tt.data+`}`), 0)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("CreateProduct returned %v, want a valid product", err)
+				}
+				return
+			}
+
+			var userErr model.UserFacingError
+			if !errors.As(err, &userErr) || !strings.Contains(userErr.UserFacingMessage, tt.wantErr) {
+				t.Errorf("CreateProduct returned %v, want an error about %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// newTestProductsService returns a ProductsService for the categories and fieldsets in the given TOML schema,
+// which keeps products in memory and tells submitters about decisions with the given notifier, if any.
+func newTestProductsService(t *testing.T, schema string, notifier *app.Notifier) *app.ProductsService {
+	metaStore, err := store.NewTOMLMetadataStore([]byte(schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiURL, err := url.Parse("https://enably.me/api/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prod, err := app.NewProductsService(app.NewMetadataService(metaStore, apiURL), store.NewMemoryProductsStore(), notifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return prod
+}
@@ -0,0 +1,180 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+
+	"github.com/mikolysz/enably/model"
+	"github.com/mikolysz/enably/pkg/email"
+)
+
+// Notifier emails submitters when a moderator decides on one of their products or translations.
+// Users can opt out of these emails in their profile.
+type Notifier struct {
+	users       *UsersService
+	emailSender email.Sender
+
+	// frontendURL is where the links in the emails point to.
+	frontendURL *url.URL
+}
+
+// NewNotifier returns a new Notifier.
+func NewNotifier(users *UsersService, emailSender email.Sender, frontendURL *url.URL) *Notifier {
+	return &Notifier{
+		users:       users,
+		emailSender: emailSender,
+		frontendURL: frontendURL,
+	}
+}
+
+// notification is an email about a moderator's decision.
+type notification struct {
+	subject string
+
+	// paragraphs are separated by blank lines.
+	text     string
+	link     *url.URL
+	linkText string
+}
+
+// ProductApproved tells the submitter of the given product that it's now public,
+// and which changes the moderator made to it, if any.
+// The product must have its derived fields set.
+func (n *Notifier) ProductApproved(c context.Context, p model.Product, edits string) {
+	text := fmt.Sprintf("A moderator has approved \"%s\", thank you for submitting it! It can now be seen by everyone.", p.Name)
+	if edits != "" {
+		text += "\n\nThey made the following changes before publishing it:\n\n" + edits
+	}
+
+	n.notify(c, p.SubmittedBy, notification{
+		subject:  fmt.Sprintf("\"%s\" is now on Enably", p.Name),
+		text:     text,
+		link:     n.productURL(p.ID),
+		linkText: "See the product",
+	})
+}
+
+// ProductRejected tells the submitter of the given product why it was rejected.
+// The product must have its derived fields set.
+func (n *Notifier) ProductRejected(c context.Context, p model.Product) {
+	n.notify(c, p.SubmittedBy, notification{
+		subject:  fmt.Sprintf("\"%s\" wasn't accepted", p.Name),
+		text:     fmt.Sprintf("A moderator has rejected \"%s\", giving the following reason:\n\n%s", p.Name, p.RejectionReason),
+		link:     n.frontendURL.JoinPath("submissions"),
+		linkText: "See your submissions",
+	})
+}
+
+// ChangesRequested tells the submitter of the given product what a moderator wants changed before it can be published.
+// The product must have its derived fields set.
+func (n *Notifier) ChangesRequested(c context.Context, p model.Product, comments string) {
+	n.notify(c, p.SubmittedBy, notification{
+		subject:  fmt.Sprintf("\"%s\" needs some changes", p.Name),
+		text:     fmt.Sprintf("A moderator has asked for some changes to \"%s\" before it can be published:\n\n%s", p.Name, comments),
+		link:     n.submissionURL(p.ID),
+		linkText: "Make the changes",
+	})
+}
+
+// CommentAdded sends a moderator's comment to the submitter of the given product.
+// The product must have its derived fields set.
+func (n *Notifier) CommentAdded(c context.Context, p model.Product, comment string) {
+	n.notify(c, p.SubmittedBy, notification{
+		subject:  fmt.Sprintf("A moderator commented on \"%s\"", p.Name),
+		text:     fmt.Sprintf("A moderator has commented on \"%s\":\n\n%s", p.Name, comment),
+		link:     n.submissionURL(p.ID),
+		linkText: "Reply",
+	})
+}
+
+// TranslationApproved tells the submitter of the given translation that it's now shown on the given product.
+func (n *Notifier) TranslationApproved(c context.Context, t model.Translation, p model.Product) {
+	n.notify(c, t.SubmittedBy, notification{
+		subject:  fmt.Sprintf("Your translation of \"%s\" is now on Enably", p.Name),
+		text:     fmt.Sprintf("A moderator has approved your translation of \"%s\" into %s, thank you!", p.Name, languageName(t.Locale)),
+		link:     n.productURL(p.ID),
+		linkText: "See the product",
+	})
+}
+
+// TranslationRejected tells the submitter of the given translation that it won't be shown on the given product.
+func (n *Notifier) TranslationRejected(c context.Context, t model.Translation, p model.Product) {
+	n.notify(c, t.SubmittedBy, notification{
+		subject:  fmt.Sprintf("Your translation of \"%s\" wasn't accepted", p.Name),
+		text:     fmt.Sprintf("A moderator has rejected your translation of \"%s\" into %s.", p.Name, languageName(t.Locale)),
+		link:     n.productURL(p.ID),
+		linkText: "See the product",
+	})
+}
+
+// languageName returns the English name of the given BCP 47 locale, e.g. "Brazilian Portuguese" for "pt-BR".
+func languageName(locale string) string {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return locale
+	}
+
+	if name := display.English.Tags().Name(tag); name != "" {
+		return name
+	}
+	return locale
+}
+
+// submissionURL returns the URL of the page where the submitter of the given product can follow its review.
+func (n *Notifier) submissionURL(id int) *url.URL {
+	return n.frontendURL.JoinPath("submissions", strconv.Itoa(id))
+}
+
+// productURL returns the URL of the given product's page on the frontend.
+func (n *Notifier) productURL(id int) *url.URL {
+	return n.frontendURL.JoinPath("products", strconv.Itoa(id))
+}
+
+// notify emails the given notification to the user with the given ID, unless they opted out.
+// Nothing is sent for submissions made without logging in.
+// Errors are only logged, as the decision has already been made by then.
+func (n *Notifier) notify(c context.Context, userID *int64, msg notification) {
+	if userID == nil {
+		return
+	}
+
+	u, err := n.users.GetUserByID(c, *userID)
+	if err != nil {
+		log.Printf("Error when notifying submitter: %s", err)
+		return
+	}
+
+	if !u.ModerationEmails {
+		return
+	}
+
+	link := msg.link.String()
+	optOut := "You're getting this email because you submitted this to Enably. " +
+		"If you'd rather not get emails about your submissions, you can turn them off at " + n.frontendURL.JoinPath("submissions").String()
+
+	var htmlParagraphs []string
+	for _, p := range strings.Split(msg.text, "\n\n") {
+		htmlParagraphs = append(htmlParagraphs, "<p>"+strings.ReplaceAll(html.EscapeString(p), "\n", "<br>")+"</p>")
+	}
+
+	m := email.Message{
+		Recipient:        u.Email,
+		Subject:          msg.subject,
+		PlainTextContent: msg.text + "\n\n" + msg.linkText + ": " + link + "\n\n" + optOut,
+		HTMLContent: strings.Join(htmlParagraphs, "") +
+			"<p><a href=\"" + html.EscapeString(link) + "\">" + msg.linkText + "</a></p>" +
+			"<p><small>" + html.EscapeString(optOut) + "</small></p>",
+	}
+
+	if err := n.emailSender.Send(m); err != nil {
+		log.Printf("Error when notifying user %d: %s", u.ID, err)
+	}
+}
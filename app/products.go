@@ -1,10 +1,18 @@
 package app
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"net/http"
+	"sort"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/mikolysz/enably/model"
 	"github.com/santhosh-tekuri/jsonschema/v5"
@@ -15,24 +23,78 @@ type ProductsService struct {
 	meta  *MetadataService
 	store ProductsStore
 
+	// notifier emails submitters about moderators' decisions. It may be nil.
+	notifier *Notifier
+
 	// compiled JSON schemas for all fieldsets, used for validation.
 	schemas map[string]*jsonschema.Schema
+
+	// counts caches the number of products in each category for the category tree.
+	// It is nil if the cache needs to be refreshed.
+	countsMu sync.Mutex
+	counts   map[string]model.ProductCounts
 }
 
 // ProductsStore is an interface for a store that can retrieve, create and update products.
 type ProductsStore interface {
 	AddProduct(c context.Context, p model.Product) (model.Product, error)
+	AddProducts(c context.Context, ps []model.Product) ([]model.Product, error)
 	GetProductsByCategory(c context.Context, slug string) ([]model.Product, error)
 	GetProductByID(c context.Context, id int) (model.Product, error)
-	GetProductsRequiringApproval(c context.Context) ([]model.Product, error)
-	ApproveProduct(c context.Context, id int) error
-	RejectProduct(c context.Context, id int) error
+	GetProductsBySubmitter(c context.Context, userID int64) ([]model.Product, error)
+
+	// StreamPublishedProducts calls fn for every published product in the given category, or all categories if categorySlug is empty.
+	// It stops at the first error returned by fn. fn may take long, e.g. when writing to a slow client, and can use the store itself.
+	StreamPublishedProducts(c context.Context, categorySlug string, fn func(model.Product) error) error
+
+	// The methods below change the status of a product. They return model.ErrInvalidTransition if its current status
+	// doesn't allow it, see model.ProductStatus.CanBecome, checking it in the same step as the change.
+	// Approving, rejecting and requesting changes also return model.ErrProductClaimed if another moderator's claim
+	// on the product hasn't expired.
+	ApproveProduct(c context.Context, id int, by model.Moderator) error
+
+	// EditAndApproveProduct replaces the product's data, approves it and records the edit in a single transaction.
+	EditAndApproveProduct(c context.Context, id int, data map[string]map[string]any, edit model.ModeratorEdit, by model.Moderator) error
+	GetModeratorEdits(c context.Context, productID int) ([]model.ModeratorEdit, error)
+	RejectProduct(c context.Context, id int, reason string, by model.Moderator) error
+	DiscontinueProduct(c context.Context, id int, by model.Moderator) error
+	WithdrawProduct(c context.Context, id int) error
+
+	// RequestProductChanges marks the product as waiting for changes and adds the given comments in a single transaction.
+	RequestProductChanges(c context.Context, id int, comments []model.Comment, by model.Moderator) error
+
+	// ResubmitProduct returns model.ErrNoChangesRequested unless the product is waiting for changes.
+	ResubmitProduct(c context.Context, id int, data map[string]map[string]any) error
+	AddProductComment(c context.Context, cm model.Comment) (model.Comment, error)
+	GetProductComments(c context.Context, productID int) ([]model.Comment, error)
+	CountProductsByCategory(c context.Context) (map[string]model.ProductCounts, error)
+
+	GetModerationQueue(c context.Context, q model.QueueQuery) (model.QueuePage, error)
+
+	// ClaimProduct returns model.ErrProductClaimed if another moderator claimed the product after expiredBefore.
+	ClaimProduct(c context.Context, id int, userID int64, expiredBefore time.Time) error
+	ReleaseProductClaim(c context.Context, id int, userID int64) error
+	AddModeratorNote(c context.Context, n model.ModeratorNote) (model.ModeratorNote, error)
+	GetModeratorNotes(c context.Context, productID int) ([]model.ModeratorNote, error)
+
+	AddTranslation(c context.Context, t model.Translation) (model.Translation, error)
+	GetApprovedTranslations(c context.Context, productIDs []int, locale string) ([]model.Translation, error)
+	GetTranslationsRequiringApproval(c context.Context) ([]model.Translation, error)
+
+	// GetTranslationByID returns model.ErrItemNotFound if there's no translation with the given ID.
+	GetTranslationByID(c context.Context, id int) (model.Translation, error)
+	ApproveTranslation(c context.Context, id int, by model.Moderator) error
+	RejectTranslation(c context.Context, id int, by model.Moderator) error
+
+	// ListModerationActions returns the most recent moderation actions, newest first.
+	ListModerationActions(c context.Context, limit int) ([]model.ModerationAction, error)
 }
 
 // NewProductsService returns a new ProductsService.
-// The returned service will use the given MetadataService to retrieve category information.
-func NewProductsService(meta *MetadataService, products ProductsStore) (*ProductsService, error) {
-	s := &ProductsService{meta: meta, store: products}
+// The returned service will use the given MetadataService to retrieve category information,
+// and the given Notifier, if any, to tell submitters about moderators' decisions.
+func NewProductsService(meta *MetadataService, products ProductsStore, notifier *Notifier) (*ProductsService, error) {
+	s := &ProductsService{meta: meta, store: products, notifier: notifier}
 
 	// Compile all  schemas
 	s.schemas = make(map[string]*jsonschema.Schema)
@@ -42,6 +104,9 @@ func NewProductsService(meta *MetadataService, products ProductsStore) (*Product
 		return nil, fmt.Errorf("error when retrieving fieldsets: %w", err)
 	}
 
+	// Register all schemas with the compiler first, so that references between them can be resolved
+	// without downloading anything.
+	compiler := jsonschema.NewCompiler()
 	for _, fset := range fsets {
 		schema, err := meta.getSchemaForFieldset(fset)
 		if err != nil {
@@ -53,10 +118,14 @@ func NewProductsService(meta *MetadataService, products ProductsStore) (*Product
 		if err != nil {
 			return nil, fmt.Errorf("error when encoding schema for fieldset %s: %w", fset.Slug, err)
 		}
-		// Our schemas don't have a URL, but jsonschema requires one, so let's just make one up.
-		url := fmt.Sprintf("https://enably.me/schemas/%s", fset.Slug)
 
-		compiled, err := jsonschema.CompileString(url, string(encoded))
+		if err := compiler.AddResource(meta.FieldsetSchemaID(fset.Slug), bytes.NewReader(encoded)); err != nil {
+			return nil, fmt.Errorf("error when adding JSON schema for fieldset %s: %w", fset.Slug, err)
+		}
+	}
+
+	for _, fset := range fsets {
+		compiled, err := compiler.Compile(meta.FieldsetSchemaID(fset.Slug))
 		if err != nil {
 			return nil, fmt.Errorf("error when compiling JSON schema for fieldset %s: %w", fset.Slug, err)
 		}
@@ -67,56 +136,143 @@ func NewProductsService(meta *MetadataService, products ProductsStore) (*Product
 	return s, nil
 }
 
-// CreateProduct creates a product in the specified category.
+// CreateProduct creates a product in the specified category, which must not have any subcategories.
 //
 // Accepts the slug of the category to create the product in and a map of fieldset slugs to
 // decoded JSON representations that satisfy the corresponding fieldset's schema.
-func (s *ProductsService) CreateProduct(categorySlug string, jsonData []byte) (model.Product, error) {
-	// Validate the received data:
+// submittedBy is the ID of the user submitting the product, or zero if they're not logged in.
+func (s *ProductsService) CreateProduct(categorySlug string, jsonData []byte, submittedBy int64) (model.Product, error) {
+	cat, err := s.getLeafCategory(categorySlug)
+	if err != nil {
+		return model.Product{}, err
+	}
 
+	decoded, err := s.decodeProductData(cat, jsonData)
+	if err != nil {
+		return model.Product{}, err
+	}
+
+	p := model.Product{
+		CategorySlug: categorySlug,
+		Data:         decoded,
+	}
+	if submittedBy != 0 {
+		p.SubmittedBy = &submittedBy
+	}
+
+	prod, err := s.store.AddProduct(context.Background(), p)
+	if err != nil {
+		return model.Product{}, fmt.Errorf("error when inserting product: %w", err)
+	}
+
+	s.invalidateProductCounts()
+	return prod, nil
+}
+
+// getLeafCategory returns the category with the given slug, or an error if it can't contain products.
+func (s *ProductsService) getLeafCategory(slug string) (*model.Category, error) {
+	cat, err := s.meta.GetCategory(slug)
+	if err != nil {
+		return nil, fmt.Errorf("error when retrieving category %s: %w", slug, err)
+	}
+
+	if !cat.IsLeafCategory() {
+		return nil, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: fmt.Sprintf("category %s has subcategories, please add products to one of them instead", slug),
+		}
+	}
+	return cat, nil
+}
+
+// decodeProductData decodes the JSON data of a product in the given category and validates it.
+func (s *ProductsService) decodeProductData(cat *model.Category, jsonData []byte) (map[string]map[string]any, error) {
 	decoded := map[string]map[string]any{}
 	if err := json.Unmarshal(jsonData, &decoded); err != nil {
-		// FIXME: This should cause a BadRequest, not an internalServerError.
-		return model.Product{}, fmt.Errorf("error when unmarshalling product JSON: %w", err)
+		return nil, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: "a product must be a JSON object mapping fieldset slugs to objects of field values",
+			SecretMessage:     fmt.Sprintf("error when unmarshalling product JSON: %s", err),
+		}
 	}
 
-	// Validate each fieldset's data against the corresponding schema.
-	cat, err := s.meta.GetCategory(categorySlug)
-	if err != nil {
-		return model.Product{}, fmt.Errorf("error when retrieving category %s: %w", categorySlug, err)
+	if err := s.validateProductData(cat, decoded); err != nil {
+		return nil, err
 	}
+	return decoded, nil
+}
 
+// validateProductData validates each fieldset's data against the corresponding schema.
+func (s *ProductsService) validateProductData(cat *model.Category, data map[string]map[string]any) error {
 	for _, fset := range cat.Fieldsets {
-		fsetData, ok := decoded[fset.Slug]
+		fsetData, ok := data[fset.Slug]
 		if !ok {
-			// FIXME: This should cause a BadRequest, not an internalServerError.
-			return model.Product{}, fmt.Errorf("product in category %s doesn't contain fieldset %s", categorySlug, fset.Slug)
+			return model.UserFacingError{
+				HTTPStatusCode:    http.StatusBadRequest,
+				UserFacingMessage: fmt.Sprintf("product in category %s doesn't contain fieldset %s", cat.Slug, fset.Slug),
+			}
 		}
 
 		if err := s.schemas[fset.Slug].Validate(fsetData); err != nil {
-			// FIXME: This should cause a BadRequest, not an internalServerError.
-			return model.Product{}, fmt.Errorf("error when validating schema for fieldset %s: %w", fset.Slug, err)
+			return model.UserFacingError{
+				HTTPStatusCode:    http.StatusBadRequest,
+				UserFacingMessage: fmt.Sprintf("invalid %s: %s", fset.Slug, validationMessage(err)),
+				SecretMessage:     fmt.Sprintf("error when validating schema for fieldset %s: %s", fset.Slug, err),
+			}
 		}
 	}
+	return nil
+}
 
-	prod, err := s.store.AddProduct(context.Background(), model.Product{
-		CategorySlug: categorySlug,
-		Data:         decoded,
-	})
-	if err != nil {
-		return model.Product{}, fmt.Errorf("error when inserting product: %w", err)
+// validationMessage turns a schema validation error into a message that can be shown to users.
+// The library reports problems in no particular order, so they're sorted by field to keep messages stable.
+func validationMessage(err error) string {
+	var ve *jsonschema.ValidationError
+	if !errors.As(err, &ve) {
+		return err.Error()
 	}
 
-	return prod, nil
+	var leaves []*jsonschema.ValidationError
+	var collect func(ve *jsonschema.ValidationError)
+	collect = func(ve *jsonschema.ValidationError) {
+		if len(ve.Causes) == 0 {
+			leaves = append(leaves, ve)
+		}
+		for _, cause := range ve.Causes {
+			collect(cause)
+		}
+	}
+	collect(ve)
+
+	sort.Slice(leaves, func(i, j int) bool {
+		if leaves[i].InstanceLocation != leaves[j].InstanceLocation {
+			return leaves[i].InstanceLocation < leaves[j].InstanceLocation
+		}
+		return leaves[i].Message < leaves[j].Message
+	})
+
+	msgs := make([]string, len(leaves))
+	for i, leaf := range leaves {
+		msgs[i] = leaf.Message
+		if field := strings.TrimPrefix(leaf.InstanceLocation, "/"); field != "" {
+			msgs[i] = field + ": " + leaf.Message
+		}
+	}
+	return strings.Join(msgs, "; ")
 }
 
 // GetProductsByCategory returns all products in the specified category.
-func (s *ProductsService) GetProductsByCategory(categorySlug string) ([]model.Product, error) {
+// If locale is not empty, the products' texts are translated into it wherever a translation exists.
+func (s *ProductsService) GetProductsByCategory(categorySlug, locale string) ([]model.Product, error) {
 	prods, err := s.store.GetProductsByCategory(context.Background(), categorySlug)
 	if err != nil {
 		return nil, fmt.Errorf("error when retrieving products: %w", err)
 	}
 
+	if err := s.translate(prods, locale); err != nil {
+		return nil, err
+	}
+
 	for i := range prods {
 		if err := s.SetDerivedFields(&prods[i]); err != nil {
 			return nil, fmt.Errorf("error when setting derived fields for product %d: %w", prods[i].ID, err)
@@ -125,8 +281,34 @@ func (s *ProductsService) GetProductsByCategory(categorySlug string) ([]model.Pr
 	return prods, nil
 }
 
-// GetProductByID returns the product with the specified ID.
-func (s *ProductsService) GetProductByID(id int) (model.Product, error) {
+// GetProductByID returns the product with the specified ID, as long as it can be seen by everyone.
+// Discontinued products are returned too, unlike in GetProductsByCategory, so that links to them keep working.
+// If locale is not empty, the product's texts are translated into it wherever a translation exists.
+// Other products can only be seen by their submitters and moderators, see GetSubmittedProducts and GetProductForModeration.
+func (s *ProductsService) GetProductByID(id int, locale string) (model.Product, error) {
+	prod, err := s.store.GetProductByID(context.Background(), id)
+	if err != nil {
+		return model.Product{}, fmt.Errorf("error when retrieving product %d: %w", id, err)
+	}
+
+	if !prod.Status.Public() {
+		return model.Product{}, model.ErrProductNotFound
+	}
+
+	prods := []model.Product{prod}
+	if err := s.translate(prods, locale); err != nil {
+		return model.Product{}, err
+	}
+	prod = prods[0]
+
+	if err := s.SetDerivedFields(&prod); err != nil {
+		return model.Product{}, fmt.Errorf("error when setting derived fields for product %d: %w", prod.ID, err)
+	}
+	return prod, nil
+}
+
+// GetProductForModeration returns the product with the specified ID, whatever its status.
+func (s *ProductsService) GetProductForModeration(id int) (model.Product, error) {
 	prod, err := s.store.GetProductByID(context.Background(), id)
 	if err != nil {
 		return model.Product{}, fmt.Errorf("error when retrieving product %d: %w", id, err)
@@ -138,11 +320,152 @@ func (s *ProductsService) GetProductByID(id int) (model.Product, error) {
 	return prod, nil
 }
 
-// GetProductsRequiringApproval returns all products that need approval by the mod team.
-func (s *ProductsService) GetProductsNeedingApproval() ([]model.Product, error) {
-	prods, err := s.store.GetProductsRequiringApproval(context.Background())
+// ApproveProduct publishes the product with the specified ID on behalf of the given moderator.
+// Pending products are approved this way, but also rejected ones after an appeal, and discontinued ones which are back on the market.
+// If the approval contains changes, they're applied first, see editAndApproveProduct.
+func (s *ProductsService) ApproveProduct(id int, approval model.Approval, by model.Moderator) error {
+	notify, err := s.approveProduct(id, approval, by)
 	if err != nil {
-		return nil, fmt.Errorf("error when retrieving products: %w", err)
+		return err
+	}
+
+	notify()
+	return nil
+}
+
+// approveProduct approves the product like ApproveProduct, but returns a function which notifies its submitter
+// instead of doing it right away, so that BulkModerate can send the emails after deciding on every product.
+func (s *ProductsService) approveProduct(id int, approval model.Approval, by model.Moderator) (notify func(), err error) {
+	p, err := s.checkTransition(id, model.StatusPublished)
+	if err != nil {
+		return nil, err
+	}
+
+	edits, err := s.editAndApproveProduct(p, approval.Changes, by)
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidateProductCounts()
+
+	// Submitters have already been thanked for discontinued products.
+	if p.Status == model.StatusDiscontinued {
+		return func() {}, nil
+	}
+	return func() {
+		s.notifyAboutProduct(id, func(c context.Context, p model.Product) {
+			s.notifier.ProductApproved(c, p, edits)
+		})
+	}, nil
+}
+
+// RejectProduct rejects the product with the specified ID on behalf of the given moderator.
+// The product is kept, and its submitter can see the reason.
+func (s *ProductsService) RejectProduct(id int, rejection model.Rejection, by model.Moderator) error {
+	notify, err := s.rejectProduct(id, rejection, by)
+	if err != nil {
+		return err
+	}
+
+	notify()
+	return nil
+}
+
+// rejectProduct is to RejectProduct what approveProduct is to ApproveProduct.
+func (s *ProductsService) rejectProduct(id int, rejection model.Rejection, by model.Moderator) (notify func(), err error) {
+	if _, err := s.checkTransition(id, model.StatusRejected); err != nil {
+		return nil, err
+	}
+
+	reason, err := rejectionReason(rejection)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.store.RejectProduct(context.Background(), id, reason, by); err != nil {
+		return nil, fmt.Errorf("error when rejecting product %d: %w", id, err)
+	}
+
+	s.invalidateProductCounts()
+	return func() { s.notifyAboutProduct(id, s.notifier.ProductRejected) }, nil
+}
+
+// notifyAboutProduct passes the product with the given ID to notify, if it has a submitter to notify.
+// Errors are only logged, as the decision has already been saved.
+func (s *ProductsService) notifyAboutProduct(id int, notify func(context.Context, model.Product)) {
+	if s.notifier == nil {
+		return
+	}
+
+	c := context.Background()
+	p, err := s.store.GetProductByID(c, id)
+	if err != nil {
+		log.Printf("Error when retrieving product %d to notify its submitter: %s", id, err)
+		return
+	}
+
+	if p.SubmittedBy == nil {
+		return
+	}
+
+	if err := s.SetDerivedFields(&p); err != nil {
+		log.Printf("Error when setting derived fields for product %d: %s", id, err)
+		return
+	}
+	notify(c, p)
+}
+
+// rejectionReason returns the text shown to the submitter of a rejected product.
+func rejectionReason(r model.Rejection) (string, error) {
+	custom := strings.TrimSpace(r.Reason)
+	if utf8.RuneCountInString(custom) > model.MaxRejectionReasonLength {
+		return "", model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: fmt.Sprintf("the reason can be at most %d characters long", model.MaxRejectionReasonLength),
+		}
+	}
+
+	var reasons []string
+	if r.CannedReason != "" {
+		canned, ok := cannedRejectionReason(r.CannedReason)
+		if !ok {
+			return "", model.UserFacingError{
+				HTTPStatusCode:    http.StatusBadRequest,
+				UserFacingMessage: fmt.Sprintf("unknown canned reason %q", r.CannedReason),
+			}
+		}
+		reasons = append(reasons, canned)
+	}
+
+	if custom != "" {
+		reasons = append(reasons, custom)
+	}
+
+	if len(reasons) == 0 {
+		return "", model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: "please give a reason for the rejection, so that the submitter knows what was wrong",
+		}
+	}
+	return strings.Join(reasons, "\n\n"), nil
+}
+
+// cannedRejectionReason returns the text of the canned rejection reason with the given code.
+func cannedRejectionReason(code string) (string, bool) {
+	for _, r := range model.CannedRejectionReasons {
+		if r.Code == code {
+			return r.Text, true
+		}
+	}
+	return "", false
+}
+
+// GetSubmittedProducts returns all products submitted by the user with the given ID, newest first,
+// whatever their status, so that the user can see how their submissions are doing.
+func (s *ProductsService) GetSubmittedProducts(userID int64) ([]model.Product, error) {
+	prods, err := s.store.GetProductsBySubmitter(context.Background(), userID)
+	if err != nil {
+		return nil, fmt.Errorf("error when retrieving products submitted by user %d: %w", userID, err)
 	}
 
 	for i := range prods {
@@ -150,24 +473,63 @@ func (s *ProductsService) GetProductsNeedingApproval() ([]model.Product, error)
 			return nil, fmt.Errorf("error when setting derived fields for product %d: %w", prods[i].ID, err)
 		}
 	}
-
 	return prods, nil
 }
 
-// ApproveProduct approves the product with the specified ID.
-func (s *ProductsService) ApproveProduct(id int) error {
-	if err := s.store.ApproveProduct(context.Background(), id); err != nil {
-		return fmt.Errorf("error when approving product %d: %w", id, err)
+// GetCategoryTree returns a tree of all categories, along with the number of published products in each.
+// If includePending is true, the numbers of products awaiting approval are included too.
+func (s *ProductsService) GetCategoryTree(includePending bool) (*model.CategoryTreeNode, error) {
+	root, err := s.meta.GetCategoryTree()
+	if err != nil {
+		return nil, fmt.Errorf("error when retrieving category tree: %w", err)
 	}
-	return nil
+
+	counts, err := s.productCounts()
+	if err != nil {
+		return nil, err
+	}
+
+	setTreeCounts(root, counts, includePending)
+	return root, nil
 }
 
-// RejectProduct rejects the product with the specified ID.
-func (s *ProductsService) RejectProduct(id int) error {
-	if err := s.store.RejectProduct(context.Background(), id); err != nil {
-		return fmt.Errorf("error when rejecting product %d: %w", id, err)
+// setTreeCounts fills in the product counts of node and all its descendants, and returns the totals for node.
+func setTreeCounts(node *model.CategoryTreeNode, counts map[string]model.ProductCounts, includePending bool) model.ProductCounts {
+	total := counts[node.Slug]
+	for _, child := range node.Subcategories {
+		childTotal := setTreeCounts(child, counts, includePending)
+		total.Approved += childTotal.Approved
+		total.Pending += childTotal.Pending
 	}
-	return nil
+
+	node.ApprovedProducts = total.Approved
+	if includePending {
+		pending := total.Pending
+		node.PendingProducts = &pending
+	}
+	return total
+}
+
+// productCounts returns the number of products in each category, using the cache if possible.
+func (s *ProductsService) productCounts() (map[string]model.ProductCounts, error) {
+	s.countsMu.Lock()
+	defer s.countsMu.Unlock()
+
+	if s.counts == nil {
+		counts, err := s.store.CountProductsByCategory(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("error when counting products: %w", err)
+		}
+		s.counts = counts
+	}
+	return s.counts, nil
+}
+
+// invalidateProductCounts makes the next call to productCounts retrieve fresh counts from the store.
+func (s *ProductsService) invalidateProductCounts() {
+	s.countsMu.Lock()
+	defer s.countsMu.Unlock()
+	s.counts = nil
 }
 
 // SetDerivedFields sets the name, description and featured fields of the given product.
@@ -178,6 +540,8 @@ func (s *ProductsService) SetDerivedFields(p *model.Product) error {
 		return fmt.Errorf("error when retrieving category %s: %w", p.CategorySlug, err)
 	}
 
+	p.CategoryPath = cat.Path
+
 	untypedName, err := s.getField(cat.NameField, p.Data)
 	if err != nil {
 		return fmt.Errorf("error when retrieving name field for product %d: %w", p.ID, err)
@@ -201,14 +565,14 @@ func (s *ProductsService) SetDerivedFields(p *model.Product) error {
 
 	p.Description = description
 
-	p.FeaturedFields = map[string]any{}
+	p.FeaturedFields = model.NewOrderedMap()
 	for _, field := range cat.FeaturedFields {
 		value, err := s.getField(field, p.Data)
 		if err != nil {
 			return fmt.Errorf("error when retrieving featured field %s for product %d: %w", field, p.ID, err)
 		}
 
-		p.FeaturedFields[field] = value
+		p.FeaturedFields.Set(field, value)
 	}
 
 	return nil
@@ -0,0 +1,326 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mikolysz/enably/model"
+)
+
+// DefaultQueuePageSize is the number of products returned from the moderation queue when the query doesn't specify a limit.
+const DefaultQueuePageSize = 50
+
+// GetModerationQueue returns a page of the submissions waiting for a moderator's decision:
+// new and resubmitted products, and edits, which are translations of published products.
+// If categorySlug isn't empty, only submissions in that category and its subcategories are included.
+// Expired claims are left out.
+func (s *ProductsService) GetModerationQueue(categorySlug string, q model.QueueQuery) (model.QueuePage, error) {
+	switch q.SubmissionType {
+	case "", model.SubmissionNew, model.SubmissionResubmission, model.SubmissionEdit:
+	default:
+		return model.QueuePage{}, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: fmt.Sprintf("the submission type must be %s, %s or %s", model.SubmissionNew, model.SubmissionResubmission, model.SubmissionEdit),
+		}
+	}
+
+	if q.Limit == 0 {
+		q.Limit = DefaultQueuePageSize
+	}
+
+	if q.Limit < 0 || q.Limit > model.MaxQueuePageSize || q.Offset < 0 {
+		return model.QueuePage{}, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: fmt.Sprintf("the limit must be between 1 and %d, and the offset can't be negative", model.MaxQueuePageSize),
+		}
+	}
+
+	if categorySlug != "" {
+		slugs, err := s.leafCategorySlugs(categorySlug)
+		if err != nil {
+			return model.QueuePage{}, err
+		}
+		q.CategorySlugs = slugs
+	}
+
+	var page model.QueuePage
+	var err error
+	switch q.SubmissionType {
+	case model.SubmissionNew, model.SubmissionResubmission:
+		page, err = s.store.GetModerationQueue(context.Background(), q)
+	default:
+		page, err = s.getQueueWithEdits(q)
+	}
+	if err != nil {
+		return model.QueuePage{}, fmt.Errorf("error when retrieving the moderation queue: %w", err)
+	}
+
+	for i := range page.Items {
+		item := &page.Items[i]
+		if err := s.SetDerivedFields(&item.Product); err != nil {
+			return model.QueuePage{}, fmt.Errorf("error when setting derived fields for product %d: %w", item.ID, err)
+		}
+
+		if item.Claim != nil && item.Claim.Expired() {
+			item.Claim = nil
+		}
+	}
+	return page, nil
+}
+
+// getQueueWithEdits returns a page of the moderation queue including edits, which are kept apart from the products.
+// Unless the query only asks for edits, the products which could end up on the page are merged with them.
+func (s *ProductsService) getQueueWithEdits(q model.QueueQuery) (model.QueuePage, error) {
+	items, err := s.getQueuedEdits(q.CategorySlugs)
+	if err != nil {
+		return model.QueuePage{}, err
+	}
+	total := len(items)
+
+	if q.SubmissionType == "" {
+		pq := q
+		pq.Limit, pq.Offset = q.Offset+q.Limit, 0
+		products, err := s.store.GetModerationQueue(context.Background(), pq)
+		if err != nil {
+			return model.QueuePage{}, err
+		}
+
+		// Products go first, so that they stay ahead of edits queued at the same time.
+		items = append(products.Items, items...)
+		total += products.Total
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i].QueuedAt, items[j].QueuedAt
+		if q.NewestFirst {
+			return a.After(b)
+		}
+		return a.Before(b)
+	})
+
+	if q.Offset > len(items) {
+		return model.QueuePage{Total: total}, nil
+	}
+	items = items[q.Offset:]
+	if len(items) > q.Limit {
+		items = items[:q.Limit]
+	}
+	return model.QueuePage{Items: items, Total: total}, nil
+}
+
+// getQueuedEdits returns the pending translations as queue items, along with the products they translate.
+// If categorySlugs isn't empty, only translations of products in those categories are included.
+func (s *ProductsService) getQueuedEdits(categorySlugs []string) ([]model.QueueItem, error) {
+	ts, err := s.store.GetTranslationsRequiringApproval(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("error when retrieving translations: %w", err)
+	}
+
+	categories := make(map[string]bool, len(categorySlugs))
+	for _, slug := range categorySlugs {
+		categories[slug] = true
+	}
+
+	var items []model.QueueItem
+	for i := range ts {
+		t := &ts[i]
+		p, err := s.store.GetProductByID(context.Background(), t.ProductID)
+		if err != nil {
+			return nil, fmt.Errorf("error when retrieving product %d: %w", t.ProductID, err)
+		}
+
+		if len(categories) > 0 && !categories[p.CategorySlug] {
+			continue
+		}
+
+		items = append(items, model.QueueItem{
+			Product:        p,
+			SubmissionType: model.SubmissionEdit,
+			Translation:    t,
+			QueuedAt:       t.CreatedAt,
+		})
+	}
+	return items, nil
+}
+
+// leafCategorySlugs returns the slugs of the categories containing products under the category with the given slug,
+// or the slug itself if it's a leaf category.
+func (s *ProductsService) leafCategorySlugs(slug string) ([]string, error) {
+	cat, err := s.meta.GetCategory(slug)
+	if err != nil {
+		return nil, fmt.Errorf("error when retrieving category %s: %w", slug, err)
+	}
+
+	if cat.IsLeafCategory() {
+		return []string{slug}, nil
+	}
+
+	var slugs []string
+	for _, sub := range cat.Subcategories {
+		subSlugs, err := s.leafCategorySlugs(sub.Slug)
+		if err != nil {
+			return nil, err
+		}
+		slugs = append(slugs, subSlugs...)
+	}
+	return slugs, nil
+}
+
+// ClaimProduct marks the product with the specified ID as being reviewed by the given moderator, so that others can skip it.
+// Claims expire after model.ClaimDuration, and claiming a product again renews the claim.
+func (s *ProductsService) ClaimProduct(id int, by model.Moderator) error {
+	if err := requireModeratorAccount(by); err != nil {
+		return err
+	}
+
+	p, err := s.store.GetProductByID(context.Background(), id)
+	if err != nil {
+		return fmt.Errorf("error when retrieving product %d: %w", id, err)
+	}
+
+	if p.Status != model.StatusPending {
+		return model.UserFacingError{
+			HTTPStatusCode:    http.StatusConflict,
+			UserFacingMessage: "only products waiting for review can be claimed",
+		}
+	}
+
+	if err := s.store.ClaimProduct(context.Background(), id, by.UserID, time.Now().Add(-model.ClaimDuration)); err != nil {
+		return fmt.Errorf("error when claiming product %d: %w", id, err)
+	}
+	return nil
+}
+
+// ReleaseProductClaim removes the given moderator's claim on the product with the specified ID, if they have one.
+func (s *ProductsService) ReleaseProductClaim(id int, by model.Moderator) error {
+	if err := requireModeratorAccount(by); err != nil {
+		return err
+	}
+
+	if err := s.store.ReleaseProductClaim(context.Background(), id, by.UserID); err != nil {
+		return fmt.Errorf("error when releasing claim on product %d: %w", id, err)
+	}
+	return nil
+}
+
+// requireModeratorAccount returns an error if the given moderator used the moderation API key, as claims need an account.
+func requireModeratorAccount(by model.Moderator) error {
+	if by.UserID == 0 {
+		return model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: "please log in with your moderator account to claim products, the moderation API key can't be used for this",
+		}
+	}
+	return nil
+}
+
+// AddModeratorNote adds an internal note by the given moderator about the product with the specified ID.
+// Notes are only shown to moderators.
+func (s *ProductsService) AddModeratorNote(id int, text string, by model.Moderator) (model.ModeratorNote, error) {
+	if _, err := s.store.GetProductByID(context.Background(), id); err != nil {
+		return model.ModeratorNote{}, fmt.Errorf("error when retrieving product %d: %w", id, err)
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return model.ModeratorNote{}, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: "notes can't be empty",
+		}
+	}
+
+	if utf8.RuneCountInString(text) > model.MaxNoteLength {
+		return model.ModeratorNote{}, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: fmt.Sprintf("notes can be at most %d characters long", model.MaxNoteLength),
+		}
+	}
+
+	n, err := s.store.AddModeratorNote(context.Background(), model.ModeratorNote{ProductID: id, AuthorID: moderatorUserID(by), Text: text})
+	if err != nil {
+		return model.ModeratorNote{}, fmt.Errorf("error when adding note to product %d: %w", id, err)
+	}
+	return n, nil
+}
+
+// GetModeratorNotes returns the internal notes about the product with the specified ID, oldest first.
+func (s *ProductsService) GetModeratorNotes(id int) ([]model.ModeratorNote, error) {
+	if _, err := s.store.GetProductByID(context.Background(), id); err != nil {
+		return nil, fmt.Errorf("error when retrieving product %d: %w", id, err)
+	}
+
+	notes, err := s.store.GetModeratorNotes(context.Background(), id)
+	if err != nil {
+		return nil, fmt.Errorf("error when retrieving notes on product %d: %w", id, err)
+	}
+	return notes, nil
+}
+
+// BulkModerate approves or rejects several products at once on behalf of the given moderator.
+// Each product is moderated separately, so a failure doesn't stop the others. The results are in the order of the IDs.
+// Submitters are notified in the background, once every product has been decided on.
+func (s *ProductsService) BulkModerate(a model.BulkAction, by model.Moderator) ([]model.BulkResult, error) {
+	if len(a.ProductIDs) == 0 || len(a.ProductIDs) > model.MaxBulkActionSize {
+		return nil, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: fmt.Sprintf("please give between 1 and %d product IDs", model.MaxBulkActionSize),
+		}
+	}
+
+	var moderate func(id int) (notify func(), err error)
+	switch a.Action {
+	case model.ActionApprove:
+		moderate = func(id int) (func(), error) { return s.approveProduct(id, model.Approval{}, by) }
+	case model.ActionReject:
+		// Check the reason up front, so that a bad one isn't reported for every product.
+		if a.Rejection == nil {
+			return nil, model.UserFacingError{
+				HTTPStatusCode:    http.StatusBadRequest,
+				UserFacingMessage: "please give a reason for rejecting the products",
+			}
+		}
+
+		if _, err := rejectionReason(*a.Rejection); err != nil {
+			return nil, err
+		}
+		moderate = func(id int) (func(), error) { return s.rejectProduct(id, *a.Rejection, by) }
+	default:
+		return nil, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: fmt.Sprintf("the action must be %s or %s", model.ActionApprove, model.ActionReject),
+		}
+	}
+
+	results := make([]model.BulkResult, 0, len(a.ProductIDs))
+	var notifications []func()
+	for _, id := range a.ProductIDs {
+		result := model.BulkResult{ProductID: id}
+		notify, err := moderate(id)
+		if err != nil {
+			var uf model.UserFacingError
+			if !errors.As(err, &uf) {
+				log.Printf("Error when moderating product %d in bulk: %s", id, err)
+				uf = model.NewInternalServerError(err)
+			}
+			result.Error = uf.UserFacingMessage
+		} else {
+			notifications = append(notifications, notify)
+		}
+		results = append(results, result)
+	}
+
+	// Sending an email for each of up to MaxBulkActionSize products would keep the moderator waiting, so it's done in the background.
+	go func() {
+		for _, notify := range notifications {
+			notify()
+		}
+	}()
+	return results, nil
+}
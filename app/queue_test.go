@@ -0,0 +1,81 @@
+package app_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/mikolysz/enably/app"
+	"github.com/mikolysz/enably/model"
+	"github.com/mikolysz/enably/pkg/email"
+	"github.com/mikolysz/enably/store"
+)
+
+// blockedEmails is an email.Sender whose sends wait until release is closed, like slow deliveries would.
+type blockedEmails struct {
+	release chan struct{}
+	sent    chan email.Message
+}
+
+func (e blockedEmails) Send(m email.Message) error {
+	<-e.release
+	e.sent <- m
+	return nil
+}
+
+func TestBulkModerateNotifiesInBackground(t *testing.T) {
+	c := context.Background()
+	frontend, err := url.Parse("https://enably.me")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := app.NewUsersService(store.NewMemoryUsersStore())
+	submitter, err := users.GetOrCreateUser(c, "submitter@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := blockedEmails{release: make(chan struct{}), sent: make(chan email.Message, model.MaxBulkActionSize)}
+	prod := newTestProductsService(t, conditionalSchema, app.NewNotifier(users, sender, frontend))
+
+	var ids []int
+	for _, name := range []string{"NVDA", "JAWS", "Narrator"} {
+		p, err := prod.CreateProduct("apps", []byte(`{"software": {"name": "`+name+`"}}`), submitter.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, p.ID)
+	}
+
+	done := make(chan []model.BulkResult)
+	go func() {
+		results, err := prod.BulkModerate(model.BulkAction{Action: model.ActionApprove, ProductIDs: ids}, model.Moderator{})
+		if err != nil {
+			t.Error(err)
+		}
+		done <- results
+	}()
+
+	select {
+	case results := <-done:
+		for _, r := range results {
+			if r.Error != "" {
+				t.Errorf("approving product %d failed: %s", r.ProductID, r.Error)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("BulkModerate didn't return while the emails were still being sent")
+	}
+
+	close(sender.release)
+	for range ids {
+		select {
+		case m := <-sender.sent:
+			if m.Recipient != submitter.Email {
+				t.Errorf("an email was sent to %s, want %s", m.Recipient, submitter.Email)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("BulkModerate didn't notify the submitter about every product")
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mikolysz/enably/model"
+)
+
+// RateLimiter counts requests in fixed windows and rejects the ones over the limit.
+type RateLimiter struct {
+	store RateLimitStore
+}
+
+// RateLimitStore keeps count of the requests made under each rate limit key.
+// An in-memory store works for a single instance, several instances need to share a database.
+type RateLimitStore interface {
+	// Hit counts a request under the given key. If the current window is over, a new one is started, which ends at resetsAt.
+	// It returns the number of requests in the current window, including this one, and when that window ends.
+	Hit(c context.Context, key string, now, resetsAt time.Time) (hits int, windowEnd time.Time, err error)
+
+	// DeleteExpiredRateLimits deletes the counts of the windows which ended before the given time.
+	DeleteExpiredRateLimits(c context.Context, now time.Time) error
+}
+
+// NewRateLimiter returns a new RateLimiter.
+func NewRateLimiter(store RateLimitStore) *RateLimiter {
+	return &RateLimiter{store: store}
+}
+
+// Take counts a request under the given key, e.g. "login:ip:127.0.0.1".
+// returns a model.RateLimitError if the limit has been exceeded.
+func (l *RateLimiter) Take(c context.Context, key string, limit model.RateLimit) error {
+	if limit.Requests <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	hits, windowEnd, err := l.store.Hit(c, key, now, now.Add(limit.Window))
+	if err != nil {
+		return fmt.Errorf("error when counting request for %s: %w", key, err)
+	}
+
+	if hits > limit.Requests {
+		return model.RateLimitError{RetryAfter: windowEnd.Sub(now)}
+	}
+	return nil
+}
+
+// DeleteExpiredRateLimits deletes the counts which are no longer needed every interval, until the context is cancelled.
+func (l *RateLimiter) DeleteExpiredRateLimits(c context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := l.store.DeleteExpiredRateLimits(c, time.Now()); err != nil {
+			log.Printf("Error when deleting expired rate limits: %s", err)
+		}
+
+		select {
+		case <-c.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mikolysz/enably/model"
+)
+
+// checkTransition returns the product with the specified ID, or an error if it can't change to the given status.
+// This only reports the error before any other work is done, the store checks the status again when changing it,
+// in case it changed in the meantime.
+func (s *ProductsService) checkTransition(id int, to model.ProductStatus) (model.Product, error) {
+	p, err := s.store.GetProductByID(context.Background(), id)
+	if err != nil {
+		return model.Product{}, fmt.Errorf("error when retrieving product %d: %w", id, err)
+	}
+
+	if !p.Status.CanBecome(to) {
+		return model.Product{}, model.ErrInvalidTransition(p.Status, to)
+	}
+	return p, nil
+}
+
+// WithdrawProduct takes back the product with the specified ID, as long as it was submitted by the user with the given ID
+// and hasn't been published or rejected yet. Withdrawn products are kept, but they can't change anymore.
+func (s *ProductsService) WithdrawProduct(id int, userID int64) (model.Product, error) {
+	p, err := s.getSubmission(id, userID)
+	if err != nil {
+		return model.Product{}, err
+	}
+
+	if !p.Status.CanBecome(model.StatusWithdrawn) {
+		return model.Product{}, model.ErrInvalidTransition(p.Status, model.StatusWithdrawn)
+	}
+
+	if err := s.store.WithdrawProduct(context.Background(), id); err != nil {
+		return model.Product{}, fmt.Errorf("error when withdrawing product %d: %w", id, err)
+	}
+	s.invalidateProductCounts()
+
+	p, err = s.getSubmission(id, userID)
+	if err != nil {
+		return model.Product{}, err
+	}
+
+	if err := s.SetDerivedFields(&p); err != nil {
+		return model.Product{}, fmt.Errorf("error when setting derived fields for product %d: %w", id, err)
+	}
+	return p, nil
+}
+
+// DiscontinueProduct marks the published product with the specified ID as no longer being made or sold,
+// on behalf of the given moderator. It stops being listed, but can still be seen by everyone.
+// Approving it again publishes it.
+func (s *ProductsService) DiscontinueProduct(id int, by model.Moderator) error {
+	if _, err := s.checkTransition(id, model.StatusDiscontinued); err != nil {
+		return err
+	}
+
+	if err := s.store.DiscontinueProduct(context.Background(), id, by); err != nil {
+		return fmt.Errorf("error when discontinuing product %d: %w", id, err)
+	}
+
+	s.invalidateProductCounts()
+	return nil
+}
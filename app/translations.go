@@ -0,0 +1,327 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"unicode/utf8"
+
+	"golang.org/x/text/language"
+
+	"github.com/mikolysz/enably/model"
+)
+
+// OriginalLocale is the language products are submitted in.
+// Requesting a product in this language returns it untranslated.
+var OriginalLocale = language.English
+
+// translatableFieldTypes are the types of fields that contain free-form text, and hence can be translated.
+var translatableFieldTypes = map[string]bool{
+	"short-text": true,
+	"textarea":   true,
+}
+
+// SubmitTranslation submits translations of some of the texts of the product with the given ID.
+// The translation needs to be approved by a moderator before it is shown.
+//
+// Accepts a locale in the BCP 47 format and a JSON object mapping fieldset slugs to objects,
+// which in turn map field names to the translated texts.
+// submittedBy is the ID of the user submitting the translation, or zero if they're not logged in.
+func (s *ProductsService) SubmitTranslation(productID int, locale string, jsonData []byte, submittedBy int64) (model.Translation, error) {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return model.Translation{}, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: fmt.Sprintf("invalid locale: %q", locale),
+			SecretMessage:     err.Error(),
+		}
+	}
+
+	if baseLanguage(tag) == baseLanguage(OriginalLocale) {
+		return model.Translation{}, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: fmt.Sprintf("products are already written in %s", OriginalLocale),
+		}
+	}
+
+	decoded := map[string]map[string]string{}
+	if err := json.Unmarshal(jsonData, &decoded); err != nil {
+		return model.Translation{}, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: "translations must be a JSON object mapping fieldsets to objects of translated texts",
+			SecretMessage:     err.Error(),
+		}
+	}
+
+	prod, err := s.store.GetProductByID(context.Background(), productID)
+	if err != nil {
+		return model.Translation{}, fmt.Errorf("error when retrieving product %d: %w", productID, err)
+	}
+
+	if !prod.Status.Public() {
+		return model.Translation{}, model.ErrProductNotFound
+	}
+
+	cat, err := s.meta.GetCategory(prod.CategorySlug)
+	if err != nil {
+		return model.Translation{}, fmt.Errorf("error when retrieving category %s: %w", prod.CategorySlug, err)
+	}
+
+	if err := validateTranslation(cat, decoded); err != nil {
+		return model.Translation{}, err
+	}
+
+	t := model.Translation{
+		ProductID: productID,
+		Locale:    tag.String(),
+		Data:      decoded,
+	}
+	if submittedBy != 0 {
+		t.SubmittedBy = &submittedBy
+	}
+
+	t, err = s.store.AddTranslation(context.Background(), t)
+	if err != nil {
+		return model.Translation{}, fmt.Errorf("error when inserting translation: %w", err)
+	}
+	return t, nil
+}
+
+// validateTranslation checks that the translation only contains non-empty texts of limited length
+// for translatable fields of the given category.
+func validateTranslation(cat *model.Category, data map[string]map[string]string) error {
+	if len(data) == 0 {
+		return model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: "the translation doesn't contain any fields",
+		}
+	}
+
+	fsets := make(map[string]*model.Fieldset)
+	for _, fset := range cat.Fieldsets {
+		fsets[fset.Slug] = fset
+	}
+
+	for fsetSlug, fields := range data {
+		fset, ok := fsets[fsetSlug]
+		if !ok {
+			return model.UserFacingError{
+				HTTPStatusCode:    http.StatusBadRequest,
+				UserFacingMessage: fmt.Sprintf("category %s doesn't contain fieldset %s", cat.Slug, fsetSlug),
+			}
+		}
+
+		for name, text := range fields {
+			field := fset.FieldByName(name)
+			if field == nil || !translatableFieldTypes[field.Type] {
+				return model.UserFacingError{
+					HTTPStatusCode:    http.StatusBadRequest,
+					UserFacingMessage: fmt.Sprintf("%s.%s is not a translatable field", fsetSlug, name),
+				}
+			}
+
+			if text == "" {
+				return model.UserFacingError{
+					HTTPStatusCode:    http.StatusBadRequest,
+					UserFacingMessage: fmt.Sprintf("the translation of %s.%s is empty", fsetSlug, name),
+				}
+			}
+
+			if utf8.RuneCountInString(text) > model.MaxTranslatedTextLength {
+				return model.UserFacingError{
+					HTTPStatusCode:    http.StatusBadRequest,
+					UserFacingMessage: fmt.Sprintf("the translation of %s.%s can be at most %d characters long", fsetSlug, name, model.MaxTranslatedTextLength),
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// GetTranslationsNeedingApproval returns all translations that need approval by the mod team.
+func (s *ProductsService) GetTranslationsNeedingApproval() ([]model.Translation, error) {
+	ts, err := s.store.GetTranslationsRequiringApproval(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("error when retrieving translations: %w", err)
+	}
+	return ts, nil
+}
+
+// ApproveTranslation approves the translation with the specified ID on behalf of the given moderator.
+// Rejected translations are deleted, so only pending translations can be approved.
+func (s *ProductsService) ApproveTranslation(id int, by model.Moderator) error {
+	t, err := s.store.GetTranslationByID(context.Background(), id)
+	if err != nil {
+		return fmt.Errorf("error when retrieving translation %d: %w", id, err)
+	}
+
+	if t.Approved {
+		return model.ErrTranslationAlreadyApproved
+	}
+
+	if err := s.store.ApproveTranslation(context.Background(), id, by); err != nil {
+		return fmt.Errorf("error when approving translation %d: %w", id, err)
+	}
+
+	s.notifyAboutTranslation(t, s.notifier.TranslationApproved)
+	return nil
+}
+
+// RejectTranslation rejects the translation with the specified ID on behalf of the given moderator.
+func (s *ProductsService) RejectTranslation(id int, by model.Moderator) error {
+	// Rejected translations are deleted, so it's retrieved first to notify its submitter.
+	t, err := s.store.GetTranslationByID(context.Background(), id)
+	if err != nil {
+		return fmt.Errorf("error when retrieving translation %d: %w", id, err)
+	}
+
+	if err := s.store.RejectTranslation(context.Background(), id, by); err != nil {
+		return fmt.Errorf("error when rejecting translation %d: %w", id, err)
+	}
+
+	s.notifyAboutTranslation(t, s.notifier.TranslationRejected)
+	return nil
+}
+
+// notifyAboutTranslation passes the given translation and its product to notify, if it has a submitter to notify.
+// Errors are only logged, as the decision has already been saved.
+func (s *ProductsService) notifyAboutTranslation(t model.Translation, notify func(context.Context, model.Translation, model.Product)) {
+	if s.notifier == nil || t.SubmittedBy == nil {
+		return
+	}
+
+	c := context.Background()
+	p, err := s.store.GetProductByID(c, t.ProductID)
+	if err != nil {
+		log.Printf("Error when retrieving product %d to notify the submitter of translation %d: %s", t.ProductID, t.ID, err)
+		return
+	}
+
+	if err := s.SetDerivedFields(&p); err != nil {
+		log.Printf("Error when setting derived fields for product %d: %s", p.ID, err)
+		return
+	}
+	notify(c, t, p)
+}
+
+// maxModerationActions is how many of the most recent moderation actions are returned.
+const maxModerationActions = 100
+
+// GetModerationActions returns the most recent moderation decisions, newest first.
+func (s *ProductsService) GetModerationActions() ([]model.ModerationAction, error) {
+	actions, err := s.store.ListModerationActions(context.Background(), maxModerationActions)
+	if err != nil {
+		return nil, fmt.Errorf("error when retrieving moderation actions: %w", err)
+	}
+	return actions, nil
+}
+
+// parseLocale returns the most preferred language from a BCP 47 tag or an Accept-Language header value.
+// ok is false if no translation should be applied, i.e. when no locale was requested,
+// it couldn't be parsed, or it is the language products are written in.
+func parseLocale(locale string) (tag language.Tag, ok bool) {
+	if locale == "" {
+		return language.Und, false
+	}
+
+	tags, _, err := language.ParseAcceptLanguage(locale)
+	if err != nil || len(tags) == 0 {
+		return language.Und, false
+	}
+
+	if baseLanguage(tags[0]) == baseLanguage(OriginalLocale) {
+		return language.Und, false
+	}
+	return tags[0], true
+}
+
+// translate replaces the texts in the products' data with their approved translations into the given locale.
+// It must be called before SetDerivedFields, so that names and descriptions are translated too.
+func (s *ProductsService) translate(prods []model.Product, locale string) error {
+	tag, ok := parseLocale(locale)
+	if !ok || len(prods) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(prods))
+	for i, p := range prods {
+		ids[i] = p.ID
+	}
+
+	// Translations for a regional variant, e.g. pt-BR, fall back to the base language.
+	base := baseLanguage(tag)
+	locales := []string{base.String()}
+	if tag.String() != base.String() {
+		locales = append(locales, tag.String())
+	}
+
+	// Later translations override earlier ones, so the more specific locale goes last.
+	var ts []model.Translation
+	for _, l := range locales {
+		found, err := s.store.GetApprovedTranslations(context.Background(), ids, l)
+		if err != nil {
+			return fmt.Errorf("error when retrieving translations: %w", err)
+		}
+		ts = append(ts, found...)
+	}
+
+	byProduct := make(map[int][]model.Translation)
+	for _, t := range ts {
+		byProduct[t.ProductID] = append(byProduct[t.ProductID], t)
+	}
+
+	for i := range prods {
+		if err := s.applyTranslations(&prods[i], tag, byProduct[prods[i].ID]); err != nil {
+			return fmt.Errorf("error when translating product %d: %w", prods[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// applyTranslations replaces the texts in p with the given translations, later translations taking precedence,
+// and records the translatable fields left in the original language.
+func (s *ProductsService) applyTranslations(p *model.Product, tag language.Tag, ts []model.Translation) error {
+	cat, err := s.meta.GetCategory(p.CategorySlug)
+	if err != nil {
+		return fmt.Errorf("error when retrieving category %s: %w", p.CategorySlug, err)
+	}
+
+	translated := make(map[string]bool)
+	for _, t := range ts {
+		for fsetSlug, fields := range t.Data {
+			fsetData, ok := p.Data[fsetSlug]
+			if !ok {
+				continue
+			}
+
+			for name, text := range fields {
+				fsetData[name] = text
+				translated[fsetSlug+"."+name] = true
+			}
+		}
+	}
+
+	p.Locale = tag.String()
+	p.FallbackFields = nil
+	for _, fset := range cat.Fieldsets {
+		for _, field := range fset.Fields {
+			if !translatableFieldTypes[field.Type] {
+				continue
+			}
+
+			name := fset.Slug + "." + field.Name
+			if text, _ := p.Data[fset.Slug][field.Name].(string); text != "" && !translated[name] {
+				p.FallbackFields = append(p.FallbackFields, name)
+			}
+		}
+	}
+	return nil
+}
+
+// baseLanguage returns the language of the given tag without any script or region, e.g. "pt" for "pt-BR".
+func baseLanguage(tag language.Tag) language.Base {
+	base, _ := tag.Base()
+	return base
+}
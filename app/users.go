@@ -0,0 +1,176 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mikolysz/enably/model"
+)
+
+// UsersService manages user accounts and their profiles.
+type UsersService struct {
+	store UsersStore
+}
+
+// UsersStore is an interface for a store that can retrieve, create and update users.
+type UsersStore interface {
+	// GetOrCreateUser returns the user with the given email address, creating one if there's none.
+	GetOrCreateUser(c context.Context, email string) (model.User, error)
+
+	// GetUserByEmail returns model.ErrUserNotFound if there's no user with the given email address.
+	GetUserByEmail(c context.Context, email string) (model.User, error)
+
+	// GetUserByID returns model.ErrUserNotFound if there's no user with the given ID.
+	GetUserByID(c context.Context, id int64) (model.User, error)
+	UpdateUser(c context.Context, u model.User) (model.User, error)
+
+	// SetUserRole returns model.ErrUserNotFound if there's no user with the given email address.
+	SetUserRole(c context.Context, email string, role model.Role) (model.User, error)
+}
+
+// NewUsersService returns a new UsersService.
+func NewUsersService(store UsersStore) *UsersService {
+	return &UsersService{store: store}
+}
+
+// GetOrCreateUser returns the user with the given email address. Accounts are created on first login.
+func (s *UsersService) GetOrCreateUser(c context.Context, email string) (model.User, error) {
+	email, err := model.NormalizeEmail(email)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	u, err := s.store.GetOrCreateUser(c, email)
+	if err != nil {
+		return model.User{}, fmt.Errorf("error when retrieving user %s: %w", email, err)
+	}
+	return u, nil
+}
+
+// GetUser returns the user with the given email address.
+func (s *UsersService) GetUser(c context.Context, email string) (model.User, error) {
+	email, err := model.NormalizeEmail(email)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	u, err := s.store.GetUserByEmail(c, email)
+	if err != nil {
+		return model.User{}, fmt.Errorf("error when retrieving user %s: %w", email, err)
+	}
+	return u, nil
+}
+
+// GetUserByID returns the user with the given ID.
+func (s *UsersService) GetUserByID(c context.Context, id int64) (model.User, error) {
+	u, err := s.store.GetUserByID(c, id)
+	if err != nil {
+		return model.User{}, fmt.Errorf("error when retrieving user %d: %w", id, err)
+	}
+	return u, nil
+}
+
+// UpdateProfile applies the given changes to the profile of the user with the given email address.
+// New users must choose a display name to finish onboarding.
+func (s *UsersService) UpdateProfile(c context.Context, email string, update model.ProfileUpdate) (model.User, error) {
+	u, err := s.GetUser(c, email)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	if update.DisplayName != nil {
+		name, err := validateDisplayName(*update.DisplayName)
+		if err != nil {
+			return model.User{}, err
+		}
+		u.DisplayName = name
+	}
+
+	if update.EmailConsent != nil {
+		u.EmailConsent = *update.EmailConsent
+	}
+
+	if update.ModerationEmails != nil {
+		u.ModerationEmails = *update.ModerationEmails
+	}
+
+	if u.NeedsOnboarding() {
+		if u.DisplayName == "" {
+			return model.User{}, model.UserFacingError{
+				HTTPStatusCode:    http.StatusBadRequest,
+				UserFacingMessage: "please choose a display name",
+			}
+		}
+
+		now := time.Now()
+		u.OnboardedAt = &now
+	}
+
+	u, err = s.store.UpdateUser(c, u)
+	if err != nil {
+		return model.User{}, fmt.Errorf("error when updating user %s: %w", email, err)
+	}
+	return u, nil
+}
+
+// SetRole changes the role of the user with the given email address on behalf of the given admin.
+// Users need to log in at least once before they can be given a role.
+func (s *UsersService) SetRole(c context.Context, by model.Moderator, email string, role model.Role) (model.User, error) {
+	if !role.Valid() {
+		return model.User{}, model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: fmt.Sprintf("unknown role %q, use %s, %s or %s", role, model.RoleContributor, model.RoleModerator, model.RoleAdmin),
+		}
+	}
+
+	u, err := s.GetUser(c, email)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	// This keeps admins from locking themselves out by accident.
+	if u.ID == by.UserID {
+		return model.User{}, model.UserFacingError{
+			HTTPStatusCode:    http.StatusForbidden,
+			UserFacingMessage: "you can't change your own role",
+		}
+	}
+
+	u, err = s.store.SetUserRole(c, u.Email, role)
+	if err != nil {
+		return model.User{}, fmt.Errorf("error when setting role of user %s: %w", email, err)
+	}
+	return u, nil
+}
+
+// validateDisplayName trims the given display name and checks that it's neither empty, too long, nor contains control characters.
+func validateDisplayName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+
+	if name == "" {
+		return "", model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: "the display name can't be empty",
+		}
+	}
+
+	if utf8.RuneCountInString(name) > model.MaxDisplayNameLength {
+		return "", model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: fmt.Sprintf("the display name can be at most %d characters long", model.MaxDisplayNameLength),
+		}
+	}
+
+	if strings.IndexFunc(name, unicode.IsControl) != -1 {
+		return "", model.UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: "the display name can't contain control characters",
+		}
+	}
+	return name, nil
+}
@@ -2,24 +2,55 @@ package main
 
 import (
 	"fmt"
+	"log"
 	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mikolysz/enably/model"
 )
 
+// defaultAPIURL is where the server listens for API requests, used when API_URL isn't set.
+const defaultAPIURL = "http://localhost:8080/api/v1"
+
 type config struct {
+	dbDriver           string // "postgres" or "sqlite"
 	dbConnectionString string
+	migrateOnStart     bool // apply pending migrations at startup, rather than refusing to start.
 	senderEmail        string
 	senderName         string
 	sendgridAPIKey     string
 	frontendURL        *url.URL
+	apiURL             *url.URL
+	redirectAllowlist  []*url.URL // origins besides the frontend users can be redirected to after logging in.
 	moderationAPIKey   string
+	sessionLifetime    time.Duration // how long a session lasts without being used.
+	exportInterval     time.Duration // how often the SQLite export is rebuilt.
+
+	rateLimitStore         string // "database" to share the counts between instances, or "memory".
+	loginRateLimitPerEmail model.RateLimit
+	loginRateLimitPerIP    model.RateLimit
+	exportRateLimitPerIP   model.RateLimit
+	trustProxyHeaders      bool // take client IPs from the X-Forwarded-For and X-Real-IP headers.
 }
 
 func loadConfig() (config, error) {
+	c, err := loadDBConfig()
+	if err != nil {
+		return config{}, err
+	}
 
-	var c config
+	var migrateOnStart string
+	c.setOptionalStringValue("MIGRATE_ON_START", &migrateOnStart, "false")
+	c.migrateOnStart = migrateOnStart == "true"
 
-	if err := c.setStringValue("DB_CONNECTION_STRING", &c.dbConnectionString); err != nil {
+	if err := c.setDurationValue("SESSION_LIFETIME", &c.sessionLifetime, "720h"); err != nil {
+		return config{}, err
+	}
+
+	if err := c.setDurationValue("SQLITE_EXPORT_INTERVAL", &c.exportInterval, "15m"); err != nil {
 		return config{}, err
 	}
 
@@ -35,18 +66,101 @@ func loadConfig() (config, error) {
 		return config{}, err
 	}
 
-	urlStr, ok := os.LookupEnv("FRONTEND_URL")
-	if !ok {
-		return config{}, fmt.Errorf("environment variable FRONTEND_URL not found")
+	if err := c.setURLValue("FRONTEND_URL", &c.frontendURL); err != nil {
+		return config{}, err
 	}
 
-	var err error
-	c.frontendURL, err = url.Parse(urlStr)
-	if err != nil {
-		return config{}, fmt.Errorf("FRONTEND_URL %s is not a valid URL: %w", urlStr, err)
+	// API_URL used to be unnecessary, so deployments from before it was added don't set it.
+	if _, ok := os.LookupEnv("API_URL"); !ok {
+		log.Printf("Warning: API_URL is not set, so the IDs of the published JSON schemas will point to %s. "+
+			"Set it to the public URL of the API.", defaultAPIURL)
+	}
+	if err := c.setOptionalURLValue("API_URL", &c.apiURL, defaultAPIURL); err != nil {
+		return config{}, err
+	}
+
+	if err := c.setURLListValue("REDIRECT_URI_ALLOWLIST", &c.redirectAllowlist); err != nil {
+		return config{}, err
+	}
+
+	// The moderation API key is optional, moderators normally log in with their own accounts.
+	c.setOptionalStringValue("MODERATION_API_KEY", &c.moderationAPIKey, "")
+
+	c.setOptionalStringValue("RATE_LIMIT_STORE", &c.rateLimitStore, "database")
+	if c.rateLimitStore != "database" && c.rateLimitStore != "memory" {
+		return config{}, fmt.Errorf("RATE_LIMIT_STORE must be either database or memory, got %q", c.rateLimitStore)
+	}
+
+	if err := c.loadRateLimitConfig(); err != nil {
+		return config{}, err
+	}
+	return c, nil
+}
+
+// loadRateLimitConfig loads the login and export rate limits, which apply in development mode too.
+func (c *config) loadRateLimitConfig() error {
+	if err := c.setRateLimitValue("LOGIN_RATE_LIMIT_PER_EMAIL", &c.loginRateLimitPerEmail, "5/1h"); err != nil {
+		return err
+	}
+
+	if err := c.setRateLimitValue("LOGIN_RATE_LIMIT_PER_IP", &c.loginRateLimitPerIP, "20/1h"); err != nil {
+		return err
+	}
+
+	if err := c.setRateLimitValue("EXPORT_RATE_LIMIT_PER_IP", &c.exportRateLimitPerIP, "10/1h"); err != nil {
+		return err
+	}
+
+	var trustProxyHeaders string
+	c.setOptionalStringValue("TRUST_PROXY_HEADERS", &trustProxyHeaders, "false")
+	c.trustProxyHeaders = trustProxyHeaders == "true"
+	return nil
+}
+
+// loadDBConfig only loads the database settings, which is all the migrate command needs.
+func loadDBConfig() (config, error) {
+	var c config
+
+	c.setOptionalStringValue("DB_DRIVER", &c.dbDriver, "postgres")
+	if c.dbDriver != "postgres" && c.dbDriver != "sqlite" {
+		return config{}, fmt.Errorf("DB_DRIVER must be either postgres or sqlite, got %q", c.dbDriver)
+	}
+
+	if err := c.setStringValue("DB_CONNECTION_STRING", &c.dbConnectionString); err != nil {
+		return config{}, err
+	}
+	return c, nil
+}
+
+// loadDevConfig returns the config for development mode, which doesn't use a database or send any emails.
+// The URLs and the moderation API key can still be overridden with environment variables.
+func loadDevConfig() (config, error) {
+	var c config
+
+	c.setOptionalStringValue("MODERATION_API_KEY", &c.moderationAPIKey, "dev")
+
+	if err := c.setDurationValue("SESSION_LIFETIME", &c.sessionLifetime, "720h"); err != nil {
+		return config{}, err
+	}
+
+	if err := c.setDurationValue("SQLITE_EXPORT_INTERVAL", &c.exportInterval, "15m"); err != nil {
+		return config{}, err
+	}
+
+	if err := c.setOptionalURLValue("FRONTEND_URL", &c.frontendURL, "http://localhost:3000"); err != nil {
+		return config{}, err
+	}
+
+	if err := c.setOptionalURLValue("API_URL", &c.apiURL, defaultAPIURL); err != nil {
+		return config{}, err
+	}
+
+	if err := c.setURLListValue("REDIRECT_URI_ALLOWLIST", &c.redirectAllowlist); err != nil {
+		return config{}, err
 	}
 
-	if err := c.setStringValue("MODERATION_API_KEY", &c.moderationAPIKey); err != nil {
+	c.rateLimitStore = "memory"
+	if err := c.loadRateLimitConfig(); err != nil {
 		return config{}, err
 	}
 	return c, nil
@@ -60,3 +174,96 @@ func (c *config) setStringValue(envVar string, field *string) error {
 	*field = value
 	return nil
 }
+
+func (c *config) setOptionalStringValue(envVar string, field *string, defaultValue string) {
+	value, ok := os.LookupEnv(envVar)
+	if !ok {
+		value = defaultValue
+	}
+	*field = value
+}
+
+func (c *config) setURLValue(envVar string, field **url.URL) error {
+	urlStr, ok := os.LookupEnv(envVar)
+	if !ok {
+		return fmt.Errorf("environment variable %s not found", envVar)
+	}
+
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return fmt.Errorf("%s %s is not a valid URL: %w", envVar, urlStr, err)
+	}
+	*field = u
+	return nil
+}
+
+func (c *config) setOptionalURLValue(envVar string, field **url.URL, defaultValue string) error {
+	if _, ok := os.LookupEnv(envVar); ok {
+		return c.setURLValue(envVar, field)
+	}
+
+	u, err := url.Parse(defaultValue)
+	if err != nil {
+		return fmt.Errorf("default %s %s is not a valid URL: %w", envVar, defaultValue, err)
+	}
+	*field = u
+	return nil
+}
+
+// setDurationValue parses an optional duration, e.g. "720h", falling back to the given default.
+func (c *config) setDurationValue(envVar string, field *time.Duration, defaultValue string) error {
+	var value string
+	c.setOptionalStringValue(envVar, &value, defaultValue)
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("%s %s is not a valid duration: %w", envVar, value, err)
+	}
+	*field = d
+	return nil
+}
+
+// setURLListValue parses an optional, comma-separated list of URLs.
+func (c *config) setURLListValue(envVar string, field *[]*url.URL) error {
+	var value string
+	c.setOptionalStringValue(envVar, &value, "")
+
+	for _, urlStr := range strings.Split(value, ",") {
+		urlStr = strings.TrimSpace(urlStr)
+		if urlStr == "" {
+			continue
+		}
+
+		u, err := url.Parse(urlStr)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("%s contains %s, which is not a valid absolute URL", envVar, urlStr)
+		}
+		*field = append(*field, u)
+	}
+	return nil
+}
+
+// setRateLimitValue parses an optional rate limit, e.g. "5/1h" for 5 requests per hour, falling back to the given default.
+// "0" turns the limit off.
+func (c *config) setRateLimitValue(envVar string, field *model.RateLimit, defaultValue string) error {
+	var value string
+	c.setOptionalStringValue(envVar, &value, defaultValue)
+
+	if value == "0" {
+		*field = model.RateLimit{}
+		return nil
+	}
+
+	requests, window, ok := strings.Cut(value, "/")
+	n, err := strconv.Atoi(requests)
+	if !ok || err != nil || n < 0 {
+		return fmt.Errorf("%s %s is not a valid rate limit, use e.g. 5/1h for 5 requests per hour", envVar, value)
+	}
+
+	d, err := time.ParseDuration(window)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("%s %s is not a valid rate limit, use e.g. 5/1h for 5 requests per hour", envVar, value)
+	}
+	*field = model.RateLimit{Requests: n, Window: d}
+	return nil
+}
@@ -2,68 +2,146 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
 	"github.com/mikolysz/enably"
 	"github.com/mikolysz/enably/api"
 	"github.com/mikolysz/enably/app"
+	"github.com/mikolysz/enably/pkg/email"
+	"github.com/mikolysz/enably/pkg/email/local"
 	"github.com/mikolysz/enably/pkg/email/sendgrid"
 	"github.com/mikolysz/enably/store"
+	"github.com/urfave/cli/v2"
 )
 
 func main() {
 	// We don't care if this errors out, a missing .env is fine.
 	godotenv.Load()
 
+	a := &cli.App{
+		Name:   "enably",
+		Usage:  "Run the Enably API server",
+		Action: runServer,
+		Commands: []*cli.Command{
+			{
+				Name:  "dev",
+				Usage: "Run the server with in-memory stores and seed data, without any external services",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "email-dir", Usage: "also write emails to files in this directory"},
+				},
+				Action: runDevServer,
+			},
+			migrateCommand,
+		},
+	}
+
+	if err := a.Run(os.Args); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func runServer(c *cli.Context) error {
 	cfg, err := loadConfig()
 	if err != nil {
-		log.Fatalf("Error when loading config: %s", err)
+		return fmt.Errorf("error when loading config: %w", err)
 	}
 
-	db, err := pgxpool.New(context.Background(), cfg.dbConnectionString)
+	stores, err := openStores(cfg)
 	if err != nil {
-		log.Fatalf("Error when connecting to database: %s", err)
+		return fmt.Errorf("error when connecting to database: %w", err)
+	}
+	defer stores.close()
+
+	if err := checkMigrations(c.Context, stores.migrator, cfg.migrateOnStart); err != nil {
+		return err
 	}
-	defer db.Close()
 
-	if err := db.Ping(context.Background()); err != nil {
-		log.Fatalf("Error when pinging database: %s", err)
+	sendgridConfig := sendgrid.Config{
+		APIKey:      cfg.sendgridAPIKey,
+		SenderEmail: cfg.senderEmail,
+		SenderName:  cfg.senderName,
+	}
+
+	emailSender := sendgrid.NewSender(sendgridConfig)
+
+	return serve(cfg, stores, emailSender, false)
+}
+
+func runDevServer(c *cli.Context) error {
+	cfg, err := loadDevConfig()
+	if err != nil {
+		return fmt.Errorf("error when loading config: %w", err)
 	}
 
+	log.Printf("Running in development mode, all data will be lost on exit. The moderation API key is %q.", cfg.moderationAPIKey)
+	emailSender := local.NewSender(c.String("email-dir"))
+	return serve(cfg, newMemoryStores(), emailSender, true)
+}
+
+// serve wires up the services with the given dependencies and runs the API server.
+// If seed is true, the products store is filled with sample products first.
+func serve(cfg config, stores stores, emailSender email.Sender, seed bool) error {
 	metaStore, err := store.NewTOMLMetadataStore(enably.Schema)
 	if err != nil {
-		log.Fatalf("Failed to create metadata store: %s", err)
+		return fmt.Errorf("failed to create metadata store: %w", err)
 	}
 
-	meta := app.NewMetadataService(metaStore)
+	meta := app.NewMetadataService(metaStore, cfg.apiURL)
 
-	productsStore := store.NewPostgresProductsStore(db)
+	users := app.NewUsersService(stores.users)
+	notifier := app.NewNotifier(users, emailSender, cfg.frontendURL)
 
-	prod, err := app.NewProductsService(meta, productsStore)
+	prod, err := app.NewProductsService(meta, stores.products, notifier)
 	if err != nil {
-		log.Fatalf("Error when creating products service: %s", err)
+		return fmt.Errorf("error when creating products service: %w", err)
 	}
 
-	sendgridConfig := sendgrid.Config{
-		APIKey:      cfg.sendgridAPIKey,
-		SenderEmail: cfg.senderEmail,
-		SenderName:  cfg.senderName,
+	if seed {
+		if err := seedProducts(prod); err != nil {
+			return fmt.Errorf("error when seeding products: %w", err)
+		}
 	}
 
-	emailSender := sendgrid.NewSender(sendgridConfig)
-
-	authStore := store.PostgresTokenStore{DB: db}
-	auth := app.NewAuthenticationService(authStore, emailSender, cfg.frontendURL)
+	openExportDB := func(path string) (app.ExportDatabase, error) {
+		return store.NewSQLiteExportDatabase(path)
+	}
+	export := app.NewExportService(prod, openExportDB, cfg.frontendURL)
+	go export.RefreshSQLiteExport(context.Background(), cfg.exportInterval)
+
+	auth := app.NewAuthenticationService(stores.tokens, users, emailSender, app.AuthConfig{
+		FrontendURL:            cfg.frontendURL,
+		AllowedRedirectOrigins: cfg.redirectAllowlist,
+		SessionLifetime:        cfg.sessionLifetime,
+	})
+	go auth.DeleteExpiredTokens(context.Background(), time.Hour)
+
+	rateLimitStore := stores.rateLimits
+	if cfg.rateLimitStore == "memory" {
+		rateLimitStore = store.NewMemoryRateLimitStore()
+	}
+	limiter := app.NewRateLimiter(rateLimitStore)
+	go limiter.DeleteExpiredRateLimits(context.Background(), time.Hour)
 
 	deps := api.Dependencies{
-		Metadata:         meta,
-		Products:         prod,
-		Auth:             auth,
-		ModerationAPIKey: cfg.moderationAPIKey,
+		Metadata:    meta,
+		Products:    prod,
+		Auth:        auth,
+		Users:       users,
+		Export:      export,
+		RateLimiter: limiter,
+		LoginRateLimits: api.LoginRateLimits{
+			PerEmail: cfg.loginRateLimitPerEmail,
+			PerIP:    cfg.loginRateLimitPerIP,
+		},
+		ExportRateLimit:   cfg.exportRateLimitPerIP,
+		ModerationAPIKey:  cfg.moderationAPIKey,
+		TrustProxyHeaders: cfg.trustProxyHeaders,
 	}
 
 	a := api.New(deps)
@@ -72,5 +150,5 @@ func main() {
 
 	r.Mount("/api/v1", a)
 
-	log.Fatal(http.ListenAndServe(":8080", r))
+	return http.ListenAndServe(":8080", r)
 }
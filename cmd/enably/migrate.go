@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/mikolysz/enably/store"
+	"github.com/urfave/cli/v2"
+)
+
+var migrateCommand = &cli.Command{
+	Name:  "migrate",
+	Usage: "Manage the database schema",
+	Subcommands: []*cli.Command{
+		{
+			Name:  "up",
+			Usage: "Apply all pending migrations",
+			Action: withMigrator(func(c *cli.Context, m *store.Migrator) error {
+				applied, err := m.Up(c.Context)
+				for _, migration := range applied {
+					fmt.Printf("applied %d_%s\n", migration.Version, migration.Name)
+				}
+				if err != nil {
+					return err
+				}
+
+				if len(applied) == 0 {
+					fmt.Println("no pending migrations")
+				}
+				return nil
+			}),
+		},
+		{
+			Name:  "down",
+			Usage: "Revert the most recently applied migrations",
+			Flags: []cli.Flag{
+				&cli.IntFlag{Name: "steps", Usage: "the number of migrations to revert", Value: 1},
+			},
+			Action: withMigrator(func(c *cli.Context, m *store.Migrator) error {
+				reverted, err := m.Down(c.Context, c.Int("steps"))
+				for _, migration := range reverted {
+					fmt.Printf("reverted %d_%s\n", migration.Version, migration.Name)
+				}
+				return err
+			}),
+		},
+		{
+			Name:  "status",
+			Usage: "List all migrations and whether they've been applied",
+			Action: withMigrator(func(c *cli.Context, m *store.Migrator) error {
+				statuses, err := m.Status(c.Context)
+				if err != nil {
+					return err
+				}
+
+				for _, s := range statuses {
+					status := "pending"
+					if s.Applied {
+						status = "applied"
+					}
+					fmt.Printf("%-8s %d_%s\n", status, s.Version, s.Name)
+				}
+				return nil
+			}),
+		},
+	},
+}
+
+// withMigrator wraps a migrate subcommand, connecting to the configured database first.
+func withMigrator(fn func(c *cli.Context, m *store.Migrator) error) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		cfg, err := loadDBConfig()
+		if err != nil {
+			return fmt.Errorf("error when loading config: %w", err)
+		}
+
+		stores, err := openStores(cfg)
+		if err != nil {
+			return fmt.Errorf("error when connecting to database: %w", err)
+		}
+		defer stores.close()
+
+		return fn(c, stores.migrator)
+	}
+}
+
+// checkMigrations makes sure the database schema is up to date before the server starts.
+// Pending migrations are applied if apply is true, otherwise they're an error.
+func checkMigrations(c context.Context, m *store.Migrator, apply bool) error {
+	if apply {
+		applied, err := m.Up(c)
+		for _, migration := range applied {
+			log.Printf("Applied migration %d_%s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return fmt.Errorf("error when applying migrations: %w", err)
+		}
+		return nil
+	}
+
+	pending, err := m.Pending(c)
+	if err != nil {
+		return fmt.Errorf("error when checking migrations: %w", err)
+	}
+
+	if len(pending) > 0 {
+		return fmt.Errorf("the database has %d pending migrations, run `enably migrate up` or set MIGRATE_ON_START=true", len(pending))
+	}
+	return nil
+}
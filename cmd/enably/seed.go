@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/mikolysz/enably/app"
+	"github.com/mikolysz/enably/model"
+)
+
+// seedProduct is a sample product added in development mode.
+type seedProduct struct {
+	category string
+	status   model.ProductStatus // StatusPending, StatusPublished or StatusDiscontinued.
+	data     string              // JSON, in the same format as submitted by the frontend.
+}
+
+// seedData contains a few products in different categories, published, discontinued and awaiting moderation,
+// so that every page of the frontend has something to show.
+var seedData = []seedProduct{
+	{
+		category: "screen_readers",
+		status:   model.StatusPublished,
+		data: `{"software": {
+			"name": "NVDA",
+			"description": "A free and open source screen reader for Windows",
+			"platform": "Windows",
+			"download_url": "https://www.nvaccess.org/download/",
+			"developer": "NV Access",
+			"free_or_paid": "Free",
+			"accessibility_rating": "Fully accessible",
+			"accessibility_approach": "The app is made for the blind or with the blind in mind",
+			"accessibility_issues": "None"
+		}}`,
+	},
+	{
+		category: "navigation_apps",
+		status:   model.StatusPublished,
+		data: `{"software": {
+			"name": "Lazarillo",
+			"description": "GPS navigation app announcing nearby places and public transit",
+			"platform": "Android",
+			"download_url": "https://lazarillo.app/",
+			"developer": "Lazarillo",
+			"free_or_paid": "Free",
+			"accessibility_rating": "Fully accessible",
+			"accessibility_approach": "The app is made for the blind or with the blind in mind",
+			"accessibility_issues": "None known"
+		}}`,
+	},
+	{
+		category: "audio_apps",
+		status:   model.StatusPublished,
+		data: `{"software": {
+			"name": "Reaper",
+			"description": "A digital audio workstation, accessible with the OSARA extension",
+			"platform": "Windows",
+			"download_url": "https://www.reaper.fm/",
+			"developer": "Cockos",
+			"free_or_paid": "Paid",
+			"price": "$60 for a personal license",
+			"accessibility_rating": "Has accessibility issues",
+			"accessibility_approach": "The developers seem to care, but issues take a while to fix and are sometimes left unfixed",
+			"accessibility_issues": "Requires the free OSARA extension to work with screen readers"
+		}}`,
+	},
+	{
+		category: "games",
+		status:   model.StatusPending,
+		data: `{"software": {
+			"name": "A Hero's Call",
+			"description": "An audio role-playing game",
+			"platform": "Windows",
+			"download_url": "https://outofsightgames.com/a-heros-call/",
+			"developer": "Out of Sight Games",
+			"free_or_paid": "Paid",
+			"price": "$20",
+			"accessibility_rating": "Fully accessible",
+			"accessibility_approach": "The app is made for the blind or with the blind in mind",
+			"accessibility_issues": "None"
+		}}`,
+	},
+	{
+		category: "microwaves",
+		status:   model.StatusDiscontinued,
+		data: `{
+			"physical_product": {
+				"model": "Example Talking Microwave",
+				"description": "A microwave with spoken feedback and tactile buttons",
+				"price": "$150",
+				"purchase_url": "https://example.com/talking-microwave"
+			},
+			"appliance": {
+				"accessibility_rating": "Fully accessible",
+				"accessibility_issues": "None",
+				"talks": true,
+				"controls": "Physical buttons",
+				"control_layout": "A number pad with a raised dot on 5, start and stop buttons below it"
+			}
+		}`,
+	},
+	{
+		category: "coffee_machines",
+		status:   model.StatusPending,
+		data: `{
+			"physical_product": {
+				"model": "Example Touch Espresso",
+				"description": "An espresso machine with a touch screen",
+				"price": "$400",
+				"purchase_url": "https://example.com/touch-espresso"
+			},
+			"appliance": {
+				"accessibility_rating": "Completely inaccessible",
+				"accessibility_issues": "All functions are only available from the touch screen",
+				"talks": false,
+				"controls": "A full touch screen, e.g., one supporting scrolling and multiple menu levels"
+			}
+		}`,
+	},
+}
+
+// seedProducts submits the sample products through the products service, so that they're validated like any other submission.
+func seedProducts(prod *app.ProductsService) error {
+	for _, sp := range seedData {
+		p, err := prod.CreateProduct(sp.category, []byte(sp.data), 0)
+		if err != nil {
+			return fmt.Errorf("error when creating product in %s: %w", sp.category, err)
+		}
+
+		if sp.status == model.StatusPending {
+			continue
+		}
+
+		if err := prod.ApproveProduct(p.ID, model.Approval{}, model.Moderator{}); err != nil {
+			return err
+		}
+
+		if sp.status == model.StatusDiscontinued {
+			if err := prod.DiscontinueProduct(p.ID, model.Moderator{}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
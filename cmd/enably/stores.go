@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mikolysz/enably"
+	"github.com/mikolysz/enably/app"
+	"github.com/mikolysz/enably/store"
+)
+
+// stores contains the stores using the configured database.
+type stores struct {
+	products   app.ProductsStore
+	tokens     app.TokenStore
+	users      app.UsersStore
+	rateLimits app.RateLimitStore
+	migrator   *store.Migrator
+
+	close func() // closes the database connections.
+}
+
+// openStores connects to the database selected by the config and returns the stores using it.
+func openStores(cfg config) (stores, error) {
+	switch cfg.dbDriver {
+	case "sqlite":
+		db, err := store.OpenSQLiteDB(cfg.dbConnectionString)
+		if err != nil {
+			return stores{}, err
+		}
+
+		if err := db.Ping(); err != nil {
+			db.Close()
+			return stores{}, fmt.Errorf("error when pinging database: %w", err)
+		}
+
+		migrator, err := newMigrator(cfg.dbDriver, db)
+		if err != nil {
+			db.Close()
+			return stores{}, err
+		}
+
+		return stores{
+			products:   store.NewSQLiteProductsStore(db),
+			tokens:     store.SQLiteTokenStore{DB: db},
+			users:      store.SQLiteUsersStore{DB: db},
+			rateLimits: store.SQLiteRateLimitStore{DB: db},
+			migrator:   migrator,
+			close:      func() { db.Close() },
+		}, nil
+
+	default:
+		pool, err := pgxpool.New(context.Background(), cfg.dbConnectionString)
+		if err != nil {
+			return stores{}, err
+		}
+
+		if err := pool.Ping(context.Background()); err != nil {
+			pool.Close()
+			return stores{}, fmt.Errorf("error when pinging database: %w", err)
+		}
+
+		// Migrations go through database/sql, so that the same code works with both databases.
+		db, err := store.OpenPostgresDB(cfg.dbConnectionString)
+		if err != nil {
+			pool.Close()
+			return stores{}, err
+		}
+
+		migrator, err := newMigrator(cfg.dbDriver, db)
+		if err != nil {
+			pool.Close()
+			db.Close()
+			return stores{}, err
+		}
+
+		return stores{
+			products:   store.NewPostgresProductsStore(pool),
+			tokens:     store.PostgresTokenStore{DB: pool},
+			users:      store.PostgresUsersStore{DB: pool},
+			rateLimits: store.PostgresRateLimitStore{DB: pool},
+			migrator:   migrator,
+			close: func() {
+				pool.Close()
+				db.Close()
+			},
+		}, nil
+	}
+}
+
+// newMemoryStores returns empty in-memory stores, for the dev server.
+func newMemoryStores() stores {
+	return stores{
+		products:   store.NewMemoryProductsStore(),
+		tokens:     store.NewMemoryTokenStore(),
+		users:      store.NewMemoryUsersStore(),
+		rateLimits: store.NewMemoryRateLimitStore(),
+		close:      func() {},
+	}
+}
+
+// newMigrator returns a migrator using the embedded migrations for the given database driver.
+func newMigrator(driver string, db *sql.DB) (*store.Migrator, error) {
+	files, err := fs.Sub(enably.Migrations, "migrations/"+driver)
+	if err != nil {
+		return nil, fmt.Errorf("error when reading migrations: %w", err)
+	}
+
+	if driver == "sqlite" {
+		return store.NewSQLiteMigrator(db, files)
+	}
+	return store.NewPostgresMigrator(db, files)
+}
@@ -1,10 +1,18 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/mikolysz/enably/model"
 	"github.com/urfave/cli/v2"
@@ -18,51 +26,242 @@ func main() {
 
 	apiURL += "/api/v1"
 
-	apiKey, ok := os.LookupEnv("ENABLY_MODERATION_API_KEY")
-	if !ok {
-		must(fmt.Errorf("environment variable ENABLY_MODERATION_API_KEY not found"))
-	}
-
+	// Moderators use their own session, see the login command. The API key is only for emergencies.
 	header := http.Header{}
-	header.Set("X-Moderation-Api-Key", apiKey)
+	if token, ok := os.LookupEnv("ENABLY_SESSION_TOKEN"); ok {
+		header.Set("Authorization", "Bearer "+token)
+	} else if apiKey, ok := os.LookupEnv("ENABLY_MODERATION_API_KEY"); ok {
+		header.Set("X-Moderation-Api-Key", apiKey)
+	}
 
 	app := &cli.App{
 		Name:  "enctl",
 		Usage: "Interact with Enably's moderation API",
 		Commands: []*cli.Command{
+			{
+				Name:      "login",
+				Usage:     "Log in with your moderator account and print a session token",
+				ArgsUsage: "EMAIL",
+				Action: func(c *cli.Context) error {
+					body, err := json.Marshal(map[string]string{"email": c.Args().First()})
+					must(err)
+					resp, err := http.Post(apiURL+"/auth/login", "application/json", bytes.NewReader(body))
+					must(err)
+					defer resp.Body.Close()
+					must(checkResponse(resp))
+
+					fmt.Print("We've sent you a login link. Paste it here: ")
+					link, err := bufio.NewReader(os.Stdin).ReadString('\n')
+					must(err)
+
+					// The token can be pasted on its own too.
+					token := strings.TrimSpace(link)
+					if u, err := url.Parse(token); err == nil && u.Query().Get("token") != "" {
+						token = u.Query().Get("token")
+					}
+
+					body, err = json.Marshal(map[string]string{"token": token})
+					must(err)
+					resp, err = http.Post(apiURL+"/auth/exchange", "application/json", bytes.NewReader(body))
+					must(err)
+					defer resp.Body.Close()
+					must(checkResponse(resp))
+
+					var session struct {
+						Token string `json:"token"`
+					}
+					must(json.NewDecoder(resp.Body).Decode(&session))
+					fmt.Printf("Logged in, now run:\n\nexport ENABLY_SESSION_TOKEN=%s\n", session.Token)
+					return nil
+				},
+			},
 			{
 				Name:  "list",
-				Usage: "Get a list of products that need approval",
+				Usage: "Get a list of submissions that need approval, oldest first",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "category", Usage: "only list products in this category and its subcategories"},
+					&cli.StringFlag{Name: "type", Usage: "only list new submissions (new), resubmissions after changes were requested (resubmission) or translations (edit)"},
+					&cli.BoolFlag{Name: "newest", Usage: "list the newest products first"},
+					&cli.IntFlag{Name: "limit", Usage: "the number of products to list", Value: 50},
+					&cli.IntFlag{Name: "offset", Usage: "the number of products to skip"},
+				},
 				Action: func(c *cli.Context) error {
-					url := apiURL + "/moderation/pending"
-					req, err := http.NewRequest(http.MethodGet, url, nil)
+					query := url.Values{}
+					query.Set("limit", strconv.Itoa(c.Int("limit")))
+					query.Set("offset", strconv.Itoa(c.Int("offset")))
+					if c.String("category") != "" {
+						query.Set("category", c.String("category"))
+					}
+					if c.String("type") != "" {
+						query.Set("type", c.String("type"))
+					}
+					if c.Bool("newest") {
+						query.Set("sort", "newest")
+					}
+
+					req, err := http.NewRequest(http.MethodGet, apiURL+"/moderation/pending?"+query.Encode(), nil)
 					must(err)
 					req.Header = header
 					resp, err := http.DefaultClient.Do(req)
 					must(err)
 					defer resp.Body.Close()
+					must(checkResponse(resp))
+
+					var page model.QueuePage
+					must(json.NewDecoder(resp.Body).Decode(&page))
+					for _, item := range page.Items {
+						if item.Translation != nil {
+							t := item.Translation
+							fmt.Printf("translation %d - %s translated into %s (%s, %s, queued %s)\n", t.ID, item.Name, t.Locale, item.CategorySlug, item.SubmissionType, item.QueuedAt.Format(time.DateTime))
+							continue
+						}
 
-					var products []model.Product
-					must(json.NewDecoder(resp.Body).Decode(&products))
-					for _, p := range products {
-						fmt.Printf("%d - %s (%s)\n", p.ID, p.Name, p.CategorySlug)
+						fmt.Printf("%d - %s (%s, %s, queued %s)", item.ID, item.Name, item.CategorySlug, item.SubmissionType, item.QueuedAt.Format(time.DateTime))
+						if item.Claim != nil {
+							claimedBy := item.Claim.ModeratorEmail
+							if claimedBy == "" {
+								claimedBy = fmt.Sprintf("user %d", item.Claim.ModeratorID)
+							}
+							fmt.Printf(", claimed by %s", claimedBy)
+						}
+						if item.NoteCount > 0 {
+							fmt.Printf(", %d notes", item.NoteCount)
+						}
+						fmt.Println()
 					}
+					fmt.Printf("Showing %d of %d submissions.\n", len(page.Items), page.Total)
 
 					return nil
 				},
 			},
 			{
-				Name:  "info",
-				Usage: "Get information about a product",
+				Name:      "claim",
+				Usage:     "Tell other moderators you're reviewing a product, the claim lasts 30 minutes",
+				ArgsUsage: "ID",
 				Action: func(c *cli.Context) error {
-					id := c.Args().First()
-					url := apiURL + "/products/" + id
-					resp, err := http.Get(url)
+					req, err := http.NewRequest(http.MethodPost, apiURL+"/moderation/products/"+c.Args().First()+"/claim", nil)
+					must(err)
+					req.Header = header
+					resp, err := http.DefaultClient.Do(req)
 					must(err)
 					defer resp.Body.Close()
+					must(checkResponse(resp))
+
+					return nil
+				},
+			},
+			{
+				Name:      "unclaim",
+				Usage:     "Let other moderators review a product you claimed",
+				ArgsUsage: "ID",
+				Action: func(c *cli.Context) error {
+					req, err := http.NewRequest(http.MethodDelete, apiURL+"/moderation/products/"+c.Args().First()+"/claim", nil)
+					must(err)
+					req.Header = header
+					resp, err := http.DefaultClient.Do(req)
+					must(err)
+					defer resp.Body.Close()
+					must(checkResponse(resp))
+
+					return nil
+				},
+			},
+			{
+				Name:      "notes",
+				Usage:     "Show the internal moderator notes about a product",
+				ArgsUsage: "ID",
+				Action: func(c *cli.Context) error {
+					req, err := http.NewRequest(http.MethodGet, apiURL+"/moderation/products/"+c.Args().First()+"/notes", nil)
+					must(err)
+					req.Header = header
+					resp, err := http.DefaultClient.Do(req)
+					must(err)
+					defer resp.Body.Close()
+					must(checkResponse(resp))
+
+					var notes []model.ModeratorNote
+					must(json.NewDecoder(resp.Body).Decode(&notes))
+					for _, n := range notes {
+						author := n.AuthorEmail
+						switch {
+						case n.AuthorID == nil:
+							author = "API key"
+						case author == "":
+							author = fmt.Sprintf("user %d", *n.AuthorID)
+						}
+						fmt.Printf("%s - %s:\n%s\n\n", n.CreatedAt.Format(time.DateTime), author, n.Text)
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "note",
+				Usage:     "Add an internal note about a product, only moderators can see it",
+				ArgsUsage: "ID TEXT",
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 2 {
+						return fmt.Errorf("usage: enctl note ID TEXT")
+					}
+
+					body, err := json.Marshal(map[string]string{"text": c.Args().Get(1)})
+					must(err)
+					url := apiURL + "/moderation/products/" + c.Args().Get(0) + "/notes"
+					req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+					must(err)
+					req.Header = header.Clone()
+					req.Header.Set("Content-Type", "application/json")
+					resp, err := http.DefaultClient.Do(req)
+					must(err)
+					defer resp.Body.Close()
+					must(checkResponse(resp))
+
+					return nil
+				},
+			},
+			{
+				Name:      "bulk-approve",
+				Usage:     "Approve several products at once",
+				ArgsUsage: "ID...",
+				Action: func(c *cli.Context) error {
+					return bulkModerate(apiURL, header, model.BulkAction{Action: model.ActionApprove}, c.Args().Slice())
+				},
+			},
+			{
+				Name:      "bulk-reject",
+				Usage:     "Reject several products at once for the same reason",
+				ArgsUsage: "ID...",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "canned", Usage: "the code of a canned reason, see the reasons command"},
+					&cli.StringFlag{Name: "reason", Usage: "the reason in your own words, added after the canned one if both are given"},
+				},
+				Action: func(c *cli.Context) error {
+					if c.String("canned") == "" && c.String("reason") == "" {
+						return fmt.Errorf("please give a reason with --canned or --reason")
+					}
+
+					action := model.BulkAction{
+						Action:    model.ActionReject,
+						Rejection: &model.Rejection{CannedReason: c.String("canned"), Reason: c.String("reason")},
+					}
+					return bulkModerate(apiURL, header, action, c.Args().Slice())
+				},
+			},
+			{
+				Name:      "info",
+				Usage:     "Get information about a product, whatever its status",
+				ArgsUsage: "ID",
+				Action: func(c *cli.Context) error {
+					req, err := http.NewRequest(http.MethodGet, apiURL+"/moderation/products/"+c.Args().First(), nil)
+					must(err)
+					req.Header = header
+					resp, err := http.DefaultClient.Do(req)
+					must(err)
+					defer resp.Body.Close()
+					must(checkResponse(resp))
 
 					var product model.Product
 					must(json.NewDecoder(resp.Body).Decode(&product))
+					fmt.Printf("Status: %s\n\n", product.Status)
 					for name, fieldset := range product.Data {
 						fmt.Printf("%s:\n", name)
 						for fieldName, field := range fieldset {
@@ -75,43 +274,508 @@ func main() {
 				},
 			},
 			{
-				Name:  "approve",
-				Usage: "Approve a product",
+				Name:      "approve",
+				Usage:     "Approve a product, optionally fixing some of its fields first",
+				ArgsUsage: "ID",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{Name: "set", Usage: "change a field to a text value before approving, as fieldset_slug.field_name=value, can be repeated"},
+					&cli.StringSliceFlag{Name: "set-json", Usage: "like --set, but the value is JSON, e.g. a number, a list or null to remove the field"},
+				},
 				Action: func(c *cli.Context) error {
+					var approval model.Approval
+					for _, flag := range []string{"set", "set-json"} {
+						for _, s := range c.StringSlice(flag) {
+							field, value, ok := strings.Cut(s, "=")
+							fsetSlug, name, ok2 := strings.Cut(field, ".")
+							if !ok || !ok2 {
+								return fmt.Errorf("invalid change %q, use fieldset_slug.field_name=value", s)
+							}
+
+							var v any = value
+							if flag == "set-json" {
+								if err := json.Unmarshal([]byte(value), &v); err != nil {
+									return fmt.Errorf("invalid JSON value for %s: %s", field, err)
+								}
+							}
+
+							if approval.Changes == nil {
+								approval.Changes = map[string]map[string]any{}
+							}
+							if approval.Changes[fsetSlug] == nil {
+								approval.Changes[fsetSlug] = map[string]any{}
+							}
+							approval.Changes[fsetSlug][name] = v
+						}
+					}
+
+					body, err := json.Marshal(approval)
+					must(err)
 					id := c.Args().First()
 					url := apiURL + "/moderation/products/" + id + "/approve"
-					req, err := http.NewRequest(http.MethodPost, url, nil)
+					req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+					must(err)
+					req.Header = header.Clone()
+					req.Header.Set("Content-Type", "application/json")
+					resp, err := http.DefaultClient.Do(req)
+					must(err)
+					defer resp.Body.Close()
+					must(checkResponse(resp))
+
+					return nil
+				},
+			},
+			{
+				Name:      "discontinue",
+				Usage:     "Mark a published product as no longer made or sold, approve it to publish it again",
+				ArgsUsage: "ID",
+				Action: func(c *cli.Context) error {
+					req, err := http.NewRequest(http.MethodPost, apiURL+"/moderation/products/"+c.Args().First()+"/discontinue", nil)
 					must(err)
 					req.Header = header
 					resp, err := http.DefaultClient.Do(req)
 					must(err)
 					defer resp.Body.Close()
+					must(checkResponse(resp))
 
 					return nil
 				},
 			},
 			{
-				Name:  "reject",
-				Usage: "Reject a product",
+				Name:      "edits",
+				Usage:     "Show the changes moderators made to a product when approving it",
+				ArgsUsage: "ID",
 				Action: func(c *cli.Context) error {
+					id := c.Args().First()
+					req, err := http.NewRequest(http.MethodGet, apiURL+"/moderation/products/"+id+"/edits", nil)
+					must(err)
+					req.Header = header
+					resp, err := http.DefaultClient.Do(req)
+					must(err)
+					defer resp.Body.Close()
+					must(checkResponse(resp))
+
+					var edits []model.ModeratorEdit
+					must(json.NewDecoder(resp.Body).Decode(&edits))
+					for _, e := range edits {
+						moderator := e.ModeratorEmail
+						switch {
+						case e.ModeratorID == nil:
+							moderator = "API key"
+						case moderator == "":
+							moderator = fmt.Sprintf("user %d", *e.ModeratorID)
+						}
+
+						fmt.Printf("%s - by %s:\n", e.CreatedAt.Format(time.DateTime), moderator)
+						for fsetSlug, fields := range e.Changes {
+							for name, value := range fields {
+								fmt.Printf("  %s.%s: %v -> %v\n", fsetSlug, name, e.Original[fsetSlug][name], value)
+							}
+						}
+						fmt.Println()
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "reject",
+				Usage:     "Reject a product, the reason is shown to the submitter",
+				ArgsUsage: "ID",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "canned", Usage: "the code of a canned reason, see the reasons command"},
+					&cli.StringFlag{Name: "reason", Usage: "the reason in your own words, added after the canned one if both are given"},
+				},
+				Action: func(c *cli.Context) error {
+					if c.String("canned") == "" && c.String("reason") == "" {
+						return fmt.Errorf("please give a reason with --canned or --reason")
+					}
+
+					body, err := json.Marshal(model.Rejection{CannedReason: c.String("canned"), Reason: c.String("reason")})
+					must(err)
 					id := c.Args().First()
 					url := apiURL + "/moderation/products/" + id + "/reject"
+					req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+					must(err)
+					req.Header = header.Clone()
+					req.Header.Set("Content-Type", "application/json")
+					resp, err := http.DefaultClient.Do(req)
+					must(err)
+					defer resp.Body.Close()
+					must(checkResponse(resp))
+
+					return nil
+				},
+			},
+			{
+				Name:  "reasons",
+				Usage: "List the canned reasons for rejecting a product",
+				Action: func(c *cli.Context) error {
+					req, err := http.NewRequest(http.MethodGet, apiURL+"/moderation/rejection-reasons", nil)
+					must(err)
+					req.Header = header
+					resp, err := http.DefaultClient.Do(req)
+					must(err)
+					defer resp.Body.Close()
+					must(checkResponse(resp))
+
+					var reasons []model.CannedRejectionReason
+					must(json.NewDecoder(resp.Body).Decode(&reasons))
+					for _, r := range reasons {
+						fmt.Printf("%s - %s\n", r.Code, r.Text)
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "request-changes",
+				Usage:     "Send a pending product back to its submitter with a list of things to fix",
+				ArgsUsage: "ID",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "message", Usage: "a general comment on the submission"},
+					&cli.StringSliceFlag{Name: "field", Usage: "a comment on one field, as fieldset_slug.field_name=comment, can be repeated"},
+				},
+				Action: func(c *cli.Context) error {
+					req := model.ChangeRequest{Message: c.String("message")}
+					for _, f := range c.StringSlice("field") {
+						field, text, ok := strings.Cut(f, "=")
+						if !ok {
+							return fmt.Errorf("invalid field comment %q, use fieldset_slug.field_name=comment", f)
+						}
+						req.FieldComments = append(req.FieldComments, model.FieldComment{Field: field, Text: text})
+					}
+
+					body, err := json.Marshal(req)
+					must(err)
+					id := c.Args().First()
+					url := apiURL + "/moderation/products/" + id + "/request-changes"
+					httpReq, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+					must(err)
+					httpReq.Header = header.Clone()
+					httpReq.Header.Set("Content-Type", "application/json")
+					resp, err := http.DefaultClient.Do(httpReq)
+					must(err)
+					defer resp.Body.Close()
+					must(checkResponse(resp))
+
+					return nil
+				},
+			},
+			{
+				Name:      "comments",
+				Usage:     "Show the conversation between moderators and the submitter of a product",
+				ArgsUsage: "ID",
+				Action: func(c *cli.Context) error {
+					id := c.Args().First()
+					req, err := http.NewRequest(http.MethodGet, apiURL+"/moderation/products/"+id+"/comments", nil)
+					must(err)
+					req.Header = header
+					resp, err := http.DefaultClient.Do(req)
+					must(err)
+					defer resp.Body.Close()
+					must(checkResponse(resp))
+
+					var comments []model.Comment
+					must(json.NewDecoder(resp.Body).Decode(&comments))
+					for _, cm := range comments {
+						author := cm.AuthorName
+						switch {
+						case cm.FromModerator && author == "":
+							author = "moderator"
+						case cm.FromModerator:
+							author += " (moderator)"
+						case author == "":
+							author = "submitter"
+						}
+
+						if cm.Field != "" {
+							author += " on " + cm.Field
+						}
+						fmt.Printf("%s - %s:\n%s\n\n", cm.CreatedAt.Format(time.DateTime), author, cm.Text)
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "comment",
+				Usage:     "Add a comment to the conversation about a product, the submitter gets it by email",
+				ArgsUsage: "ID TEXT",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "field", Usage: "the field the comment is about, as fieldset_slug.field_name"},
+				},
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 2 {
+						return fmt.Errorf("usage: enctl comment [--field FIELD] ID TEXT")
+					}
+
+					body, err := json.Marshal(model.FieldComment{Field: c.String("field"), Text: c.Args().Get(1)})
+					must(err)
+					url := apiURL + "/moderation/products/" + c.Args().Get(0) + "/comments"
+					req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+					must(err)
+					req.Header = header.Clone()
+					req.Header.Set("Content-Type", "application/json")
+					resp, err := http.DefaultClient.Do(req)
+					must(err)
+					defer resp.Body.Close()
+					must(checkResponse(resp))
+
+					return nil
+				},
+			},
+			{
+				Name:      "import",
+				Usage:     "Import products from a CSV or JSON Lines file",
+				ArgsUsage: "FILE",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "category", Usage: "the slug of the category to add the products to", Required: true},
+					&cli.StringFlag{Name: "format", Usage: "csv or jsonl, determined from the file extension by default"},
+					&cli.BoolFlag{Name: "dry-run", Usage: "only validate the products"},
+				},
+				Action: func(c *cli.Context) error {
+					path := c.Args().First()
+					f, err := os.Open(path)
+					must(err)
+					defer f.Close()
+
+					format := c.String("format")
+					if format == "" {
+						format = strings.TrimPrefix(filepath.Ext(path), ".")
+					}
+
+					query := url.Values{}
+					query.Set("format", format)
+					query.Set("dry_run", strconv.FormatBool(c.Bool("dry-run")))
+					url := apiURL + "/moderation/import/" + c.String("category") + "?" + query.Encode()
+					req, err := http.NewRequest(http.MethodPost, url, f)
+					must(err)
+					req.Header = header
+					resp, err := http.DefaultClient.Do(req)
+					must(err)
+					defer resp.Body.Close()
+					must(checkResponse(resp))
+
+					var res model.ImportResult
+					must(json.NewDecoder(resp.Body).Decode(&res))
+					for _, e := range res.Errors {
+						fmt.Printf("row %d: %s\n", e.Row, e.Message)
+					}
+
+					if res.DryRun {
+						fmt.Printf("%d of %d products are valid, nothing was imported\n", res.Valid, res.Total)
+					} else {
+						fmt.Printf("imported %d of %d products\n", len(res.ProductIDs), res.Total)
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "export",
+				Usage: "Download all approved products",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "format", Usage: "jsonl, csv or sqlite", Value: "jsonl"},
+					&cli.StringFlag{Name: "category", Usage: "the slug of the category to export, required for csv"},
+					&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "the file to write to", Required: true},
+				},
+				Action: func(c *cli.Context) error {
+					var path string
+					switch c.String("format") {
+					case "jsonl":
+						path = "/export/products.jsonl"
+					case "csv":
+						if c.String("category") == "" {
+							return fmt.Errorf("the csv format requires a --category")
+						}
+						path = "/export/categories/" + c.String("category") + "/products.csv"
+					case "sqlite":
+						path = "/export/enably.sqlite"
+					default:
+						return fmt.Errorf("unknown format %q", c.String("format"))
+					}
+
+					resp, err := http.Get(apiURL + path)
+					must(err)
+					defer resp.Body.Close()
+					must(checkResponse(resp))
+
+					f, err := os.Create(c.String("output"))
+					must(err)
+					defer f.Close()
+
+					n, err := io.Copy(f, resp.Body)
+					must(err)
+					fmt.Printf("wrote %d bytes to %s\n", n, c.String("output"))
+					return nil
+				},
+			},
+			{
+				Name:  "translations",
+				Usage: "Get a list of product translations that need approval",
+				Action: func(c *cli.Context) error {
+					url := apiURL + "/moderation/pending-translations"
+					req, err := http.NewRequest(http.MethodGet, url, nil)
+					must(err)
+					req.Header = header
+					resp, err := http.DefaultClient.Do(req)
+					must(err)
+					defer resp.Body.Close()
+					must(checkResponse(resp))
+
+					var translations []model.Translation
+					must(json.NewDecoder(resp.Body).Decode(&translations))
+					for _, t := range translations {
+						fmt.Printf("%d - product %d (%s):\n", t.ID, t.ProductID, t.Locale)
+						for fsetName, fields := range t.Data {
+							for fieldName, text := range fields {
+								fmt.Printf("  %s.%s: %s\n", fsetName, fieldName, text)
+							}
+						}
+						fmt.Println()
+					}
+
+					return nil
+				},
+			},
+			{
+				Name:  "approve-translation",
+				Usage: "Approve a product translation",
+				Action: func(c *cli.Context) error {
+					id := c.Args().First()
+					url := apiURL + "/moderation/translations/" + id + "/approve"
+					req, err := http.NewRequest(http.MethodPost, url, nil)
+					must(err)
+					req.Header = header
+					resp, err := http.DefaultClient.Do(req)
+					must(err)
+					defer resp.Body.Close()
+					must(checkResponse(resp))
+
+					return nil
+				},
+			},
+			{
+				Name:  "reject-translation",
+				Usage: "Reject a product translation",
+				Action: func(c *cli.Context) error {
+					id := c.Args().First()
+					url := apiURL + "/moderation/translations/" + id + "/reject"
 					req, err := http.NewRequest(http.MethodPost, url, nil)
 					must(err)
 					req.Header = header
 					resp, err := http.DefaultClient.Do(req)
 					must(err)
 					defer resp.Body.Close()
+					must(checkResponse(resp))
 
 					return nil
 				},
 			},
+			{
+				Name:  "actions",
+				Usage: "List the most recent moderation decisions and who made them",
+				Action: func(c *cli.Context) error {
+					req, err := http.NewRequest(http.MethodGet, apiURL+"/moderation/actions", nil)
+					must(err)
+					req.Header = header
+					resp, err := http.DefaultClient.Do(req)
+					must(err)
+					defer resp.Body.Close()
+					must(checkResponse(resp))
+
+					var actions []model.ModerationAction
+					must(json.NewDecoder(resp.Body).Decode(&actions))
+					for _, a := range actions {
+						moderator := a.ModeratorEmail
+						switch {
+						case a.ModeratorID == nil:
+							moderator = "API key"
+						case moderator == "":
+							moderator = fmt.Sprintf("user %d", *a.ModeratorID)
+						}
+						fmt.Printf("%s - %s %s %d by %s\n", a.CreatedAt.Format(time.DateTime), a.Action, a.ItemType, a.ItemID, moderator)
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "set-role",
+				Usage:     "Change the role of a user to contributor, moderator or admin, only admins can do this",
+				ArgsUsage: "EMAIL ROLE",
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 2 {
+						return fmt.Errorf("usage: enctl set-role EMAIL ROLE")
+					}
+
+					body, err := json.Marshal(map[string]string{"role": c.Args().Get(1)})
+					must(err)
+					url := apiURL + "/moderation/users/" + url.PathEscape(c.Args().Get(0)) + "/role"
+					req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(body))
+					must(err)
+					req.Header = header.Clone()
+					req.Header.Set("Content-Type", "application/json")
+					resp, err := http.DefaultClient.Do(req)
+					must(err)
+					defer resp.Body.Close()
+					must(checkResponse(resp))
+
+					var u model.User
+					must(json.NewDecoder(resp.Body).Decode(&u))
+					fmt.Printf("%s is now a %s\n", u.Email, u.Role)
+					return nil
+				},
+			},
 		},
 	}
 
 	must(app.Run(os.Args))
 }
 
+// bulkModerate applies the given action to the products with the given IDs, and prints the outcome for each of them.
+func bulkModerate(apiURL string, header http.Header, action model.BulkAction, ids []string) error {
+	for _, id := range ids {
+		n, err := strconv.Atoi(id)
+		if err != nil {
+			return fmt.Errorf("invalid product ID %q", id)
+		}
+		action.ProductIDs = append(action.ProductIDs, n)
+	}
+
+	body, err := json.Marshal(action)
+	must(err)
+	req, err := http.NewRequest(http.MethodPost, apiURL+"/moderation/products/bulk", bytes.NewReader(body))
+	must(err)
+	req.Header = header.Clone()
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	must(err)
+	defer resp.Body.Close()
+	must(checkResponse(resp))
+
+	var results []model.BulkResult
+	must(json.NewDecoder(resp.Body).Decode(&results))
+	for _, r := range results {
+		if r.Error != "" {
+			fmt.Printf("%d - failed: %s\n", r.ProductID, r.Error)
+		} else {
+			fmt.Printf("%d - done\n", r.ProductID)
+		}
+	}
+	return nil
+}
+
+// checkResponse returns the error message from the response, if any.
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode < 400 {
+		return nil
+	}
+
+	var e struct {
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&e); err != nil {
+		return fmt.Errorf("request failed with status %s", resp.Status)
+	}
+	return fmt.Errorf("request failed with status %s: %s", resp.Status, e.Message)
+}
+
 func must(err error) {
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%s\n", err)
@@ -86,7 +86,12 @@ const LoginLink = () => {
   const toggleLoginModal = () => setIsLoginModalOpen(!isLoginModalOpen);
 
   if (isLoggedIn) {
-    return <NavbarText>Signed In</NavbarText>;
+    return (
+      <>
+        <NavbarLink href="/submissions">My submissions</NavbarLink>
+        <NavbarText>Signed In</NavbarText>
+      </>
+    );
   }
 
   return (
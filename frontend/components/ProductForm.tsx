@@ -0,0 +1,197 @@
+import Form from "@rjsf/core";
+import { RJSFSchema, UiSchema } from "@rjsf/utils";
+import validator from "@rjsf/validator-ajv6";
+import { useState, MouseEventHandler } from "react";
+
+import { useApi } from "../lib/api";
+import { Fieldset, Field, Category, Schemas, UISchemas } from "../lib/types";
+
+interface OnChange {
+  formData: any;
+}
+
+interface Props {
+  category_slug: string;
+
+  // fieldset_slug -> field_name -> field_value, empty for new products.
+  initialData?: { [key: string]: { [key: string]: any } };
+  submitLabel: string;
+  onSubmit: (data: any) => void;
+}
+
+// ProductForm renders the fieldsets of the given category as a form for submitting or correcting a product.
+const ProductForm = ({
+  category_slug,
+  initialData,
+  submitLabel,
+  onSubmit,
+}: Props) => {
+  const { data: schemas, error: schemaError } = useApi<Schemas>(
+    `categories/${category_slug}/schemas`
+  );
+
+  if (schemaError) {
+    console.error(schemaError);
+    return <div>Error loading schemas</div>;
+  }
+
+  const { data: uiSchemas, error: uiSchemaError } = useApi<UISchemas>(
+    `categories/${category_slug}/ui-schema`
+  );
+
+  if (uiSchemaError) {
+    console.error(uiSchemaError);
+    return <div>Error loading schemas</div>;
+  }
+
+  const { data: category, error: categoryError } = useApi<Category>(
+    `categories/${category_slug}`
+  );
+
+  if (categoryError) {
+    console.error(categoryError);
+    return <div>Error loading category</div>;
+  }
+
+  if (!schemas || !uiSchemas || !category) {
+    return <div>Loading...</div>;
+  }
+
+  return (
+    <Fieldsets
+      category={category}
+      schemas={schemas}
+      uiSchemas={uiSchemas}
+      initialData={initialData}
+      submitLabel={submitLabel}
+      onSubmit={onSubmit}
+    />
+  );
+};
+
+const Fieldsets = ({
+  schemas,
+  uiSchemas,
+  category,
+  initialData,
+  submitLabel,
+  onSubmit,
+}: {
+  schemas: Schemas;
+  uiSchemas: UISchemas;
+  category: Category;
+  initialData?: { [key: string]: { [key: string]: any } };
+  submitLabel: string;
+  onSubmit: (data: any) => void;
+}) => {
+  let startingData: any = {};
+  for (let fieldset of category.fieldsets) {
+    startingData[fieldset.slug] = initialData?.[fieldset.slug] ?? {};
+  }
+
+  const [data, setData] = useState(startingData);
+
+  const onClick: MouseEventHandler = (e) => {
+    e.preventDefault();
+    onSubmit(withoutHiddenFields(category.fieldsets, data));
+  };
+
+  return (
+    <>
+      {category.fieldsets.map((fieldset) => {
+        const onChange = (e: any) => {
+          const newData = {
+            ...data,
+            [fieldset.slug]: e.formData,
+          };
+          setData(newData);
+        };
+
+        return (
+          <Section
+            key={fieldset.slug}
+            fieldset={fieldset}
+            schema={schemas[fieldset.slug]}
+            uiSchema={uiSchemas[fieldset.slug]}
+            onChange={onChange}
+            formData={data[fieldset.slug]}
+          />
+        );
+      })}
+      <button onClick={onClick}>{submitLabel}</button>
+    </>
+  );
+};
+
+const Section = ({
+  fieldset,
+  onChange,
+  formData,
+  schema,
+  uiSchema,
+}: {
+  fieldset: Fieldset;
+  onChange: (data: OnChange) => void;
+  formData: any;
+  schema: RJSFSchema;
+  uiSchema: UiSchema;
+}) => {
+  return (
+    <>
+      <h2>{uiSchema["ui:title"] ?? fieldset.name}</h2>
+      <Form
+        schema={schema}
+        uiSchema={uiSchemaForFieldset(fieldset, uiSchema, formData)}
+        validator={validator}
+        onChange={onChange}
+        formData={formData}
+      />
+    </>
+  );
+};
+
+// conditionHolds returns true if the given field should be shown for the current state of its fieldset.
+const conditionHolds = ({ condition }: Field, formData: any): boolean =>
+  !condition || condition.one_of.includes(formData?.[condition.field]);
+
+// withoutHiddenFields removes the values of fields whose condition doesn't hold, which the API refuses.
+// They're kept in the form itself, so that they come back if the user changes their mind.
+const withoutHiddenFields = (fieldsets: Fieldset[], data: any): any => {
+  const result: any = {};
+  for (let fieldset of fieldsets) {
+    const fsetData = { ...data[fieldset.slug] };
+    for (let field of fieldset.fields) {
+      if (!conditionHolds(field, fsetData)) {
+        delete fsetData[field.name];
+      }
+    }
+    result[fieldset.slug] = fsetData;
+  }
+  return result;
+};
+
+// uiSchemaForFieldset adapts the UI schema from the API to the current state of the form.
+const uiSchemaForFieldset = (
+  { fields }: Fieldset,
+  uiSchema: UiSchema,
+  formData: any
+): UiSchema => {
+  // The title is already rendered as a section heading, so we don't want the form to repeat it.
+  const { "ui:title": _, ...rest } = uiSchema;
+  const schema: UiSchema = {
+    ...rest,
+    "ui:submitButtonOptions": {
+      norender: true,
+    },
+  };
+
+  for (let field of fields) {
+    // Hide fields whose condition doesn't hold, so that they're not announced by screen readers.
+    if (!conditionHolds(field, formData)) {
+      schema[field.name] = { ...schema[field.name], "ui:widget": "hidden" };
+    }
+  }
+  return schema;
+};
+
+export default ProductForm;
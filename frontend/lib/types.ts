@@ -1,16 +1,22 @@
 // For documentation, see the Go types in package model.
 
-import { RJSFSchema } from "@rjsf/utils";
+import { RJSFSchema, UiSchema } from "@rjsf/utils";
 
 export interface Category {
   slug: string;
   name: string;
   short_description: string;
   parent: string;
+  path: Breadcrumb[];
   subcategories: SubcategoryInfo[];
   fieldsets: Fieldset[];
 }
 
+export interface Breadcrumb {
+  slug: string;
+  name: string;
+}
+
 export interface SubcategoryInfo {
   slug: string;
   name: string;
@@ -21,6 +27,11 @@ export interface Schemas {
   [key: string]: RJSFSchema;
 }
 
+// UISchemas map fieldset slugs to react-jsonschema-form UI schemas.
+export interface UISchemas {
+  [key: string]: UiSchema;
+}
+
 export interface Fieldset {
   slug: string;
   name: string;
@@ -31,15 +42,41 @@ export interface Field {
   name: string;
   label: string;
   type: string;
+  optional: boolean;
+  options: string[] | null;
+  condition?: FieldCondition;
+}
+
+// A field with a condition is only relevant when another field in its fieldset has one of the given values.
+export interface FieldCondition {
+  field: string;
+  one_of: any[];
 }
 
+export type ProductStatus =
+  | "pending"
+  | "published"
+  | "rejected"
+  | "changes_requested"
+  | "withdrawn"
+  | "discontinued";
+
 export interface Product {
   id: number;
   name: string;
   category_slug: string;
+  status: ProductStatus;
+  submitted_at: string;
+  published_at?: string;
+  discontinued_at?: string;
   description: string;
   featured_fields: { [key: string]: any };
+  category_path: Breadcrumb[];
 
   // fieldset_slug -> field_name -> field_value
   data: { [key: string]: { [key: string]: any } };
+
+  // Only present when the product was requested in a language other than the original.
+  locale?: string;
+  fallback_fields?: string[];
 }
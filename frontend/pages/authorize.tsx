@@ -1,32 +1,58 @@
-import { useEffect } from "react";
+import { useEffect, useState } from "react";
 import { GetServerSideProps } from "next";
 import { useRouter } from "next/router";
+import { apiURL } from "../lib/api";
 
 export const getServerSideProps: GetServerSideProps = async (context) => {
   return {
     props: {
       token: context.query.token,
-      redirectURI: context.query.redirect_uri,
     },
   };
 };
 
-const Authorize = ({
-  token,
-  redirectURI,
-}: {
-  token: string;
-  redirectURI: string;
-}) => {
+const Authorize = ({ token }: { token: string }) => {
   const router = useRouter();
+  const [error, setError] = useState<string | null>(null);
+
   useEffect(() => {
-    localStorage.setItem("token", token);
+    // The token from the link can only be used once, so it's exchanged for a session token we can keep.
+    const exchange = async () => {
+      const response = await fetch(`${apiURL}/auth/exchange`, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ token }),
+      });
+      const data = await response.json();
+      if (!response.ok) {
+        setError(data.message);
+        return;
+      }
+
+      localStorage.setItem("token", data.token);
+      // The API only accepts redirect URIs pointing to us, so this is safe to follow.
+      if (data.needs_onboarding) {
+        router.push({
+          pathname: "/onboarding",
+          query: { next: data.redirect_uri },
+        });
+        return;
+      }
+      router.push(data.redirect_uri);
+    };
+
+    exchange().catch(() => setError("Could not log in, please try again."));
+    // Exchanging the token twice would fail, so this must only run once.
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, []);
 
-    // set a timer for 1 second that redirects to redirect URI
-    setTimeout(() => {
-      router.push(redirectURI);
-    }, 200);
-  });
+  if (error) {
+    return (
+      <div role="alert">
+        <p>{error}</p>
+      </div>
+    );
+  }
 
   return <div>Authorizing...</div>;
 };
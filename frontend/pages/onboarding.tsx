@@ -0,0 +1,83 @@
+import React, { useState } from "react";
+import { GetServerSideProps } from "next";
+import { useRouter } from "next/router";
+import { Alert, Button, Form, FormGroup, Input, Label } from "reactstrap";
+
+import { PageWithLayout } from "../components/Layout";
+import { getAPIResponse } from "../lib/api";
+
+export const getServerSideProps: GetServerSideProps = async (context) => {
+  return {
+    props: {
+      next: context.query.next || "/",
+    },
+  };
+};
+
+// Onboarding is shown on a user's first login, so that they can choose a display name.
+const Onboarding: PageWithLayout<{ next: string }> = ({ next }) => {
+  const router = useRouter();
+  const [displayName, setDisplayName] = useState("");
+  const [emailConsent, setEmailConsent] = useState(false);
+  const [error, setError] = useState<string | null>(null);
+
+  const submit = async (e: React.FormEvent) => {
+    e.preventDefault();
+
+    const response = await getAPIResponse("auth/me", {
+      method: "PATCH",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({
+        display_name: displayName,
+        email_consent: emailConsent,
+      }),
+    });
+    const data = await response.json();
+    if (!response.ok) {
+      setError(data.message);
+      return;
+    }
+
+    // next comes from the API's redirect_uri, which only ever points to us.
+    router.push(next);
+  };
+
+  return (
+    <>
+      <h1>Welcome to Enably</h1>
+      <p>Before you continue, please tell us how you'd like to be called.</p>
+      {error && <Alert color="danger">{error}</Alert>}
+      <Form onSubmit={submit}>
+        <FormGroup>
+          <Label for="display-name">Display name</Label>
+          <Input
+            id="display-name"
+            required
+            maxLength={50}
+            value={displayName}
+            onChange={(e) => setDisplayName(e.target.value)}
+          />
+          <small>This is shown next to the products you submit.</small>
+        </FormGroup>
+        <FormGroup check>
+          <Input
+            id="email-consent"
+            type="checkbox"
+            checked={emailConsent}
+            onChange={(e) => setEmailConsent(e.target.checked)}
+          />
+          <Label for="email-consent" check>
+            Send me occasional emails about Enably
+          </Label>
+        </FormGroup>
+        <Button color="primary" type="submit">
+          Continue
+        </Button>
+      </Form>
+    </>
+  );
+};
+
+Onboarding.getTitle = () => "Welcome | Enably";
+
+export default Onboarding;
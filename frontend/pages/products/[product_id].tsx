@@ -1,4 +1,5 @@
 import { GetServerSideProps } from "next";
+import { Alert } from "reactstrap";
 import { PageWithLayout } from "../../components/Layout";
 import { Product } from "../../lib/types";
 
@@ -7,6 +8,9 @@ export const getServerSideProps: GetServerSideProps = async ({ params }) => {
   const productResponse = await fetch(
     `${process.env.API_URL}/products/${productID}`
   );
+  if (!productResponse.ok) {
+    return { notFound: true };
+  }
 
   const product = await productResponse.json();
 
@@ -27,6 +31,12 @@ const ProductPage: PageWithLayout<Props> = (props) => {
   return (
     <>
       <h1>{product.name}</h1>
+      {product.status === "discontinued" && (
+        <Alert color="warning">
+          This product has been discontinued, so it may be hard to buy or get
+          support for it.
+        </Alert>
+      )}
       {Object.keys(product.data).map((fieldsetName) => (
         <Fieldset
           key={fieldsetName}
@@ -0,0 +1,133 @@
+import React, { useEffect, useState } from "react";
+import Link from "next/link";
+import {
+  Alert,
+  Badge,
+  FormGroup,
+  Input,
+  Label,
+  ListGroup,
+  ListGroupItem,
+} from "reactstrap";
+
+import { PageWithLayout } from "../components/Layout";
+import { getAPIResponse } from "../lib/api";
+import { ProductStatus } from "../lib/types";
+
+interface Submission {
+  id: number;
+  name: string;
+  status: ProductStatus;
+  rejection_reason?: string;
+}
+
+// statusBadges maps each status to the color and text of its badge.
+const statusBadges: Record<ProductStatus, [string, string]> = {
+  pending: ["secondary", "Waiting for review"],
+  published: ["success", "Published"],
+  rejected: ["danger", "Rejected"],
+  changes_requested: ["warning", "Changes requested"],
+  withdrawn: ["light", "Withdrawn"],
+  discontinued: ["dark", "Discontinued"],
+};
+
+const Status = ({ submission }: { submission: Submission }) => {
+  const [color, text] = statusBadges[submission.status];
+  return <Badge color={color}>{text}</Badge>;
+};
+
+// EmailToggle lets the user opt out of emails about moderators' decisions.
+const EmailToggle = () => {
+  const [enabled, setEnabled] = useState<boolean | null>(null);
+
+  useEffect(() => {
+    getAPIResponse("auth/me")
+      .then((response) => response.json())
+      .then((user) => setEnabled(user.moderation_emails))
+      .catch(() => {});
+  }, []);
+
+  const toggle = async (e: React.ChangeEvent<HTMLInputElement>) => {
+    const checked = e.target.checked;
+    const response = await getAPIResponse("auth/me", {
+      method: "PATCH",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ moderation_emails: checked }),
+    });
+    if (response.ok) {
+      setEnabled(checked);
+    }
+  };
+
+  if (enabled === null) {
+    return null;
+  }
+
+  return (
+    <FormGroup check>
+      <Input
+        id="moderation-emails"
+        type="checkbox"
+        checked={enabled}
+        onChange={toggle}
+      />
+      <Label for="moderation-emails" check>
+        Email me when a moderator approves, rejects or comments on my submissions
+      </Label>
+    </FormGroup>
+  );
+};
+
+// Submissions lists the products the user submitted, so that they can see why
+// a moderator rejected them or which changes they asked for.
+const Submissions: PageWithLayout = () => {
+  const [submissions, setSubmissions] = useState<Submission[] | null>(null);
+  const [error, setError] = useState<string | null>(null);
+
+  useEffect(() => {
+    getAPIResponse("products/mine")
+      .then(async (response) => {
+        const data = await response.json();
+        if (!response.ok) {
+          setError(data.message);
+          return;
+        }
+        setSubmissions(data);
+      })
+      .catch(() => setError("Please log in to see your submissions."));
+  }, []);
+
+  if (error) {
+    return <Alert color="danger">{error}</Alert>;
+  }
+  if (!submissions) {
+    return <p>Loading...</p>;
+  }
+
+  return (
+    <>
+      <h1>My submissions</h1>
+      <EmailToggle />
+      {submissions.length === 0 && <p>You haven't submitted anything yet.</p>}
+      <ListGroup>
+        {submissions.map((s) => (
+          <ListGroupItem key={s.id}>
+            <Status submission={s} />{" "}
+            {s.status === "published" || s.status === "discontinued" ? (
+              <Link href={`/products/${s.id}`}>{s.name}</Link>
+            ) : (
+              <Link href={`/submissions/${s.id}`}>{s.name}</Link>
+            )}
+            {s.status === "rejected" && s.rejection_reason && (
+              <p style={{ whiteSpace: "pre-wrap" }}>{s.rejection_reason}</p>
+            )}
+          </ListGroupItem>
+        ))}
+      </ListGroup>
+    </>
+  );
+};
+
+Submissions.getTitle = () => "My submissions | Enably";
+
+export default Submissions;
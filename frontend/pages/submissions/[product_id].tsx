@@ -0,0 +1,200 @@
+import React, { useEffect, useState } from "react";
+import { useRouter } from "next/router";
+import {
+  Alert,
+  Button,
+  Card,
+  CardBody,
+  CardSubtitle,
+  Form,
+  Input,
+  Label,
+} from "reactstrap";
+
+import { PageWithLayout } from "../../components/Layout";
+import ProductForm from "../../components/ProductForm";
+import { getAPIResponse } from "../../lib/api";
+import { Product } from "../../lib/types";
+
+interface Comment {
+  id: number;
+  author_name?: string;
+  from_moderator: boolean;
+  field?: string;
+  text: string;
+  created_at: string;
+}
+
+const Conversation = ({ comments }: { comments: Comment[] }) => (
+  <>
+    {comments.map((c) => (
+      <Card key={c.id} className="mb-2">
+        <CardBody>
+          <CardSubtitle className="mb-2 text-muted">
+            {c.from_moderator ? "Moderator" : c.author_name ?? "You"}
+            {c.field && ` on ${c.field}`},{" "}
+            {new Date(c.created_at).toLocaleString()}
+          </CardSubtitle>
+          <p style={{ whiteSpace: "pre-wrap" }}>{c.text}</p>
+        </CardBody>
+      </Card>
+    ))}
+  </>
+);
+
+const Reply = ({
+  productID,
+  onSent,
+}: {
+  productID: number;
+  onSent: (c: Comment) => void;
+}) => {
+  const [text, setText] = useState("");
+  const [error, setError] = useState<string | null>(null);
+
+  const send = async (e: React.FormEvent) => {
+    e.preventDefault();
+    const response = await getAPIResponse(`products/${productID}/comments`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ text }),
+    });
+    const data = await response.json();
+    if (!response.ok) {
+      setError(data.message);
+      return;
+    }
+    setError(null);
+    setText("");
+    onSent(data);
+  };
+
+  return (
+    <Form onSubmit={send}>
+      {error && <Alert color="danger">{error}</Alert>}
+      <Label for="reply">Reply to the moderators</Label>
+      <Input
+        id="reply"
+        type="textarea"
+        value={text}
+        onChange={(e) => setText(e.target.value)}
+      />
+      <Button className="mt-2" type="submit">
+        Send
+      </Button>
+    </Form>
+  );
+};
+
+// SubmissionPage shows the conversation between moderators and the submitter of a product,
+// and lets the submitter correct and resubmit it when a moderator asked for changes,
+// or withdraw it while it's not published or rejected.
+const SubmissionPage: PageWithLayout = () => {
+  const router = useRouter();
+  const productID = Number(router.query.product_id);
+
+  const [submission, setSubmission] = useState<Product | null>(null);
+  const [comments, setComments] = useState<Comment[]>([]);
+  const [error, setError] = useState<string | null>(null);
+  const [resubmitted, setResubmitted] = useState(false);
+
+  useEffect(() => {
+    if (!router.isReady) {
+      return;
+    }
+
+    const load = async () => {
+      const response = await getAPIResponse("products/mine");
+      const submissions: Product[] = await response.json();
+      const s = submissions.find((s) => s.id === productID);
+      if (!s) {
+        setError("This submission doesn't exist.");
+        return;
+      }
+      setSubmission(s);
+
+      const commentsResponse = await getAPIResponse(
+        `products/${productID}/comments`
+      );
+      setComments(await commentsResponse.json());
+    };
+    load().catch(() => setError("Please log in to see your submission."));
+  }, [router.isReady, productID]);
+
+  const resubmit = async (data: any) => {
+    const response = await getAPIResponse(`products/${productID}`, {
+      method: "PUT",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify(data),
+    });
+    const body = await response.json();
+    if (!response.ok) {
+      setError(body.message);
+      return;
+    }
+    setError(null);
+    setSubmission(body);
+    setResubmitted(true);
+  };
+
+  const withdraw = async () => {
+    if (!window.confirm("Withdraw this submission? It can't be resubmitted.")) {
+      return;
+    }
+    const response = await getAPIResponse(`products/${productID}/withdraw`, {
+      method: "POST",
+    });
+    const body = await response.json();
+    if (!response.ok) {
+      setError(body.message);
+      return;
+    }
+    setError(null);
+    setSubmission(body);
+  };
+
+  if (!submission) {
+    return error ? <Alert color="danger">{error}</Alert> : <p>Loading...</p>;
+  }
+
+  return (
+    <>
+      <h1>{submission.name}</h1>
+      {error && <Alert color="danger">{error}</Alert>}
+      {resubmitted && (
+        <Alert color="success">
+          Thank you! Your submission is waiting for review again.
+        </Alert>
+      )}
+      <Conversation comments={comments} />
+      <Reply
+        productID={productID}
+        onSent={(c) => setComments([...comments, c])}
+      />
+      {submission.status === "withdrawn" && (
+        <Alert color="secondary">You have withdrawn this submission.</Alert>
+      )}
+      {submission.status === "changes_requested" && (
+        <>
+          <h2 className="mt-4">Make the changes</h2>
+          <ProductForm
+            category_slug={submission.category_slug}
+            initialData={submission.data}
+            submitLabel="Resubmit"
+            onSubmit={resubmit}
+          />
+        </>
+      )}
+      {(submission.status === "pending" ||
+        submission.status === "changes_requested") && (
+        <Button className="mt-4" color="danger" outline onClick={withdraw}>
+          Withdraw this submission
+        </Button>
+      )}
+    </>
+  );
+};
+
+SubmissionPage.getTitle = () => "My submission | Enably";
+
+export default SubmissionPage;
@@ -1,17 +1,8 @@
-import Form from "@rjsf/core";
-import { RJSFSchema, UiSchema } from "@rjsf/utils";
-import validator from "@rjsf/validator-ajv6";
-import { useRouter } from "next/router";
 import { GetStaticPaths, GetStaticProps } from "next";
-import { useState, MouseEventHandler } from "react";
 
-import { useApi } from "../../lib/api";
-import { Fieldset, Category, Schemas } from "../../lib/types";
+import { getAPIResponse } from "../../lib/api";
 import { PageWithLayout } from "../../components/Layout";
-
-interface OnChange {
-  formData: any;
-}
+import ProductForm from "../../components/ProductForm";
 
 export const getStaticPaths: GetStaticPaths = async () => {
   // FIXME: actually get the list of categories from the API.
@@ -35,131 +26,23 @@ interface Props {
 }
 
 const Submit: PageWithLayout<Props> = ({ category_slug }) => {
-  const { data: schemas, error: schemaError } = useApi<Schemas>(
-    `categories/${category_slug}/schemas`
-  );
-
-  if (schemaError) {
-    console.error(schemaError);
-    return <div>Error loading schemas</div>;
-  }
-
-  const { data: category, error: categoryError } = useApi<Category>(
-    `categories/${category_slug}`
-  );
-
-  if (categoryError) {
-    console.error(categoryError);
-    return <div>Error loading category</div>;
-  }
-
-  if (!schemas || !category) {
-    return <div>Loading...</div>;
-  }
-
-  return <SubmitPage category={category} schemas={schemas} />;
-};
-
-const SubmitPage = ({
-  schemas,
-  category,
-}: {
-  schemas: Schemas;
-  category: Category;
-}) => {
-  let initialData: any = {};
-  for (let fieldset of category.fieldsets) {
-    initialData[fieldset.slug] = {};
-  }
-
-  const [data, setData] = useState(initialData);
-
-  const onSubmit: MouseEventHandler = (e) => {
-    e.preventDefault();
-    submit(category.slug, data);
-  };
-
   return (
     <>
       <h1>Submit a product</h1>
-      {category.fieldsets.map((fieldset) => {
-        const onChange = (e: any) => {
-          const newData = {
-            ...data,
-            [fieldset.slug]: e.formData,
-          };
-          setData(newData);
-        };
-
-        return (
-          <Section
-            key={fieldset.slug}
-            fieldset={fieldset}
-            schema={schemas[fieldset.slug]}
-            onChange={onChange}
-            formData={data[fieldset.slug]}
-          />
-        );
-      })}
-      <button onClick={onSubmit}>Submit</button>
-    </>
-  );
-};
-
-const Section = ({
-  fieldset,
-  onChange,
-  formData,
-  schema,
-}: {
-  fieldset: Fieldset;
-  onChange: (data: OnChange) => void;
-  formData: any;
-  schema: RJSFSchema;
-}) => {
-  return (
-    <>
-      <h2>{fieldset.name}</h2>
-      <Form
-        schema={schema}
-        uiSchema={uiSchemaForFieldset(fieldset)}
-        validator={validator}
-        onChange={onChange}
-        formData={formData}
+      <ProductForm
+        category_slug={category_slug}
+        submitLabel="Submit"
+        onSubmit={(data) => submit(category_slug, data)}
       />
     </>
   );
 };
 
-const uiSchemaForFieldset = ({ fields }: Fieldset): UiSchema => {
-  const schema: UiSchema = {
-    "ui:submitButtonOptions": {
-      norender: true,
-    },
-    "ui:order": fields.map((field) => field.name),
-  };
-
-  const widgetTypes = {
-    textarea: "textarea",
-    dropdown: "select",
-    "radio-buttons": "radio",
-  };
-
-  for (let field of fields) {
-    if (field.type in widgetTypes) {
-      schema[field.name] = {
-        "ui:widget": widgetTypes[field.type as keyof typeof widgetTypes],
-      };
-    }
-  }
-  return schema;
-};
-
 export default Submit;
 
 // FIXME: URLencode the slug, potential vulnerability here.
 const submit = (category_slug: string, data: any) =>
-  fetch(`/api/v1/products/${category_slug}`, {
+  getAPIResponse(`products/${category_slug}`, {
     method: "post",
     headers: {
       "Content-Type": "application/json",
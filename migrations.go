@@ -0,0 +1,9 @@
+package enably
+
+import "embed"
+
+//go:embed migrations
+
+// Migrations contains the SQL migrations for all supported databases,
+// in the migrations/postgres and migrations/sqlite directories.
+var Migrations embed.FS
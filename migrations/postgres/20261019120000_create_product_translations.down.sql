@@ -0,0 +1 @@
+DROP TABLE product_translations;
\ No newline at end of file
@@ -0,0 +1,10 @@
+CREATE TABLE product_translations (
+  id BIGSERIAL PRIMARY KEY,
+  product_id bigint NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  locale text NOT NULL,
+  data jsonb NOT NULL,
+  approved BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX product_translations_product_id_locale_idx ON product_translations(product_id, locale);
@@ -0,0 +1,18 @@
+CREATE TABLE login_tokens (
+  id BIGSERIAL PRIMARY KEY,
+  email_address text NOT NULL,
+  token text NOT NULL UNIQUE,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  expires_at timestamp(0) with time zone NOT NULL
+);
+
+-- Existing sessions were started with tokens sent by email, which may still sit in someone's inbox,
+-- so they all expire right away.
+ALTER TABLE session_tokens
+ADD COLUMN expires_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+ADD COLUMN last_used_at timestamp(0) with time zone NOT NULL DEFAULT NOW();
+
+ALTER TABLE session_tokens
+ALTER COLUMN expires_at DROP DEFAULT;
+
+CREATE UNIQUE INDEX session_tokens_token_idx ON session_tokens(token);
@@ -0,0 +1,2 @@
+ALTER TABLE login_tokens
+DROP COLUMN redirect_uri;
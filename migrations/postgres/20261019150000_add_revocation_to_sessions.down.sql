@@ -0,0 +1,5 @@
+DROP INDEX session_tokens_email_address_idx;
+
+ALTER TABLE session_tokens
+DROP COLUMN user_agent,
+DROP COLUMN revoked_at;
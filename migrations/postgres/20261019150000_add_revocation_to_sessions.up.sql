@@ -0,0 +1,5 @@
+ALTER TABLE session_tokens
+ADD COLUMN user_agent text NOT NULL DEFAULT '',
+ADD COLUMN revoked_at timestamp(0) with time zone;
+
+CREATE INDEX session_tokens_email_address_idx ON session_tokens(email_address);
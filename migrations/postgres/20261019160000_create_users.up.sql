@@ -0,0 +1,15 @@
+CREATE TABLE users (
+  id BIGSERIAL PRIMARY KEY,
+  email_address text NOT NULL UNIQUE,
+  display_name text NOT NULL DEFAULT '',
+  email_consent boolean NOT NULL DEFAULT FALSE,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  onboarded_at timestamp(0) with time zone
+);
+
+-- Email addresses are now normalized before being stored.
+UPDATE session_tokens
+SET email_address = lower(trim(email_address));
+
+UPDATE login_tokens
+SET email_address = lower(trim(email_address));
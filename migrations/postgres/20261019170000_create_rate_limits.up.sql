@@ -0,0 +1,5 @@
+CREATE TABLE rate_limits (
+  key text PRIMARY KEY,
+  hits integer NOT NULL,
+  resets_at timestamp with time zone NOT NULL
+);
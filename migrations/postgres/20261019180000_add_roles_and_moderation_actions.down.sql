@@ -0,0 +1,4 @@
+DROP TABLE moderation_actions;
+
+ALTER TABLE users
+DROP COLUMN role;
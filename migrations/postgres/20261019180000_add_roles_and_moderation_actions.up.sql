@@ -0,0 +1,14 @@
+ALTER TABLE users
+ADD COLUMN role text NOT NULL DEFAULT 'contributor' CHECK (role IN ('contributor', 'moderator', 'admin'));
+
+CREATE TABLE moderation_actions (
+  id BIGSERIAL PRIMARY KEY,
+  item_type text NOT NULL,
+  item_id integer NOT NULL,
+  action text NOT NULL,
+  -- NULL if the moderation API key was used.
+  moderator_id bigint REFERENCES users(id) ON DELETE SET NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX moderation_actions_item_idx ON moderation_actions(item_type, item_id);
@@ -0,0 +1,10 @@
+DROP INDEX products_submitted_by_idx;
+
+ALTER TABLE products
+DROP COLUMN submitted_by;
+
+ALTER TABLE products
+DROP COLUMN rejected_at;
+
+ALTER TABLE products
+DROP COLUMN rejection_reason;
@@ -0,0 +1,12 @@
+-- Products submitted before accounts existed have no submitter.
+ALTER TABLE products
+ADD COLUMN submitted_by bigint REFERENCES users(id) ON DELETE SET NULL;
+
+-- Rejected products are kept, so that their submitters can see why they were rejected.
+ALTER TABLE products
+ADD COLUMN rejected_at timestamp(0) with time zone;
+
+ALTER TABLE products
+ADD COLUMN rejection_reason text NOT NULL DEFAULT '';
+
+CREATE INDEX products_submitted_by_idx ON products(submitted_by);
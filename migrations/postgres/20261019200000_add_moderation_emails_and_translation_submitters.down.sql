@@ -0,0 +1,5 @@
+ALTER TABLE product_translations
+DROP COLUMN submitted_by;
+
+ALTER TABLE users
+DROP COLUMN moderation_emails;
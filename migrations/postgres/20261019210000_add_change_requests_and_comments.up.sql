@@ -0,0 +1,15 @@
+-- Set while a product waits for its submitter to make the changes a moderator asked for.
+ALTER TABLE products
+ADD COLUMN changes_requested_at timestamp(0) with time zone;
+
+CREATE TABLE product_comments (
+  id BIGSERIAL PRIMARY KEY,
+  product_id bigint NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  author_id bigint REFERENCES users(id) ON DELETE SET NULL,
+  from_moderator boolean NOT NULL,
+  field text NOT NULL DEFAULT '',
+  text text NOT NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX product_comments_product_id_idx ON product_comments(product_id);
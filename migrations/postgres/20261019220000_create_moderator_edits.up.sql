@@ -0,0 +1,11 @@
+-- Changes made by moderators when approving a product, kept apart from the submitter's original data.
+CREATE TABLE moderator_edits (
+  id BIGSERIAL PRIMARY KEY,
+  product_id bigint NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  moderator_id bigint REFERENCES users(id) ON DELETE SET NULL,
+  original_data jsonb NOT NULL,
+  changes jsonb NOT NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX moderator_edits_product_id_idx ON moderator_edits(product_id);
@@ -0,0 +1,10 @@
+DROP TABLE moderator_notes;
+
+ALTER TABLE products
+DROP COLUMN claimed_at;
+
+ALTER TABLE products
+DROP COLUMN claimed_by;
+
+ALTER TABLE products
+DROP COLUMN resubmitted_at;
@@ -0,0 +1,21 @@
+-- Set when a submitter resubmits a product after a moderator asked for changes.
+ALTER TABLE products
+ADD COLUMN resubmitted_at timestamp(0) with time zone;
+
+-- The moderator reviewing a product, so that others can skip it. Claims expire after a while.
+ALTER TABLE products
+ADD COLUMN claimed_by bigint REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE products
+ADD COLUMN claimed_at timestamp(0) with time zone;
+
+-- Internal notes, only shown to moderators.
+CREATE TABLE moderator_notes (
+  id BIGSERIAL PRIMARY KEY,
+  product_id bigint NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  author_id bigint REFERENCES users(id) ON DELETE SET NULL,
+  text text NOT NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX moderator_notes_product_id_idx ON moderator_notes(product_id);
@@ -0,0 +1,36 @@
+-- Replaces the approved flag, which couldn't tell apart the stages of a product's lifecycle. See model.ProductStatus.
+ALTER TABLE products
+ADD COLUMN status text NOT NULL DEFAULT 'pending'
+CHECK (status IN ('pending', 'published', 'rejected', 'changes_requested', 'withdrawn', 'discontinued'));
+
+ALTER TABLE products
+ADD COLUMN published_at timestamp(0) with time zone;
+
+ALTER TABLE products
+ADD COLUMN withdrawn_at timestamp(0) with time zone;
+
+ALTER TABLE products
+ADD COLUMN discontinued_at timestamp(0) with time zone;
+
+-- Rejections and change requests used to be cleared when a product was approved, so approval wins here.
+UPDATE products
+SET status = CASE
+  WHEN approved THEN 'published'
+  WHEN rejected_at IS NOT NULL THEN 'rejected'
+  WHEN changes_requested_at IS NOT NULL THEN 'changes_requested'
+  ELSE 'pending'
+END;
+
+-- Products approved before moderation actions were recorded fall back to the time of their last update.
+UPDATE products
+SET published_at = COALESCE(
+  (SELECT MAX(created_at) FROM moderation_actions
+    WHERE item_type = 'product' AND item_id = products.id AND action = 'approve'),
+  updated_at
+)
+WHERE status = 'published';
+
+ALTER TABLE products
+DROP COLUMN approved;
+
+CREATE INDEX products_status_idx ON products(status, category_slug);
@@ -0,0 +1,13 @@
+-- Products can no longer have a price unless they're paid, see the condition of software.price in schema.toml.
+-- Prices kept from before would make those products fail validation when they're resubmitted or edited.
+UPDATE products
+SET data = data #- '{software,price}'
+WHERE data #> '{software,price}' IS NOT NULL
+AND data #>> '{software,free_or_paid}' IS DISTINCT FROM 'Paid';
+
+UPDATE product_translations t
+SET data = t.data #- '{software,price}'
+FROM products p
+WHERE p.id = t.product_id
+AND t.data #> '{software,price}' IS NOT NULL
+AND p.data #>> '{software,free_or_paid}' IS DISTINCT FROM 'Paid';
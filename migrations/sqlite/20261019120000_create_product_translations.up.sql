@@ -0,0 +1,10 @@
+CREATE TABLE product_translations (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  locale TEXT NOT NULL,
+  data TEXT NOT NULL CHECK (json_valid(data)),
+  approved BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX product_translations_product_id_locale_idx ON product_translations(product_id, locale);
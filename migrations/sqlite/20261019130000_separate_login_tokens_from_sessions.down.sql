@@ -0,0 +1,9 @@
+DROP INDEX session_tokens_token_idx;
+
+ALTER TABLE session_tokens
+DROP COLUMN expires_at;
+
+ALTER TABLE session_tokens
+DROP COLUMN last_used_at;
+
+DROP TABLE login_tokens;
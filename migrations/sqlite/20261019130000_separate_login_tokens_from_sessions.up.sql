@@ -0,0 +1,17 @@
+CREATE TABLE login_tokens (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  email_address TEXT NOT NULL,
+  token TEXT NOT NULL UNIQUE,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  expires_at DATETIME NOT NULL
+);
+
+-- Existing sessions were started with tokens sent by email, which may still sit in someone's inbox,
+-- so they all expire right away.
+ALTER TABLE session_tokens
+ADD COLUMN expires_at DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00';
+
+ALTER TABLE session_tokens
+ADD COLUMN last_used_at DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00';
+
+CREATE UNIQUE INDEX session_tokens_token_idx ON session_tokens(token);
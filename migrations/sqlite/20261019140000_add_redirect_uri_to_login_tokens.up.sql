@@ -0,0 +1,2 @@
+ALTER TABLE login_tokens
+ADD COLUMN redirect_uri TEXT NOT NULL DEFAULT '';
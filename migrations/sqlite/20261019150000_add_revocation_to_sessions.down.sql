@@ -0,0 +1,7 @@
+DROP INDEX session_tokens_email_address_idx;
+
+ALTER TABLE session_tokens
+DROP COLUMN user_agent;
+
+ALTER TABLE session_tokens
+DROP COLUMN revoked_at;
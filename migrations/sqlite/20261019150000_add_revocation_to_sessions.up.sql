@@ -0,0 +1,7 @@
+ALTER TABLE session_tokens
+ADD COLUMN user_agent TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE session_tokens
+ADD COLUMN revoked_at DATETIME;
+
+CREATE INDEX session_tokens_email_address_idx ON session_tokens(email_address);
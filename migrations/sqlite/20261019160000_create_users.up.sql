@@ -0,0 +1,15 @@
+CREATE TABLE users (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  email_address TEXT NOT NULL UNIQUE,
+  display_name TEXT NOT NULL DEFAULT '',
+  email_consent BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  onboarded_at DATETIME
+);
+
+-- Email addresses are now normalized before being stored.
+UPDATE session_tokens
+SET email_address = lower(trim(email_address));
+
+UPDATE login_tokens
+SET email_address = lower(trim(email_address));
@@ -0,0 +1,5 @@
+CREATE TABLE rate_limits (
+  key TEXT PRIMARY KEY,
+  hits INTEGER NOT NULL,
+  resets_at DATETIME NOT NULL
+);
@@ -0,0 +1,14 @@
+ALTER TABLE users
+ADD COLUMN role TEXT NOT NULL DEFAULT 'contributor' CHECK (role IN ('contributor', 'moderator', 'admin'));
+
+CREATE TABLE moderation_actions (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  item_type TEXT NOT NULL,
+  item_id INTEGER NOT NULL,
+  action TEXT NOT NULL,
+  -- NULL if the moderation API key was used.
+  moderator_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX moderation_actions_item_idx ON moderation_actions(item_type, item_id);
@@ -0,0 +1,7 @@
+-- Users get emails about the moderation of their submissions unless they opt out.
+ALTER TABLE users
+ADD COLUMN moderation_emails BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- Translations submitted before accounts existed have no submitter.
+ALTER TABLE product_translations
+ADD COLUMN submitted_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
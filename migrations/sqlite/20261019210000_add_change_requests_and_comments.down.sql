@@ -0,0 +1,4 @@
+DROP TABLE product_comments;
+
+ALTER TABLE products
+DROP COLUMN changes_requested_at;
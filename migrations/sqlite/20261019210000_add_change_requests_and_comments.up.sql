@@ -0,0 +1,15 @@
+-- Set while a product waits for its submitter to make the changes a moderator asked for.
+ALTER TABLE products
+ADD COLUMN changes_requested_at DATETIME;
+
+CREATE TABLE product_comments (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  from_moderator BOOLEAN NOT NULL,
+  field TEXT NOT NULL DEFAULT '',
+  text TEXT NOT NULL,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX product_comments_product_id_idx ON product_comments(product_id);
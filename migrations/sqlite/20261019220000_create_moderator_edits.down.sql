@@ -0,0 +1 @@
+DROP TABLE moderator_edits;
@@ -0,0 +1,11 @@
+-- Changes made by moderators when approving a product, kept apart from the submitter's original data.
+CREATE TABLE moderator_edits (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  moderator_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  original_data TEXT NOT NULL CHECK (json_valid(original_data)),
+  changes TEXT NOT NULL CHECK (json_valid(changes)),
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX moderator_edits_product_id_idx ON moderator_edits(product_id);
@@ -0,0 +1,21 @@
+-- Set when a submitter resubmits a product after a moderator asked for changes.
+ALTER TABLE products
+ADD COLUMN resubmitted_at DATETIME;
+
+-- The moderator reviewing a product, so that others can skip it. Claims expire after a while.
+ALTER TABLE products
+ADD COLUMN claimed_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE products
+ADD COLUMN claimed_at DATETIME;
+
+-- Internal notes, only shown to moderators.
+CREATE TABLE moderator_notes (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+  author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  text TEXT NOT NULL,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX moderator_notes_product_id_idx ON moderator_notes(product_id);
@@ -0,0 +1,32 @@
+ALTER TABLE products
+ADD COLUMN approved BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE products
+SET approved = status IN ('published', 'discontinued');
+
+-- Withdrawals can't be represented without a status, rejecting the products is the closest thing.
+UPDATE products
+SET rejected_at = withdrawn_at, rejection_reason = 'Withdrawn by the submitter.'
+WHERE status = 'withdrawn';
+
+UPDATE products
+SET rejected_at = NULL, rejection_reason = ''
+WHERE status NOT IN ('rejected', 'withdrawn');
+
+UPDATE products
+SET changes_requested_at = NULL
+WHERE status <> 'changes_requested';
+
+DROP INDEX products_status_idx;
+
+ALTER TABLE products
+DROP COLUMN discontinued_at;
+
+ALTER TABLE products
+DROP COLUMN withdrawn_at;
+
+ALTER TABLE products
+DROP COLUMN published_at;
+
+ALTER TABLE products
+DROP COLUMN status;
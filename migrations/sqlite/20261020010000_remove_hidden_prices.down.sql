@@ -0,0 +1 @@
+-- The removed prices were hidden, and products can't have them anymore, so they aren't restored.
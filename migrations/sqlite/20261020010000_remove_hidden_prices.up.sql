@@ -0,0 +1,11 @@
+-- Products can no longer have a price unless they're paid, see the condition of software.price in schema.toml.
+-- Prices kept from before would make those products fail validation when they're resubmitted or edited.
+UPDATE products
+SET data = json_remove(data, '$.software.price')
+WHERE json_type(data, '$.software.price') IS NOT NULL
+AND json_extract(data, '$.software.free_or_paid') IS NOT 'Paid';
+
+UPDATE product_translations
+SET data = json_remove(data, '$.software.price')
+WHERE json_type(data, '$.software.price') IS NOT NULL
+AND product_id IN (SELECT id FROM products WHERE json_extract(data, '$.software.free_or_paid') IS NOT 'Paid');
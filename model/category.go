@@ -13,6 +13,10 @@ type Category struct {
 	// If this is a top-level category, it is empty.
 	Parent string `json:"parent,omitempty"`
 
+	// Path lists the ancestors of this category, starting from the top-level one, followed by the category itself.
+	// It is meant for displaying breadcrumbs.
+	Path []*Breadcrumb `json:"path"`
+
 	// Subcategories is a list of categories which this category is a parent of.
 	Subcategories []*SubcategoryInfo `json:"subcategories"`
 
@@ -39,8 +43,35 @@ type SubcategoryInfo struct {
 	IsLeafCategory bool `json:"is_leaf_category"`
 }
 
+// Breadcrumb identifies a single category on the path from the root down to another category.
+type Breadcrumb struct {
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
 // IsLeafCategory returns true if this category has no subcategories.
 // Leaf categories can only contain products, while non-leaf categories can contain other subcategories, but not products directly.
 func (c *Category) IsLeafCategory() bool {
-	return len(c.Subcategories) > 0
+	return len(c.Subcategories) == 0
+}
+
+// CategoryTreeNode is a single category in the tree of all categories, along with the number of products it contains.
+// For branch categories, the counts are totals of all their subcategories.
+type CategoryTreeNode struct {
+	Slug           string `json:"slug"`
+	Name           string `json:"name"`
+	IsLeafCategory bool   `json:"is_leaf_category"`
+
+	ApprovedProducts int `json:"approved_products"`
+
+	// PendingProducts is only present if it was explicitly requested.
+	PendingProducts *int `json:"pending_products,omitempty"`
+
+	Subcategories []*CategoryTreeNode `json:"subcategories"`
+}
+
+// ProductCounts contains the number of products in a single category.
+type ProductCounts struct {
+	Approved int // published products.
+	Pending  int
 }
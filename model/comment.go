@@ -0,0 +1,48 @@
+package model
+
+import (
+	"net/http"
+	"time"
+)
+
+// MaxCommentLength is the maximum length of a comment on a submission, in characters.
+const MaxCommentLength = 2000
+
+// Comment is a message in the conversation between the moderators and the submitter of a product.
+type Comment struct {
+	ID        int64 `json:"id"`
+	ProductID int   `json:"product_id"`
+
+	// AuthorID is nil if a moderator used the moderation API key, or the author's account was deleted.
+	AuthorID   *int64 `json:"author_id"`
+	AuthorName string `json:"author_name,omitempty"` // the author's display name.
+
+	// FromModerator is true for comments made by moderators, and false for the submitter's replies.
+	FromModerator bool `json:"from_moderator"`
+
+	// Field is the field the comment is about, in the fieldset_slug.field_name format.
+	// It is empty for comments about the submission as a whole.
+	Field string `json:"field,omitempty"`
+
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FieldComment is a comment to be added to a submission, optionally about one of its fields.
+type FieldComment struct {
+	Field string `json:"field"`
+	Text  string `json:"text"`
+}
+
+// ChangeRequest is a moderator's decision to send a submission back to its author to be corrected.
+// At least one of the message and the field comments must be given.
+type ChangeRequest struct {
+	Message       string         `json:"message"`
+	FieldComments []FieldComment `json:"field_comments"`
+}
+
+// ErrNoChangesRequested is returned when trying to resubmit a product no moderator has asked to be changed.
+var ErrNoChangesRequested = UserFacingError{
+	HTTPStatusCode:    http.StatusConflict,
+	UserFacingMessage: "no changes were requested to this submission",
+}
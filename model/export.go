@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// ExportMetadata describes a bulk export of the Enably directory.
+type ExportMetadata struct {
+	// SchemaVersion identifies the version of the schema the exported products conform to.
+	SchemaVersion string    `json:"schema_version"`
+	GeneratedAt   time.Time `json:"generated_at"`
+
+	Licence     string `json:"licence"`
+	LicenceURL  string `json:"licence_url"`
+	Attribution string `json:"attribution"`
+	Source      string `json:"source"` // where the data comes from.
+}
+
+// ExportedProduct is an approved product as it appears in bulk exports.
+type ExportedProduct struct {
+	ID           int                       `json:"id"`
+	CategorySlug string                    `json:"category_slug"`
+	CategoryPath []*Breadcrumb             `json:"category_path"`
+	Name         string                    `json:"name"`
+	Description  string                    `json:"description"`
+	Data         map[string]map[string]any `json:"data"`
+}
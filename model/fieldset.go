@@ -5,6 +5,10 @@ type Fieldset struct {
 	Slug string `json:"slug"` // used by computers
 	Name string `json:"name"` // used by humans
 
+	// Order determines where this fieldset appears in lists of all fieldsets, see the schema for details.
+	Order int `json:"-"`
+
+	// Fields are kept in the order they are defined in.
 	Fields []*Field `json:"fields"`
 }
 
@@ -15,4 +19,34 @@ type Field struct {
 	Type     string   `json:"type"`  // TODO:provide constants for the types we accept.
 	Optional bool     `json:"optional"`
 	Options  []string `json:"options"`
+
+	// Description is a longer explanation of what to put in the field, shown as help text in forms.
+	Description string `json:"description,omitempty"`
+
+	// Autocomplete is the value of the HTML autocomplete attribute for this field.
+	// If empty, browsers are told not to autocomplete free-form fields, as they never contain the user's own details.
+	Autocomplete string `json:"autocomplete,omitempty"`
+
+	// Condition, if present, makes this field relevant only when another field has one of the given values.
+	// Forms should hide the field otherwise, and a non-optional field is only required when the condition holds.
+	Condition *FieldCondition `json:"condition,omitempty"`
+}
+
+// FieldCondition makes a field depend on the value of another field in the same fieldset.
+type FieldCondition struct {
+	Field string `json:"field"` // the name of the field this condition depends on.
+
+	// OneOf lists the values of Field for which the condition holds.
+	// These are booleans for checkboxes and options for radio buttons and dropdowns.
+	OneOf []any `json:"one_of" toml:"one_of"`
+}
+
+// FieldByName returns the field with the given name, or nil if this fieldset doesn't contain it.
+func (fs *Fieldset) FieldByName(name string) *Field {
+	for _, f := range fs.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
 }
@@ -0,0 +1,22 @@
+package model
+
+// ImportResult describes the outcome of a bulk import of products.
+type ImportResult struct {
+	DryRun bool `json:"dry_run"`
+
+	Total int `json:"total"` // the number of products in the file
+	Valid int `json:"valid"` // the number of products that passed validation
+
+	// ProductIDs contains the IDs of the inserted products. It is empty for dry runs.
+	ProductIDs []int `json:"product_ids"`
+
+	Errors []ImportError `json:"errors"`
+}
+
+// ImportError describes why a single product couldn't be imported.
+type ImportError struct {
+	// Row is the 1-based number of the line (for JSON Lines) or record (for CSV) the product was on.
+	// As the first CSV record contains the headers, the first product in a CSV file is on row 2.
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
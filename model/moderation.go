@@ -0,0 +1,84 @@
+package model
+
+import (
+	"net/http"
+	"time"
+)
+
+// Moderator is whoever makes a moderation decision.
+type Moderator struct {
+	// UserID is the ID of the moderator's account.
+	// It is zero if the moderation API key was used instead of an account.
+	UserID int64
+}
+
+// ModerationAction records a moderation decision, so that it's possible to tell who approved or rejected what.
+type ModerationAction struct {
+	ID       int64  `json:"id"`
+	ItemType string `json:"item_type"` // "product" or "translation".
+	ItemID   int    `json:"item_id"`
+	Action   string `json:"action"` // "approve", "reject", "request_changes" or "discontinue".
+
+	// ModeratorID is nil if the moderation API key was used.
+	ModeratorID    *int64 `json:"moderator_id"`
+	ModeratorEmail string `json:"moderator_email,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Approval is the optional body of a request to approve a product.
+type Approval struct {
+	// Changes are applied to the product's data before it's approved, e.g. to fix a typo.
+	// They map fieldset slugs to field names to new values, and a null value removes the field.
+	Changes map[string]map[string]any `json:"changes,omitempty"`
+}
+
+// ModeratorEdit records the changes a moderator made to a product when approving it,
+// so that they can be told apart from what the submitter wrote.
+type ModeratorEdit struct {
+	ID        int64 `json:"id"`
+	ProductID int   `json:"product_id"`
+
+	// ModeratorID is nil if the moderation API key was used.
+	ModeratorID    *int64 `json:"moderator_id"`
+	ModeratorEmail string `json:"moderator_email,omitempty"`
+
+	// Original is the product's data as it was before the edit.
+	Original map[string]map[string]any `json:"original"`
+
+	// Changes only contains the fields which the moderator changed, with their new values.
+	// Removed fields are null.
+	Changes map[string]map[string]any `json:"changes"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Kinds of items that can be moderated.
+const (
+	ItemProduct     = "product"
+	ItemTranslation = "translation"
+)
+
+// Moderation actions.
+const (
+	ActionApprove        = "approve"
+	ActionReject         = "reject"
+	ActionRequestChanges = "request_changes"
+	ActionDiscontinue    = "discontinue"
+)
+
+// NewModerationAction returns a record of the given moderator taking the given action on an item.
+func NewModerationAction(itemType string, itemID int, action string, by Moderator) ModerationAction {
+	a := ModerationAction{ItemType: itemType, ItemID: itemID, Action: action}
+	if by.UserID != 0 {
+		id := by.UserID
+		a.ModeratorID = &id
+	}
+	return a
+}
+
+// ErrItemNotFound is returned when trying to moderate a product or translation which doesn't exist.
+var ErrItemNotFound = UserFacingError{
+	HTTPStatusCode:    http.StatusNotFound,
+	UserFacingMessage: "there's no such product or translation",
+}
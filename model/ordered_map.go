@@ -0,0 +1,105 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// OrderedMap is a JSON object which remembers the order its keys were added in.
+// Unlike a regular map, it is encoded with its keys in that order, rather than alphabetically.
+type OrderedMap struct {
+	keys   []string
+	values map[string]any
+}
+
+// NewOrderedMap returns an empty OrderedMap.
+func NewOrderedMap() *OrderedMap {
+	return &OrderedMap{values: make(map[string]any)}
+}
+
+// Set sets the value for the given key.
+// New keys are added at the end, while existing keys keep their position.
+func (m *OrderedMap) Set(key string, value any) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// Get returns the value for the given key, if any.
+func (m *OrderedMap) Get(key string) (value any, ok bool) {
+	value, ok = m.values[key]
+	return value, ok
+}
+
+// Keys returns the keys of the map in order.
+func (m *OrderedMap) Keys() []string {
+	return m.keys
+}
+
+// Len returns the number of keys in the map.
+func (m *OrderedMap) Len() int {
+	return len(m.keys)
+}
+
+// MarshalJSON encodes the map as a JSON object with its keys in order.
+func (m *OrderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		encodedKey, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(encodedKey)
+		buf.WriteByte(':')
+
+		encodedValue, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, fmt.Errorf("error when encoding value for key %q: %w", key, err)
+		}
+		buf.Write(encodedValue)
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON decodes a JSON object, preserving the order of its keys.
+// Nested objects are decoded as regular maps.
+func (m *OrderedMap) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("expected a JSON object, got %v", tok)
+	}
+
+	m.keys = nil
+	m.values = make(map[string]any)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key := tok.(string) // object keys are always strings.
+
+		var value any
+		if err := dec.Decode(&value); err != nil {
+			return fmt.Errorf("error when decoding value for key %q: %w", key, err)
+		}
+		m.Set(key, value)
+	}
+
+	_, err = dec.Token() // the closing brace
+	return err
+}
@@ -1,16 +1,56 @@
 package model
 
+import (
+	"net/http"
+	"time"
+)
+
 type Product struct {
-	ID           int    `json:"id"`
-	CategorySlug string `json:"category_slug"`
-	Approved     bool   `json:"approved"`
+	ID           int           `json:"id"`
+	CategorySlug string        `json:"category_slug"`
+	Status       ProductStatus `json:"status"`
+
+	// SubmittedBy is the ID of the user who submitted the product.
+	// It is nil for imported products, and ones submitted without logging in.
+	SubmittedBy *int64 `json:"submitted_by,omitempty"`
+
+	// The times below record when the product last got each status, see ProductStatus.
+	// They're kept when it changes again, so that e.g. a published product shows when it was rejected before an appeal.
+	SubmittedAt        time.Time  `json:"submitted_at"`
+	ResubmittedAt      *time.Time `json:"resubmitted_at,omitempty"`
+	PublishedAt        *time.Time `json:"published_at,omitempty"`
+	RejectedAt         *time.Time `json:"rejected_at,omitempty"`
+	ChangesRequestedAt *time.Time `json:"changes_requested_at,omitempty"`
+	WithdrawnAt        *time.Time `json:"withdrawn_at,omitempty"`
+	DiscontinuedAt     *time.Time `json:"discontinued_at,omitempty"`
+
+	// RejectionReason is shown to the submitter of a rejected product.
+	// Comments explaining what to change when a moderator asks for changes are kept separately.
+	RejectionReason string `json:"rejection_reason,omitempty"`
 
 	// maps fieldset slugs to maps of field names to their values
 	Data map[string]map[string]any `json:"data"`
 
 	// The fields below aren't stored in the database,
 	// as they can be derived from the JSON data and the schema.
-	Name           string         `json:"name"`
-	Description    string         `json:"description"`
-	FeaturedFields map[string]any `json:"featured_fields"`
+	Name           string      `json:"name"`
+	Description    string      `json:"description"`
+	FeaturedFields *OrderedMap `json:"featured_fields"` // in the order given by the category.
+
+	// CategoryPath lists the product's category and its ancestors, starting from the top-level one.
+	CategoryPath []*Breadcrumb `json:"category_path"`
+
+	// Locale is the language the texts of this product were requested in.
+	// It is empty if the product is in its original language.
+	Locale string `json:"locale,omitempty"`
+
+	// FallbackFields lists the translatable fields, in the fieldset_slug.field_name format,
+	// which have no translation into Locale and contain the original text instead.
+	FallbackFields []string `json:"fallback_fields,omitempty"`
+}
+
+// ErrProductNotFound is returned when there's no product with a given ID, or it can't be shown.
+var ErrProductNotFound = UserFacingError{
+	HTTPStatusCode:    http.StatusNotFound,
+	UserFacingMessage: "product not found",
 }
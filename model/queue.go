@@ -0,0 +1,122 @@
+package model
+
+import (
+	"net/http"
+	"time"
+)
+
+// Kinds of submissions in the moderation queue.
+const (
+	// SubmissionNew is a product submitted for the first time.
+	SubmissionNew = "new"
+
+	// SubmissionResubmission is a product which its submitter corrected after a moderator asked for changes.
+	SubmissionResubmission = "resubmission"
+
+	// SubmissionEdit is a change to a published product. So far, translations of its texts are the only such changes.
+	SubmissionEdit = "edit"
+)
+
+// ClaimDuration is how long a moderator's claim on a product lasts.
+// Claims expire so that products don't get stuck when a moderator forgets about them.
+const ClaimDuration = 30 * time.Minute
+
+// MaxNoteLength is the maximum length of a moderator note, in characters.
+const MaxNoteLength = 2000
+
+// MaxQueuePageSize is the largest number of products that can be requested from the moderation queue at once.
+const MaxQueuePageSize = 200
+
+// MaxBulkActionSize is the largest number of products that can be moderated with a single bulk action.
+const MaxBulkActionSize = 100
+
+// QueueQuery selects a page of the moderation queue.
+type QueueQuery struct {
+	// CategorySlugs limits the queue to products in the given categories. All categories are included if it's empty.
+	CategorySlugs []string
+
+	// SubmissionType is SubmissionNew, SubmissionResubmission or SubmissionEdit, or empty for all of them.
+	SubmissionType string
+
+	// NewestFirst sorts the queue by the time products entered it, newest first. Otherwise, the oldest are first.
+	NewestFirst bool
+
+	Limit  int
+	Offset int
+}
+
+// QueueItem is a submission waiting for a moderator's decision, along with what moderators need to know about it.
+// For edits, Product is the published product being changed, and Translation is the change.
+type QueueItem struct {
+	Product
+
+	SubmissionType string `json:"submission_type"`
+
+	// Translation is only set for edits.
+	Translation *Translation `json:"translation,omitempty"`
+
+	// QueuedAt is when the submission was made or, for resubmissions, when the product was last resubmitted.
+	QueuedAt time.Time `json:"queued_at"`
+
+	// Claim is nil if nobody is reviewing the product. Edits can't be claimed.
+	Claim *Claim `json:"claim,omitempty"`
+
+	// NoteCount is the number of notes about the product. It's always zero for edits.
+	NoteCount int `json:"note_count"`
+}
+
+// QueuePage is a page of the moderation queue.
+type QueuePage struct {
+	Items []QueueItem `json:"items"`
+
+	// Total is the number of submissions in the queue which match the query, on all pages.
+	Total int `json:"total"`
+}
+
+// Claim marks a product as being reviewed by a moderator, so that others can skip it.
+type Claim struct {
+	ModeratorID    int64     `json:"moderator_id"`
+	ModeratorEmail string    `json:"moderator_email,omitempty"`
+	ClaimedAt      time.Time `json:"claimed_at"`
+}
+
+// Expired reports whether the claim has lasted longer than ClaimDuration.
+func (c *Claim) Expired() bool {
+	return time.Since(c.ClaimedAt) > ClaimDuration
+}
+
+// ModeratorNote is an internal note about a product, only shown to moderators.
+type ModeratorNote struct {
+	ID        int64 `json:"id"`
+	ProductID int   `json:"product_id"`
+
+	// AuthorID is nil if the moderation API key was used.
+	AuthorID    *int64 `json:"author_id"`
+	AuthorEmail string `json:"author_email,omitempty"`
+
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BulkAction approves or rejects several products at once.
+type BulkAction struct {
+	Action     string `json:"action"` // ActionApprove or ActionReject.
+	ProductIDs []int  `json:"product_ids"`
+
+	// Rejection is required when rejecting, and applies to all the products.
+	Rejection *Rejection `json:"rejection,omitempty"`
+}
+
+// BulkResult is the outcome of a bulk action for a single product.
+type BulkResult struct {
+	ProductID int `json:"product_id"`
+
+	// Error is empty if the action succeeded.
+	Error string `json:"error,omitempty"`
+}
+
+// ErrProductClaimed is returned when a moderator tries to claim or decide on a product which another moderator has claimed.
+var ErrProductClaimed = UserFacingError{
+	HTTPStatusCode:    http.StatusConflict,
+	UserFacingMessage: "another moderator is reviewing this product",
+}
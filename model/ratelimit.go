@@ -0,0 +1,31 @@
+package model
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// RateLimit allows at most Requests requests in every window of the given length.
+// A limit with no requests allowed is treated as no limit at all.
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// RateLimitError is returned when a rate limit has been exceeded.
+type RateLimitError struct {
+	RetryAfter time.Duration // how long until the next request will be allowed.
+}
+
+func (e RateLimitError) Error() string {
+	return fmt.Sprintf("rate limit exceeded, retry after %s", e.RetryAfter)
+}
+
+// Unwrap lets RateLimitError be handled like any other UserFacingError.
+func (e RateLimitError) Unwrap() error {
+	return UserFacingError{
+		HTTPStatusCode:    http.StatusTooManyRequests,
+		UserFacingMessage: "too many requests, please try again later",
+	}
+}
@@ -0,0 +1,26 @@
+package model
+
+// MaxRejectionReasonLength is the maximum length of a rejection reason written by a moderator, in characters.
+const MaxRejectionReasonLength = 2000
+
+// CannedRejectionReason is a common reason for rejecting a submission, which moderators can pick instead of writing their own.
+type CannedRejectionReason struct {
+	Code string `json:"code"`
+	Text string `json:"text"` // shown to the submitter.
+}
+
+// CannedRejectionReasons lists the reasons moderators can pick from.
+var CannedRejectionReasons = []CannedRejectionReason{
+	{"duplicate", "This product is already listed on Enably."},
+	{"wrong_category", "This product doesn't belong in the category it was submitted to."},
+	{"insufficient_information", "The submission doesn't contain enough information about the product's accessibility."},
+	{"inaccurate", "Some of the information in the submission doesn't match the product."},
+	{"spam", "The submission looks like spam or advertising."},
+}
+
+// Rejection is a moderator's decision to reject a submission.
+// At least one of the fields must be set. If both are, the canned reason is followed by the moderator's own words.
+type Rejection struct {
+	CannedReason string `json:"canned_reason"` // the code of one of the CannedRejectionReasons.
+	Reason       string `json:"reason"`
+}
@@ -0,0 +1,102 @@
+package model
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// ProductStatus is the stage of its lifecycle a product is in.
+type ProductStatus string
+
+const (
+	// StatusPending is the status of new and resubmitted products, which wait for a moderator's decision.
+	StatusPending ProductStatus = "pending"
+
+	// StatusPublished products are shown to everyone.
+	StatusPublished ProductStatus = "published"
+
+	// StatusRejected products are only shown to their submitters, along with the reason.
+	StatusRejected ProductStatus = "rejected"
+
+	// StatusChangesRequested products were sent back to their submitters to be corrected and resubmitted.
+	StatusChangesRequested ProductStatus = "changes_requested"
+
+	// StatusWithdrawn products were taken back by their submitters before being published.
+	StatusWithdrawn ProductStatus = "withdrawn"
+
+	// StatusDiscontinued products are no longer made or sold. They can still be seen by everyone,
+	// so that links to them keep working, but they aren't listed anymore.
+	StatusDiscontinued ProductStatus = "discontinued"
+)
+
+// productTransitions lists the statuses a product with a given status can change to.
+// Withdrawn products can't change at all.
+var productTransitions = map[ProductStatus][]ProductStatus{
+	StatusPending:          {StatusPublished, StatusRejected, StatusChangesRequested, StatusWithdrawn},
+	StatusChangesRequested: {StatusPending, StatusRejected, StatusWithdrawn},
+
+	// Rejected products can be approved after an appeal, and published ones can be taken down.
+	StatusRejected:  {StatusPublished},
+	StatusPublished: {StatusRejected, StatusDiscontinued},
+
+	// Discontinued products can be published again if they come back on the market.
+	StatusDiscontinued: {StatusPublished},
+}
+
+// CanBecome returns true if a product with status s can change to the given status.
+func (s ProductStatus) CanBecome(to ProductStatus) bool {
+	for _, allowed := range productTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusesBecoming returns the statuses which can change to the given status, sorted by name.
+func StatusesBecoming(to ProductStatus) []ProductStatus {
+	var from []ProductStatus
+	for s := range productTransitions {
+		if s.CanBecome(to) {
+			from = append(from, s)
+		}
+	}
+
+	sort.Slice(from, func(i, j int) bool { return from[i] < from[j] })
+	return from
+}
+
+// Public returns true if products with this status can be seen by everyone.
+func (s ProductStatus) Public() bool {
+	return s == StatusPublished || s == StatusDiscontinued
+}
+
+// statusDescriptions describe products with each status in error messages, e.g. "this product is waiting for review".
+var statusDescriptions = map[ProductStatus]string{
+	StatusPending:          "waiting for review",
+	StatusPublished:        "published",
+	StatusRejected:         "rejected",
+	StatusChangesRequested: "waiting for changes",
+	StatusWithdrawn:        "withdrawn",
+	StatusDiscontinued:     "discontinued",
+}
+
+// transitionDescriptions describe changing to each status in error messages, e.g. "it can't be withdrawn".
+var transitionDescriptions = map[ProductStatus]string{
+	StatusPending:          "resubmitted",
+	StatusPublished:        "published",
+	StatusRejected:         "rejected",
+	StatusChangesRequested: "sent back for changes",
+	StatusWithdrawn:        "withdrawn",
+	StatusDiscontinued:     "marked as discontinued",
+}
+
+// ErrInvalidTransition returns the error for trying to change the status of a product from one status to another
+// when CanBecome doesn't allow it.
+func ErrInvalidTransition(from, to ProductStatus) error {
+	return UserFacingError{
+		HTTPStatusCode:    http.StatusConflict,
+		UserFacingMessage: fmt.Sprintf("this product is %s, so it can't be %s", statusDescriptions[from], transitionDescriptions[to]),
+	}
+}
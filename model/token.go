@@ -7,12 +7,45 @@ import (
 	"github.com/google/uuid"
 )
 
-// Token is a secure token authenticating a user with a specific email address.
-type Token struct {
+// LoginToken is a short-lived, single-use token sent to a user in a "magic link".
+// It can only be exchanged for a session, it doesn't authenticate any other requests.
+type LoginToken struct {
 	ID        int64     `json:"id"`
 	Email     string    `json:"email"`
 	Token     string    `json:"token"`
 	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// RedirectURI is where the user is sent after logging in.
+	// It's kept here, rather than in the link, so that nobody can forge a link redirecting elsewhere.
+	RedirectURI string `json:"redirect_uri"`
+}
+
+// Session is a secure token authenticating a user with a specific email address.
+// Sessions expire unless they're used, each use pushes the expiry further into the future.
+type Session struct {
+	ID         int64     `json:"id"`
+	Email      string    `json:"email"`
+	Token      string    `json:"-"` // Only ever sent to the user once, when the session is created.
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	UserAgent  string    `json:"user_agent"` // of the client which started the session.
+
+	// RevokedAt is set when the user logs out, or ends the session from another device.
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+
+	// Current is set when listing sessions, for the one the list was requested with.
+	Current bool `json:"current"`
+}
+
+// Login is the result of exchanging a login token for a session.
+type Login struct {
+	Session Session
+	User    User
+
+	// RedirectURI is where the user asked to be sent after logging in.
+	RedirectURI string
 }
 
 var ErrInvalidToken = UserFacingError{
@@ -20,10 +53,35 @@ var ErrInvalidToken = UserFacingError{
 	UserFacingMessage: "Invalid authentication token",
 }
 
-// NewToken 		creates a new token for a given email address.
-func NewToken(email string) *Token {
-	return &Token{
-		Email: email,
-		Token: uuid.NewString(),
+// ErrSessionNotFound is returned when trying to end a session that doesn't exist or belongs to someone else.
+var ErrSessionNotFound = UserFacingError{
+	HTTPStatusCode:    http.StatusNotFound,
+	UserFacingMessage: "Session not found",
+}
+
+// ErrExpiredLoginToken is returned when a login link has already been used or has expired.
+var ErrExpiredLoginToken = UserFacingError{
+	HTTPStatusCode:    http.StatusForbidden,
+	UserFacingMessage: "This login link has expired or has already been used, please request a new one",
+}
+
+// NewLoginToken creates a new login token for a given email address, valid for the given duration.
+func NewLoginToken(email string, lifetime time.Duration) *LoginToken {
+	return &LoginToken{
+		Email:     email,
+		Token:     uuid.NewString(),
+		ExpiresAt: time.Now().Add(lifetime),
+	}
+}
+
+// NewSession creates a new session for a given email address, expiring after the given duration of inactivity.
+func NewSession(email, userAgent string, lifetime time.Duration) *Session {
+	now := time.Now()
+	return &Session{
+		Email:      email,
+		UserAgent:  userAgent,
+		Token:      uuid.NewString(),
+		ExpiresAt:  now.Add(lifetime),
+		LastUsedAt: now,
 	}
 }
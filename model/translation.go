@@ -0,0 +1,32 @@
+package model
+
+import (
+	"net/http"
+	"time"
+)
+
+// MaxTranslatedTextLength is the maximum length of a single translated text, in characters.
+const MaxTranslatedTextLength = 10000
+
+// Translation contains user-submitted translations of a product's texts into a single locale.
+// Like products, translations need to be approved by a moderator before they're shown.
+type Translation struct {
+	ID        int       `json:"id"`
+	ProductID int       `json:"product_id"`
+	Locale    string    `json:"locale"` // a BCP 47 language tag, e.g. "pl" or "pt-BR".
+	Approved  bool      `json:"approved"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// SubmittedBy is the ID of the user who submitted the translation, if they were logged in.
+	SubmittedBy *int64 `json:"submitted_by,omitempty"`
+
+	// maps fieldset slugs to maps of field names to the translated texts.
+	// A translation doesn't need to contain all the translatable fields of a product.
+	Data map[string]map[string]string `json:"data"`
+}
+
+// ErrTranslationAlreadyApproved is returned when trying to approve a translation which has already been approved.
+var ErrTranslationAlreadyApproved = UserFacingError{
+	HTTPStatusCode:    http.StatusConflict,
+	UserFacingMessage: "this translation has already been approved",
+}
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// MaxDisplayNameLength is the maximum length of a display name, in characters.
+const MaxDisplayNameLength = 50
+
+// Role determines what a user is allowed to do.
+type Role string
+
+const (
+	// RoleContributor is the role of every new user. Contributors can submit products and translations.
+	RoleContributor Role = "contributor"
+
+	// RoleModerator lets users approve and reject submissions.
+	RoleModerator Role = "moderator"
+
+	// RoleAdmin lets users moderate, and change the roles of other users.
+	RoleAdmin Role = "admin"
+)
+
+// Valid returns true if r is one of the known roles.
+func (r Role) Valid() bool {
+	return r == RoleContributor || r == RoleModerator || r == RoleAdmin
+}
+
+// CanModerate returns true if users with this role can approve and reject submissions.
+func (r Role) CanModerate() bool {
+	return r == RoleModerator || r == RoleAdmin
+}
+
+// User is an account of someone who has logged in at least once.
+type User struct {
+	ID          int64  `json:"id"`
+	Email       string `json:"email"` // always normalized, see NormalizeEmail.
+	DisplayName string `json:"display_name"`
+	Role        Role   `json:"role"`
+
+	// EmailConsent is true if the user agreed to receive occasional emails, other than the ones they ask for, like login links.
+	EmailConsent bool `json:"email_consent"`
+
+	// ModerationEmails is true unless the user opted out of emails about moderators' decisions on their submissions.
+	ModerationEmails bool `json:"moderation_emails"`
+
+	CreatedAt time.Time `json:"created_at"`
+
+	// OnboardedAt is set once the user has chosen a display name on their first login.
+	OnboardedAt *time.Time `json:"onboarded_at"`
+}
+
+// NeedsOnboarding returns true if the user hasn't yet chosen a display name and whether they want to receive emails.
+func (u User) NeedsOnboarding() bool {
+	return u.OnboardedAt == nil
+}
+
+// ProfileUpdate contains changes to a user's profile. Nil fields are left unchanged.
+type ProfileUpdate struct {
+	DisplayName      *string `json:"display_name"`
+	EmailConsent     *bool   `json:"email_consent"`
+	ModerationEmails *bool   `json:"moderation_emails"`
+}
+
+// ErrUserNotFound is returned when there's no user with a given email address or ID.
+var ErrUserNotFound = UserFacingError{
+	HTTPStatusCode:    http.StatusNotFound,
+	UserFacingMessage: "User not found",
+}
+
+// NormalizeEmail checks that the given string is a bare email address, e.g. "john@example.com", and normalizes it,
+// so that the same person always ends up with the same account.
+// Surrounding whitespace is removed and the address is lowercased.
+// While the part before the @ is technically case-sensitive, no email provider we know of treats it that way.
+func NormalizeEmail(address string) (string, error) {
+	address = strings.TrimSpace(address)
+
+	parsed, err := mail.ParseAddress(address)
+	if err != nil || parsed.Address != address {
+		return "", UserFacingError{
+			HTTPStatusCode:    http.StatusBadRequest,
+			UserFacingMessage: "please provide a valid email address, like john@example.com",
+		}
+	}
+
+	return strings.ToLower(parsed.Address), nil
+}
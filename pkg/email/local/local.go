@@ -0,0 +1,52 @@
+// Package local provides an email sender for local development, which never actually sends anything.
+package local
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mikolysz/enably/pkg/email"
+)
+
+// Sender logs messages instead of sending them.
+// If Dir is set, each message is also written to a separate file in that directory.
+type Sender struct {
+	Dir string
+}
+
+func NewSender(dir string) *Sender {
+	return &Sender{Dir: dir}
+}
+
+func (s *Sender) Send(m email.Message) error {
+	log.Printf("email to %s: %s\n%s", m.Recipient, m.Subject, m.PlainTextContent)
+
+	if s.Dir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return fmt.Errorf("error when creating email directory: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s.txt", time.Now().Format("20060102-150405.000000"), sanitize(m.Recipient))
+	content := fmt.Sprintf("To: %s\nSubject: %s\n\n%s\n", m.Recipient, m.Subject, m.PlainTextContent)
+	if err := os.WriteFile(filepath.Join(s.Dir, name), []byte(content), 0o644); err != nil {
+		return fmt.Errorf("error when writing email: %w", err)
+	}
+	return nil
+}
+
+// sanitize makes an email address safe to use in a file name.
+func sanitize(address string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == os.PathSeparator {
+			return '_'
+		}
+		return r
+	}, address)
+}
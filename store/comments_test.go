@@ -0,0 +1,76 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/mikolysz/enably/model"
+)
+
+func TestChangeRequests(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s testStores) {
+		c := context.Background()
+		moderator := addTestUser(t, s, "moderator@example.com")
+		submitter := addTestUser(t, s, "submitter@example.com")
+		p := addTestProduct(t, s, "screen_readers", &submitter.ID)
+
+		if err := s.products.ResubmitProduct(c, p.ID, testProductData("NVDA")); !errors.Is(err, model.ErrNoChangesRequested) {
+			t.Errorf("resubmitting a product without requested changes returned %v, want %v", err, model.ErrNoChangesRequested)
+		}
+
+		comments := []model.Comment{
+			{ProductID: p.ID, AuthorID: &moderator.ID, FromModerator: true, Text: "Please add a download link"},
+			{ProductID: p.ID, AuthorID: &moderator.ID, FromModerator: true, Field: "software.name", Text: "Is this the full name?"},
+		}
+		if err := s.products.RequestProductChanges(c, p.ID, comments, model.Moderator{UserID: moderator.ID}); err != nil {
+			t.Fatal(err)
+		}
+		got, err := s.products.GetProductByID(c, p.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != model.StatusChangesRequested || got.ChangesRequestedAt == nil {
+			t.Errorf("GetProductByID returned %+v after requesting changes, want it waiting for changes", got)
+		}
+		assertQueuedProducts(t, s)
+
+		reply, err := s.products.AddProductComment(c, model.Comment{ProductID: p.ID, AuthorID: &submitter.ID, Text: "Done"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reply.ID == 0 {
+			t.Errorf("AddProductComment returned %+v, want a comment with an ID", reply)
+		}
+
+		// Comments are listed oldest first.
+		listed, err := s.products.GetProductComments(c, p.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var texts []string
+		for _, cm := range listed {
+			texts = append(texts, cm.Text)
+		}
+		if want := []string{"Please add a download link", "Is this the full name?", "Done"}; !reflect.DeepEqual(texts, want) {
+			t.Fatalf("GetProductComments returned comments %q, want %q", texts, want)
+		}
+		if !listed[1].FromModerator || listed[1].Field != "software.name" || listed[2].FromModerator ||
+			listed[2].AuthorID == nil || *listed[2].AuthorID != submitter.ID {
+			t.Errorf("GetProductComments returned %+v, want the moderator's comments followed by the submitter's reply", listed)
+		}
+
+		if err := s.products.ResubmitProduct(c, p.ID, testProductData("NVDA 2023")); err != nil {
+			t.Fatal(err)
+		}
+		got, err = s.products.GetProductByID(c, p.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != model.StatusPending || got.ResubmittedAt == nil || got.Data["software"]["name"] != "NVDA 2023" {
+			t.Errorf("GetProductByID returned %+v after resubmitting, want the new data waiting for approval", got)
+		}
+		assertQueuedProducts(t, s, p.ID)
+	})
+}
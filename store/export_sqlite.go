@@ -0,0 +1,188 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mikolysz/enably/model"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" driver.
+)
+
+// exportSchema creates the tables of an SQLite export.
+// Besides the raw JSON data of each product, every field value is stored in product_values,
+// so that products can be filtered with plain SQL.
+const exportSchema = `
+CREATE TABLE metadata (
+  key TEXT PRIMARY KEY,
+  value TEXT NOT NULL
+);
+
+CREATE TABLE categories (
+  slug TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  short_description TEXT NOT NULL,
+  parent TEXT REFERENCES categories(slug),
+  path TEXT NOT NULL, -- names of the category and its ancestors, starting from the top-level one, separated by " > ".
+  position INTEGER NOT NULL
+);
+
+CREATE TABLE fields (
+  fieldset_slug TEXT NOT NULL,
+  fieldset_name TEXT NOT NULL,
+  name TEXT NOT NULL,
+  label TEXT NOT NULL,
+  type TEXT NOT NULL,
+  description TEXT NOT NULL,
+  position INTEGER NOT NULL,
+  PRIMARY KEY (fieldset_slug, name)
+);
+
+CREATE TABLE products (
+  id INTEGER PRIMARY KEY,
+  category_slug TEXT NOT NULL REFERENCES categories(slug),
+  name TEXT NOT NULL,
+  description TEXT NOT NULL,
+  data TEXT NOT NULL -- JSON
+);
+
+CREATE TABLE product_values (
+  product_id INTEGER NOT NULL REFERENCES products(id),
+  fieldset_slug TEXT NOT NULL,
+  field_name TEXT NOT NULL,
+  value,
+  PRIMARY KEY (product_id, fieldset_slug, field_name)
+);
+
+CREATE INDEX products_category_slug_idx ON products(category_slug);
+`
+
+// SQLiteExportDatabase writes an export of the whole directory to a new SQLite database.
+// Everything is written in a single transaction, which is committed on Close.
+type SQLiteExportDatabase struct {
+	db *sql.DB
+	tx *sql.Tx
+
+	categories int // the number of categories added so far, used for their positions.
+	fieldsets  int
+}
+
+// NewSQLiteExportDatabase creates an SQLite database at the given path and prepares it for an export.
+func NewSQLiteExportDatabase(path string) (*SQLiteExportDatabase, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("error when opening SQLite database: %s", err)
+	}
+
+	if _, err := db.Exec(exportSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error when creating export tables: %s", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error when starting transaction: %s", err)
+	}
+
+	return &SQLiteExportDatabase{db: db, tx: tx}, nil
+}
+
+// SetMetadata stores the export metadata as key-value pairs.
+func (e *SQLiteExportDatabase) SetMetadata(m model.ExportMetadata) error {
+	values := map[string]string{
+		"schema_version": m.SchemaVersion,
+		"generated_at":   m.GeneratedAt.Format(time.RFC3339),
+		"licence":        m.Licence,
+		"licence_url":    m.LicenceURL,
+		"attribution":    m.Attribution,
+		"source":         m.Source,
+	}
+
+	for k, v := range values {
+		if _, err := e.tx.Exec("INSERT INTO metadata(key, value) VALUES(?, ?)", k, v); err != nil {
+			return fmt.Errorf("error when inserting metadata: %s", err)
+		}
+	}
+	return nil
+}
+
+// AddCategory adds a category. Parents must be added before their subcategories.
+func (e *SQLiteExportDatabase) AddCategory(cat *model.Category) error {
+	names := make([]string, len(cat.Path))
+	for i, b := range cat.Path {
+		names[i] = b.Name
+	}
+
+	var parent *string
+	if cat.Parent != "" {
+		parent = &cat.Parent
+	}
+
+	query := "INSERT INTO categories(slug, name, short_description, parent, path, position) VALUES(?, ?, ?, ?, ?, ?)"
+	if _, err := e.tx.Exec(query, cat.Slug, cat.Name, cat.ShortDescription, parent, strings.Join(names, " > "), e.categories); err != nil {
+		return fmt.Errorf("error when inserting category: %s", err)
+	}
+	e.categories++
+	return nil
+}
+
+// AddFieldset adds the fields of a fieldset.
+func (e *SQLiteExportDatabase) AddFieldset(fset *model.Fieldset) error {
+	query := "INSERT INTO fields(fieldset_slug, fieldset_name, name, label, type, description, position) VALUES(?, ?, ?, ?, ?, ?, ?)"
+	for i, field := range fset.Fields {
+		position := e.fieldsets*1000 + i
+		if _, err := e.tx.Exec(query, fset.Slug, fset.Name, field.Name, field.Label, field.Type, field.Description, position); err != nil {
+			return fmt.Errorf("error when inserting field: %s", err)
+		}
+	}
+	e.fieldsets++
+	return nil
+}
+
+// AddProduct adds a product along with all of its field values.
+func (e *SQLiteExportDatabase) AddProduct(p model.ExportedProduct) error {
+	data, err := json.Marshal(p.Data)
+	if err != nil {
+		return fmt.Errorf("error when encoding product data: %s", err)
+	}
+
+	query := "INSERT INTO products(id, category_slug, name, description, data) VALUES(?, ?, ?, ?, ?)"
+	if _, err := e.tx.Exec(query, p.ID, p.CategorySlug, p.Name, p.Description, string(data)); err != nil {
+		return fmt.Errorf("error when inserting product: %s", err)
+	}
+
+	query = "INSERT INTO product_values(product_id, fieldset_slug, field_name, value) VALUES(?, ?, ?, ?)"
+	for fsetSlug, fields := range p.Data {
+		for name, value := range fields {
+			// SQLite can only store scalars, anything else is stored as JSON.
+			switch value.(type) {
+			case nil, string, bool, float64:
+			default:
+				encoded, err := json.Marshal(value)
+				if err != nil {
+					return fmt.Errorf("error when encoding product value: %s", err)
+				}
+				value = string(encoded)
+			}
+
+			if _, err := e.tx.Exec(query, p.ID, fsetSlug, name, value); err != nil {
+				return fmt.Errorf("error when inserting product value: %s", err)
+			}
+		}
+	}
+	return nil
+}
+
+// Close commits the transaction and closes the database.
+func (e *SQLiteExportDatabase) Close() error {
+	defer e.db.Close()
+
+	if err := e.tx.Commit(); err != nil {
+		return fmt.Errorf("error when committing export: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,652 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mikolysz/enably/model"
+)
+
+// MemoryProductsStore is a ProductsStore that keeps everything in memory.
+// It's meant for local development and loses all data when the process exits.
+type MemoryProductsStore struct {
+	mu sync.RWMutex
+
+	// both kept in order of their IDs.
+	products     []model.Product
+	translations []model.Translation
+
+	// moderation actions, comments and moderator edits, oldest first.
+	actions  []model.ModerationAction
+	comments []model.Comment
+	edits    []model.ModeratorEdit
+	notes    []model.ModeratorNote
+
+	// claims holds the moderators' claims on products, by product ID.
+	claims map[int]model.Claim
+
+	lastProductID     int
+	lastTranslationID int
+	lastActionID      int64
+	lastCommentID     int64
+	lastEditID        int64
+	lastNoteID        int64
+}
+
+// NewMemoryProductsStore returns an empty MemoryProductsStore.
+func NewMemoryProductsStore() *MemoryProductsStore {
+	return &MemoryProductsStore{claims: map[int]model.Claim{}}
+}
+
+// AddProduct adds a product to the store.
+// The returned product will have the "id", "status" and "submitted_at" fields filled in.
+func (s *MemoryProductsStore) AddProduct(c context.Context, p model.Product) (model.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastProductID++
+	p = model.Product{
+		ID:           s.lastProductID,
+		CategorySlug: p.CategorySlug,
+		Status:       model.StatusPending,
+		SubmittedBy:  p.SubmittedBy,
+		SubmittedAt:  time.Now().Truncate(time.Second),
+		Data:         p.Data,
+	}
+	s.products = append(s.products, copyProduct(p))
+	return p, nil
+}
+
+// AddProducts adds several products to the store at once.
+// The returned products will have the "id", "status" and "submitted_at" fields filled in.
+func (s *MemoryProductsStore) AddProducts(c context.Context, ps []model.Product) ([]model.Product, error) {
+	inserted := make([]model.Product, 0, len(ps))
+	for _, p := range ps {
+		p, _ := s.AddProduct(c, p)
+		inserted = append(inserted, p)
+	}
+	return inserted, nil
+}
+
+// GetProductsByCategory returns all products in the category with the given slug.
+// ONLY published products are returned.
+func (s *MemoryProductsStore) GetProductsByCategory(c context.Context, slug string) ([]model.Product, error) {
+	return s.findProducts(func(p model.Product) bool {
+		return p.Status == model.StatusPublished && p.CategorySlug == slug
+	}), nil
+}
+
+// StreamPublishedProducts calls fn for every published product in the category with the given slug,
+// or in all categories if the slug is empty, in order of their IDs.
+func (s *MemoryProductsStore) StreamPublishedProducts(c context.Context, categorySlug string, fn func(model.Product) error) error {
+	// fn is called without holding the lock, so that it can use the store too.
+	products := s.findProducts(func(p model.Product) bool {
+		return p.Status == model.StatusPublished && (categorySlug == "" || p.CategorySlug == categorySlug)
+	})
+
+	for _, p := range products {
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetProductByID returns the product with the given ID.
+func (s *MemoryProductsStore) GetProductByID(c context.Context, id int) (model.Product, error) {
+	products := s.findProducts(func(p model.Product) bool {
+		return p.ID == id
+	})
+
+	if len(products) == 0 {
+		return model.Product{}, model.ErrProductNotFound
+	}
+	return products[0], nil
+}
+
+// GetProductsBySubmitter returns all products submitted by the user with the given ID, newest first, whatever their status.
+func (s *MemoryProductsStore) GetProductsBySubmitter(c context.Context, userID int64) ([]model.Product, error) {
+	products := s.findProducts(func(p model.Product) bool {
+		return p.SubmittedBy != nil && *p.SubmittedBy == userID
+	})
+
+	for i, j := 0, len(products)-1; i < j; i, j = i+1, j-1 {
+		products[i], products[j] = products[j], products[i]
+	}
+	return products, nil
+}
+
+// findProducts returns copies of all products matching the given predicate.
+func (s *MemoryProductsStore) findProducts(match func(model.Product) bool) []model.Product {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var products []model.Product
+	for _, p := range s.products {
+		if match(p) {
+			products = append(products, copyProduct(p))
+		}
+	}
+	return products
+}
+
+// ApproveProduct publishes the product with the given ID, recording who did it.
+func (s *MemoryProductsStore) ApproveProduct(c context.Context, id int, by model.Moderator) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, err := s.transition(id, model.StatusPublished)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkClaim(id, by); err != nil {
+		return err
+	}
+
+	now := time.Now().Truncate(time.Second)
+	s.products[i].Status = model.StatusPublished
+	s.products[i].PublishedAt = &now
+	s.releaseClaim(id)
+	s.recordAction(model.NewModerationAction(model.ItemProduct, id, model.ActionApprove, by))
+	return nil
+}
+
+// EditAndApproveProduct replaces the data of the product with the given ID and approves it, recording who did it.
+// The edit is kept along with the product's original data.
+func (s *MemoryProductsStore) EditAndApproveProduct(c context.Context, id int, data map[string]map[string]any, edit model.ModeratorEdit, by model.Moderator) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, err := s.transition(id, model.StatusPublished)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkClaim(id, by); err != nil {
+		return err
+	}
+
+	now := time.Now().Truncate(time.Second)
+	s.products[i].Data = copyProduct(model.Product{Data: data}).Data
+	s.products[i].Status = model.StatusPublished
+	s.products[i].PublishedAt = &now
+	s.releaseClaim(id)
+
+	a := model.NewModerationAction(model.ItemProduct, id, model.ActionApprove, by)
+	s.lastEditID++
+	s.edits = append(s.edits, model.ModeratorEdit{
+		ID:          s.lastEditID,
+		ProductID:   id,
+		ModeratorID: a.ModeratorID,
+		Original:    copyProduct(model.Product{Data: edit.Original}).Data,
+		Changes:     copyProduct(model.Product{Data: edit.Changes}).Data,
+		CreatedAt:   now,
+	})
+	s.recordAction(a)
+	return nil
+}
+
+// GetModeratorEdits returns the edits moderators made to the product with the given ID, oldest first.
+// Moderator emails aren't filled in, as users are kept in a separate store.
+func (s *MemoryProductsStore) GetModeratorEdits(c context.Context, productID int) ([]model.ModeratorEdit, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var edits []model.ModeratorEdit
+	for _, e := range s.edits {
+		if e.ProductID == productID {
+			edits = append(edits, e)
+		}
+	}
+	return edits, nil
+}
+
+// RejectProduct rejects the product with the given ID for the given reason, recording who did it.
+// The product is kept, so that the submitter can see why it was rejected.
+func (s *MemoryProductsStore) RejectProduct(c context.Context, id int, reason string, by model.Moderator) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, err := s.transition(id, model.StatusRejected)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkClaim(id, by); err != nil {
+		return err
+	}
+
+	now := time.Now().Truncate(time.Second)
+	s.products[i].Status = model.StatusRejected
+	s.products[i].RejectedAt = &now
+	s.products[i].RejectionReason = reason
+	s.releaseClaim(id)
+	s.recordAction(model.NewModerationAction(model.ItemProduct, id, model.ActionReject, by))
+	return nil
+}
+
+// DiscontinueProduct marks the product with the given ID as discontinued, recording who did it.
+func (s *MemoryProductsStore) DiscontinueProduct(c context.Context, id int, by model.Moderator) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, err := s.transition(id, model.StatusDiscontinued)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Truncate(time.Second)
+	s.products[i].Status = model.StatusDiscontinued
+	s.products[i].DiscontinuedAt = &now
+	s.recordAction(model.NewModerationAction(model.ItemProduct, id, model.ActionDiscontinue, by))
+	return nil
+}
+
+// WithdrawProduct marks the product with the given ID as withdrawn by its submitter.
+func (s *MemoryProductsStore) WithdrawProduct(c context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, err := s.transition(id, model.StatusWithdrawn)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Truncate(time.Second)
+	s.products[i].Status = model.StatusWithdrawn
+	s.products[i].WithdrawnAt = &now
+	s.releaseClaim(id)
+	return nil
+}
+
+// transition returns the index of the product with the given ID,
+// or an error if it doesn't exist or its status can't change to the given status. The caller must hold the lock.
+func (s *MemoryProductsStore) transition(id int, to model.ProductStatus) (int, error) {
+	for i := range s.products {
+		if s.products[i].ID != id {
+			continue
+		}
+
+		if !s.products[i].Status.CanBecome(to) {
+			return 0, model.ErrInvalidTransition(s.products[i].Status, to)
+		}
+		return i, nil
+	}
+	return 0, model.ErrProductNotFound
+}
+
+// CountProductsByCategory returns the number of published and pending products in each category.
+// Products with other statuses aren't counted, and categories without any products are omitted.
+func (s *MemoryProductsStore) CountProductsByCategory(c context.Context) (map[string]model.ProductCounts, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := make(map[string]model.ProductCounts)
+	for _, p := range s.products {
+		cnt := counts[p.CategorySlug]
+		switch p.Status {
+		case model.StatusPublished:
+			cnt.Approved++
+		case model.StatusPending:
+			cnt.Pending++
+		default:
+			continue
+		}
+		counts[p.CategorySlug] = cnt
+	}
+	return counts, nil
+}
+
+// AddTranslation adds a product translation to the store.
+// The returned translation will have the "id" and "created_at" fields filled in.
+func (s *MemoryProductsStore) AddTranslation(c context.Context, t model.Translation) (model.Translation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastTranslationID++
+	t.ID = s.lastTranslationID
+	t.Approved = false
+	t.CreatedAt = time.Now().Truncate(time.Second)
+	s.translations = append(s.translations, copyTranslation(t))
+	return t, nil
+}
+
+// GetApprovedTranslations returns the approved translations of the given products into the given locale.
+// Translations are ordered from oldest to newest.
+func (s *MemoryProductsStore) GetApprovedTranslations(c context.Context, productIDs []int, locale string) ([]model.Translation, error) {
+	ids := make(map[int]bool, len(productIDs))
+	for _, id := range productIDs {
+		ids[id] = true
+	}
+
+	return s.findTranslations(func(t model.Translation) bool {
+		return t.Approved && ids[t.ProductID] && t.Locale == locale
+	}), nil
+}
+
+// GetTranslationsRequiringApproval returns all translations that need approval by the mod team.
+func (s *MemoryProductsStore) GetTranslationsRequiringApproval(c context.Context) ([]model.Translation, error) {
+	return s.findTranslations(func(t model.Translation) bool {
+		return !t.Approved
+	}), nil
+}
+
+// GetTranslationByID returns the translation with the given ID, or model.ErrItemNotFound if there's none.
+func (s *MemoryProductsStore) GetTranslationByID(c context.Context, id int) (model.Translation, error) {
+	ts := s.findTranslations(func(t model.Translation) bool {
+		return t.ID == id
+	})
+	if len(ts) == 0 {
+		return model.Translation{}, model.ErrItemNotFound
+	}
+	return ts[0], nil
+}
+
+// findTranslations returns copies of all translations matching the given predicate.
+func (s *MemoryProductsStore) findTranslations(match func(model.Translation) bool) []model.Translation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var translations []model.Translation
+	for _, t := range s.translations {
+		if match(t) {
+			translations = append(translations, copyTranslation(t))
+		}
+	}
+	return translations
+}
+
+// ApproveTranslation approves the translation with the given ID, recording who did it.
+func (s *MemoryProductsStore) ApproveTranslation(c context.Context, id int, by model.Moderator) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.translations {
+		if s.translations[i].ID == id {
+			s.translations[i].Approved = true
+			s.recordAction(model.NewModerationAction(model.ItemTranslation, id, model.ActionApprove, by))
+			return nil
+		}
+	}
+	return model.ErrItemNotFound
+}
+
+// RejectTranslation rejects the translation with the given ID, deleting it, and records who did it.
+func (s *MemoryProductsStore) RejectTranslation(c context.Context, id int, by model.Moderator) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	translations := s.translations[:0]
+	for _, t := range s.translations {
+		if t.ID != id {
+			translations = append(translations, t)
+		}
+	}
+	if len(translations) == len(s.translations) {
+		return model.ErrItemNotFound
+	}
+	s.translations = translations
+	s.recordAction(model.NewModerationAction(model.ItemTranslation, id, model.ActionReject, by))
+	return nil
+}
+
+// RequestProductChanges sends the product with the given ID back to its submitter with the given comments,
+// recording who did it. The product stays out of the moderation queue until it's resubmitted.
+func (s *MemoryProductsStore) RequestProductChanges(c context.Context, id int, comments []model.Comment, by model.Moderator) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, err := s.transition(id, model.StatusChangesRequested)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkClaim(id, by); err != nil {
+		return err
+	}
+
+	now := time.Now().Truncate(time.Second)
+	s.products[i].Status = model.StatusChangesRequested
+	s.products[i].ChangesRequestedAt = &now
+	s.releaseClaim(id)
+	for _, cm := range comments {
+		cm.ProductID = id
+		s.addComment(cm)
+	}
+	s.recordAction(model.NewModerationAction(model.ItemProduct, id, model.ActionRequestChanges, by))
+	return nil
+}
+
+// ResubmitProduct replaces the data of the product with the given ID and returns it to the moderation queue.
+// returns model.ErrNoChangesRequested if no changes were requested to the product.
+func (s *MemoryProductsStore) ResubmitProduct(c context.Context, id int, data map[string]map[string]any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.products {
+		if s.products[i].ID == id && s.products[i].Status.CanBecome(model.StatusPending) {
+			now := time.Now().Truncate(time.Second)
+			s.products[i].Data = copyProduct(model.Product{Data: data}).Data
+			s.products[i].Status = model.StatusPending
+			s.products[i].ResubmittedAt = &now
+			return nil
+		}
+	}
+	return model.ErrNoChangesRequested
+}
+
+// AddProductComment adds a comment to the conversation about a product.
+// The returned comment will have the "id" and "created_at" fields filled in.
+func (s *MemoryProductsStore) AddProductComment(c context.Context, cm model.Comment) (model.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addComment(cm), nil
+}
+
+// addComment adds a comment and returns it with its ID and creation time. The caller must hold the lock.
+func (s *MemoryProductsStore) addComment(cm model.Comment) model.Comment {
+	s.lastCommentID++
+	cm.ID = s.lastCommentID
+	cm.CreatedAt = time.Now().Truncate(time.Second)
+	s.comments = append(s.comments, cm)
+	return cm
+}
+
+// GetProductComments returns the conversation about the product with the given ID, oldest first.
+// Author names aren't filled in, as users are kept in a separate store.
+func (s *MemoryProductsStore) GetProductComments(c context.Context, productID int) ([]model.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var comments []model.Comment
+	for _, cm := range s.comments {
+		if cm.ProductID == productID {
+			comments = append(comments, cm)
+		}
+	}
+	return comments, nil
+}
+
+// GetModerationQueue returns a page of the products waiting for a moderator's decision which match the given query.
+// Moderator emails aren't filled in, as users are kept in a separate store.
+func (s *MemoryProductsStore) GetModerationQueue(c context.Context, q model.QueueQuery) (model.QueuePage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	categories := make(map[string]bool, len(q.CategorySlugs))
+	for _, slug := range q.CategorySlugs {
+		categories[slug] = true
+	}
+
+	var items []model.QueueItem
+	for _, p := range s.products {
+		if p.Status != model.StatusPending {
+			continue
+		}
+
+		if len(categories) > 0 && !categories[p.CategorySlug] {
+			continue
+		}
+
+		item := model.QueueItem{Product: copyProduct(p), SubmissionType: model.SubmissionNew, QueuedAt: p.SubmittedAt}
+		if p.ResubmittedAt != nil {
+			item.SubmissionType = model.SubmissionResubmission
+			item.QueuedAt = *p.ResubmittedAt
+		}
+
+		if q.SubmissionType != "" && item.SubmissionType != q.SubmissionType {
+			continue
+		}
+
+		if claim, ok := s.claims[p.ID]; ok {
+			item.Claim = &claim
+		}
+
+		for _, n := range s.notes {
+			if n.ProductID == p.ID {
+				item.NoteCount++
+			}
+		}
+		items = append(items, item)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if !a.QueuedAt.Equal(b.QueuedAt) {
+			return a.QueuedAt.Before(b.QueuedAt) != q.NewestFirst
+		}
+		return (a.ID < b.ID) != q.NewestFirst
+	})
+
+	page := model.QueuePage{Total: len(items)}
+	if q.Offset < len(items) {
+		items = items[q.Offset:]
+		if len(items) > q.Limit {
+			items = items[:q.Limit]
+		}
+		page.Items = items
+	}
+	return page, nil
+}
+
+// ClaimProduct marks the product with the given ID as being reviewed by the user with the given ID.
+// Claims made before expiredBefore are overridden, other claims by other moderators result in model.ErrProductClaimed.
+func (s *MemoryProductsStore) ClaimProduct(c context.Context, id int, userID int64, expiredBefore time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if claim, ok := s.claims[id]; ok && claim.ModeratorID != userID && !claim.ClaimedAt.Before(expiredBefore) {
+		return model.ErrProductClaimed
+	}
+
+	s.claims[id] = model.Claim{ModeratorID: userID, ClaimedAt: time.Now().Truncate(time.Second)}
+	return nil
+}
+
+// ReleaseProductClaim removes the claim of the user with the given ID on the product with the given ID, if they have one.
+func (s *MemoryProductsStore) ReleaseProductClaim(c context.Context, id int, userID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if claim, ok := s.claims[id]; ok && claim.ModeratorID == userID {
+		s.releaseClaim(id)
+	}
+	return nil
+}
+
+// checkClaim returns model.ErrProductClaimed if another moderator has claimed the product with the given ID,
+// and the claim hasn't expired. The moderation API key isn't bound by claims. The caller must hold the lock.
+func (s *MemoryProductsStore) checkClaim(id int, by model.Moderator) error {
+	if by.UserID == 0 {
+		return nil
+	}
+
+	if claim, ok := s.claims[id]; ok && claim.ModeratorID != by.UserID && !claim.Expired() {
+		return model.ErrProductClaimed
+	}
+	return nil
+}
+
+// releaseClaim removes any claim on the product with the given ID. The caller must hold the lock.
+func (s *MemoryProductsStore) releaseClaim(id int) {
+	delete(s.claims, id)
+}
+
+// AddModeratorNote adds an internal note about a product.
+// The returned note will have the "id" and "created_at" fields filled in.
+func (s *MemoryProductsStore) AddModeratorNote(c context.Context, n model.ModeratorNote) (model.ModeratorNote, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastNoteID++
+	n.ID = s.lastNoteID
+	n.CreatedAt = time.Now().Truncate(time.Second)
+	s.notes = append(s.notes, n)
+	return n, nil
+}
+
+// GetModeratorNotes returns the internal notes about the product with the given ID, oldest first.
+// Author emails aren't filled in, as users are kept in a separate store.
+func (s *MemoryProductsStore) GetModeratorNotes(c context.Context, productID int) ([]model.ModeratorNote, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var notes []model.ModeratorNote
+	for _, n := range s.notes {
+		if n.ProductID == productID {
+			notes = append(notes, n)
+		}
+	}
+	return notes, nil
+}
+
+// recordAction adds a moderation action to the log. The caller must hold the lock.
+func (s *MemoryProductsStore) recordAction(a model.ModerationAction) {
+	s.lastActionID++
+	a.ID = s.lastActionID
+	a.CreatedAt = time.Now().Truncate(time.Second)
+	s.actions = append(s.actions, a)
+}
+
+// ListModerationActions returns the most recent moderation actions, newest first.
+// Moderator email addresses aren't filled in, as users are kept in a separate store.
+func (s *MemoryProductsStore) ListModerationActions(c context.Context, limit int) ([]model.ModerationAction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var actions []model.ModerationAction
+	for i := len(s.actions) - 1; i >= 0 && len(actions) < limit; i-- {
+		actions = append(actions, s.actions[i])
+	}
+	return actions, nil
+}
+
+// copyProduct returns a copy of p which doesn't share its data maps,
+// as callers are free to modify the products they get, e.g. when translating them.
+func copyProduct(p model.Product) model.Product {
+	data := make(map[string]map[string]any, len(p.Data))
+	for fset, fields := range p.Data {
+		data[fset] = make(map[string]any, len(fields))
+		for name, value := range fields {
+			data[fset][name] = value
+		}
+	}
+	p.Data = data
+	return p
+}
+
+// copyTranslation returns a copy of t which doesn't share its data maps.
+func copyTranslation(t model.Translation) model.Translation {
+	data := make(map[string]map[string]string, len(t.Data))
+	for fset, fields := range t.Data {
+		data[fset] = make(map[string]string, len(fields))
+		for name, text := range fields {
+			data[fset][name] = text
+		}
+	}
+	t.Data = data
+	return t
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryRateLimitStore is a RateLimitStore that keeps counts in memory, which only works with a single instance of the server.
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	windows map[string]rateLimitWindow // keyed by rate limit key.
+}
+
+type rateLimitWindow struct {
+	hits     int
+	resetsAt time.Time
+}
+
+// NewMemoryRateLimitStore returns an empty MemoryRateLimitStore.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{windows: make(map[string]rateLimitWindow)}
+}
+
+// Hit counts a request under the given key, starting a new window which ends at resetsAt if the current one is over.
+func (s *MemoryRateLimitStore) Hit(c context.Context, key string, now, resetsAt time.Time) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.windows[key]
+	if !ok || !w.resetsAt.After(now) {
+		w = rateLimitWindow{resetsAt: resetsAt}
+	}
+	w.hits++
+	s.windows[key] = w
+	return w.hits, w.resetsAt, nil
+}
+
+// DeleteExpiredRateLimits deletes the counts of the windows which ended before the given time.
+func (s *MemoryRateLimitStore) DeleteExpiredRateLimits(c context.Context, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, w := range s.windows {
+		if !w.resetsAt.After(now) {
+			delete(s.windows, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mikolysz/enably/model"
+)
+
+// MemoryTokenStore is a TokenStore that keeps tokens in memory, for local development.
+type MemoryTokenStore struct {
+	mu sync.Mutex
+
+	// both keyed by token contents.
+	loginTokens map[string]model.LoginToken
+	sessions    map[string]model.Session
+
+	lastLoginTokenID int64
+	lastSessionID    int64
+}
+
+// NewMemoryTokenStore returns an empty MemoryTokenStore.
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{
+		loginTokens: make(map[string]model.LoginToken),
+		sessions:    make(map[string]model.Session),
+	}
+}
+
+// AddLoginToken adds a login token to the store.
+// The returned token will have the "id" and "created_at" fields filled in.
+func (s *MemoryTokenStore) AddLoginToken(c context.Context, t model.LoginToken) (model.LoginToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastLoginTokenID++
+	t.ID = s.lastLoginTokenID
+	t.CreatedAt = time.Now().Truncate(time.Second)
+	s.loginTokens[t.Token] = t
+	return t, nil
+}
+
+// TakeLoginToken removes the login token with the given contents from the store and returns it.
+// returns model.ErrExpiredLoginToken if the token does not exist.
+func (s *MemoryTokenStore) TakeLoginToken(c context.Context, token string) (model.LoginToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.loginTokens[token]
+	if !ok {
+		return model.LoginToken{}, model.ErrExpiredLoginToken
+	}
+	delete(s.loginTokens, token)
+	return t, nil
+}
+
+// AddSession adds a session to the store.
+// The returned session will have the "id" and "created_at" fields filled in.
+func (s *MemoryTokenStore) AddSession(c context.Context, session model.Session) (model.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastSessionID++
+	session.ID = s.lastSessionID
+	session.CreatedAt = time.Now().Truncate(time.Second)
+	s.sessions[session.Token] = session
+	return session, nil
+}
+
+// GetSessionByToken returns the session with the given token.
+// returns model.ErrInvalidToken if the session does not exist.
+func (s *MemoryTokenStore) GetSessionByToken(c context.Context, token string) (model.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[token]
+	if !ok {
+		return model.Session{}, model.ErrInvalidToken
+	}
+	return session, nil
+}
+
+// ExtendSession records that the session with the given ID was used, and pushes back its expiry.
+func (s *MemoryTokenStore) ExtendSession(c context.Context, id int64, lastUsedAt, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for token, session := range s.sessions {
+		if session.ID == id {
+			session.LastUsedAt = lastUsedAt
+			session.ExpiresAt = expiresAt
+			s.sessions[token] = session
+		}
+	}
+	return nil
+}
+
+// ListSessions returns the sessions of the given user which are neither expired nor revoked, most recently used first.
+func (s *MemoryTokenStore) ListSessions(c context.Context, email string, now time.Time) ([]model.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sessions []model.Session
+	for _, session := range s.sessions {
+		if session.Email == email && !session.ExpiresAt.Before(now) && session.RevokedAt == nil {
+			sessions = append(sessions, session)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].LastUsedAt.Equal(sessions[j].LastUsedAt) {
+			return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+		}
+		return sessions[i].ID > sessions[j].ID
+	})
+	return sessions, nil
+}
+
+// RevokeSession ends the session with the given ID, as long as it belongs to the given user.
+// returns model.ErrSessionNotFound if there's no such session, or it has already been revoked.
+func (s *MemoryTokenStore) RevokeSession(c context.Context, id int64, email string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for token, session := range s.sessions {
+		if session.ID == id && session.Email == email && session.RevokedAt == nil {
+			session.RevokedAt = &now
+			s.sessions[token] = session
+			return nil
+		}
+	}
+	return model.ErrSessionNotFound
+}
+
+// RevokeOtherSessions ends all sessions of the given user, except the one with the given ID.
+func (s *MemoryTokenStore) RevokeOtherSessions(c context.Context, email string, exceptID int64, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for token, session := range s.sessions {
+		if session.Email == email && session.ID != exceptID && session.RevokedAt == nil {
+			session.RevokedAt = &now
+			s.sessions[token] = session
+		}
+	}
+	return nil
+}
+
+// DeleteExpiredTokens deletes all login tokens and sessions which expired before the given time, and all revoked sessions.
+func (s *MemoryTokenStore) DeleteExpiredTokens(c context.Context, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for token, t := range s.loginTokens {
+		if t.ExpiresAt.Before(now) {
+			delete(s.loginTokens, token)
+		}
+	}
+
+	for token, session := range s.sessions {
+		if session.ExpiresAt.Before(now) || session.RevokedAt != nil {
+			delete(s.sessions, token)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mikolysz/enably/model"
+)
+
+// MemoryUsersStore is a UsersStore that keeps user accounts in memory, for local development.
+type MemoryUsersStore struct {
+	mu     sync.Mutex
+	users  map[string]model.User // keyed by email address.
+	lastID int64
+}
+
+// NewMemoryUsersStore returns an empty MemoryUsersStore.
+func NewMemoryUsersStore() *MemoryUsersStore {
+	return &MemoryUsersStore{users: make(map[string]model.User)}
+}
+
+// GetOrCreateUser returns the user with the given email address, creating one if there's none.
+func (s *MemoryUsersStore) GetOrCreateUser(c context.Context, email string) (model.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u, ok := s.users[email]; ok {
+		return u, nil
+	}
+
+	s.lastID++
+	u := model.User{
+		ID:               s.lastID,
+		Email:            email,
+		Role:             model.RoleContributor,
+		ModerationEmails: true,
+		CreatedAt:        time.Now().Truncate(time.Second),
+	}
+	s.users[email] = u
+	return u, nil
+}
+
+// GetUserByEmail returns the user with the given email address.
+// returns model.ErrUserNotFound if there's no such user.
+func (s *MemoryUsersStore) GetUserByEmail(c context.Context, email string) (model.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[email]
+	if !ok {
+		return model.User{}, model.ErrUserNotFound
+	}
+	return u, nil
+}
+
+// GetUserByID returns the user with the given ID.
+// returns model.ErrUserNotFound if there's no such user.
+func (s *MemoryUsersStore) GetUserByID(c context.Context, id int64) (model.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return model.User{}, model.ErrUserNotFound
+}
+
+// UpdateUser saves the profile of the given user.
+func (s *MemoryUsersStore) UpdateUser(c context.Context, u model.User) (model.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.users[u.Email]
+	if !ok {
+		return model.User{}, model.ErrUserNotFound
+	}
+
+	// Like the database stores, only the profile is updated, the role is changed with SetUserRole.
+	existing.DisplayName = u.DisplayName
+	existing.EmailConsent = u.EmailConsent
+	existing.ModerationEmails = u.ModerationEmails
+	existing.OnboardedAt = u.OnboardedAt
+	s.users[u.Email] = existing
+	return existing, nil
+}
+
+// SetUserRole changes the role of the user with the given email address.
+// returns model.ErrUserNotFound if there's no such user.
+func (s *MemoryUsersStore) SetUserRole(c context.Context, email string, role model.Role) (model.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[email]
+	if !ok {
+		return model.User{}, model.ErrUserNotFound
+	}
+	u.Role = role
+	s.users[email] = u
+	return u, nil
+}
@@ -1,8 +1,11 @@
 package store
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 
 	"github.com/pelletier/go-toml/v2"
@@ -15,6 +18,16 @@ type TOMLMetadataStore struct {
 	categories         map[string]*model.Category
 	topLevelCategories []*model.SubcategoryInfo
 	fieldsets          map[string]*model.Fieldset
+
+	// categoryOrder determines the order of categories in all lists, see orderKey.
+	categoryOrder map[string]orderKey
+
+	// allCategories and allFieldsets contain all categories and fieldsets in order.
+	allCategories []*model.Category
+	allFieldsets  []*model.Fieldset
+
+	// version is derived from the contents of the schema file.
+	version string
 }
 
 type schema struct {
@@ -37,6 +50,7 @@ type category struct {
 	ShortDescription string   `toml:"short_description"`
 	Parent           string   //empty if this is a top-level category
 	FieldsetSlugs    []string `toml:"fieldsets"`
+	Order            int      // position among its siblings, see orderKey.
 
 	// field names of the form fieldset.field_name.
 	// If empty, take from parent.
@@ -55,10 +69,14 @@ func NewTOMLMetadataStore(schemaData []byte) (*TOMLMetadataStore, error) {
 	}
 
 	st := &TOMLMetadataStore{
-		categories: make(map[string]*model.Category),
-		fieldsets:  make(map[string]*model.Fieldset),
+		categories:    make(map[string]*model.Category),
+		fieldsets:     make(map[string]*model.Fieldset),
+		categoryOrder: make(map[string]orderKey),
 	}
 
+	hash := sha256.Sum256(schemaData)
+	st.version = hex.EncodeToString(hash[:6])
+
 	fieldsets, err := st.populateFieldsets(s)
 	if err != nil {
 		return nil, fmt.Errorf("failed to populate fieldsets: %w", err)
@@ -70,9 +88,92 @@ func NewTOMLMetadataStore(schemaData []byte) (*TOMLMetadataStore, error) {
 		return nil, fmt.Errorf("failed to populate categories: %w", err)
 	}
 
+	st.sortFieldsets()
+	st.sortCategories()
 	return st, nil
 }
 
+// orderKey determines the position of a category or fieldset in a list.
+//
+// Items with an explicit, positive order key come first, sorted by that key.
+// The rest follow in alphabetical order of their names.
+// Categories called "other", or with a slug starting with "other_", always go last,
+// as that's where people look for things that don't fit anywhere else.
+type orderKey struct {
+	order int
+	name  string
+	other bool
+}
+
+func newOrderKey(slug, name string, order int) orderKey {
+	return orderKey{
+		order: order,
+		name:  strings.ToLower(name),
+		other: slug == "other" || strings.HasPrefix(slug, "other_"),
+	}
+}
+
+func (a orderKey) less(b orderKey) bool {
+	if a.other != b.other {
+		return b.other
+	}
+
+	aOrdered, bOrdered := a.order > 0, b.order > 0
+	if aOrdered != bOrdered {
+		return aOrdered
+	}
+
+	if a.order != b.order {
+		return a.order < b.order
+	}
+	return a.name < b.name
+}
+
+func (st *TOMLMetadataStore) sortFieldsets() {
+	st.allFieldsets = make([]*model.Fieldset, 0, len(st.fieldsets))
+	for _, fset := range st.fieldsets {
+		st.allFieldsets = append(st.allFieldsets, fset)
+	}
+
+	sort.Slice(st.allFieldsets, func(i, j int) bool {
+		a, b := st.allFieldsets[i], st.allFieldsets[j]
+		aKey, bKey := newOrderKey(a.Slug, a.Name, a.Order), newOrderKey(b.Slug, b.Name, b.Order)
+		if aKey == bKey {
+			return a.Slug < b.Slug
+		}
+		return aKey.less(bKey)
+	})
+}
+
+// sortCategories sorts all lists of categories, and then lists all categories depth-first in that order.
+func (st *TOMLMetadataStore) sortCategories() {
+	st.sortSubcategoryInfos(st.topLevelCategories)
+	for _, cat := range st.categories {
+		st.sortSubcategoryInfos(cat.Subcategories)
+	}
+
+	st.allCategories = make([]*model.Category, 0, len(st.categories))
+	var visit func(infos []*model.SubcategoryInfo)
+	visit = func(infos []*model.SubcategoryInfo) {
+		for _, info := range infos {
+			cat := st.categories[info.Slug]
+			st.allCategories = append(st.allCategories, cat)
+			visit(cat.Subcategories)
+		}
+	}
+	visit(st.topLevelCategories)
+}
+
+func (st *TOMLMetadataStore) sortSubcategoryInfos(infos []*model.SubcategoryInfo) {
+	sort.Slice(infos, func(i, j int) bool {
+		a, b := st.categoryOrder[infos[i].Slug], st.categoryOrder[infos[j].Slug]
+		if a == b {
+			return infos[i].Slug < infos[j].Slug
+		}
+		return a.less(b)
+	})
+}
+
 func (st *TOMLMetadataStore) populateFieldsets(s schema) (map[string]*model.Fieldset, error) {
 	fsets := make(map[string]*model.Fieldset)
 	for slug, fs := range s.Fieldsets {
@@ -90,9 +191,55 @@ func (st *TOMLMetadataStore) populateFieldsets(s schema) (map[string]*model.Fiel
 
 		fs.Fields = fields
 	}
+
+	for _, fs := range fsets {
+		for _, field := range fs.Fields {
+			if err := verifyCondition(fs, field); err != nil {
+				return nil, err
+			}
+		}
+	}
 	return fsets, nil
 }
 
+// verifyCondition checks that the condition of the given field, if any, refers to an existing field and valid values.
+func verifyCondition(fs *model.Fieldset, field *model.Field) error {
+	cond := field.Condition
+	if cond == nil {
+		return nil
+	}
+
+	dep := fs.FieldByName(cond.Field)
+	if dep == nil || dep == field {
+		return fmt.Errorf("field %s.%s depends on nonexistent field %q", fs.Slug, field.Name, cond.Field)
+	}
+
+	if len(cond.OneOf) == 0 {
+		return fmt.Errorf("the condition of field %s.%s doesn't list any values", fs.Slug, field.Name)
+	}
+
+	for _, v := range cond.OneOf {
+		valid := false
+		switch dep.Type {
+		case "checkbox":
+			_, valid = v.(bool)
+		case "radio-buttons", "dropdown":
+			for _, opt := range dep.Options {
+				if v == opt {
+					valid = true
+				}
+			}
+		default:
+			_, valid = v.(string)
+		}
+
+		if !valid {
+			return fmt.Errorf("field %s.%s depends on invalid value %v of field %s", fs.Slug, field.Name, v, dep.Name)
+		}
+	}
+	return nil
+}
+
 func (st *TOMLMetadataStore) populateCategories(s schema) (map[string]*model.Category, error) {
 	cats := make(map[string]*model.Category)
 	for slug, cat := range s.Categories {
@@ -109,6 +256,8 @@ func (st *TOMLMetadataStore) populateCategories(s schema) (map[string]*model.Cat
 		if cat.ShortDescription == "" {
 			cats[slug].ShortDescription = cat.Name
 		}
+
+		st.categoryOrder[slug] = newOrderKey(slug, cat.Name, cat.Order)
 	}
 
 	// Set up parent-child relationships.
@@ -131,6 +280,15 @@ func (st *TOMLMetadataStore) populateCategories(s schema) (map[string]*model.Cat
 		}
 	}
 
+	// Compute the breadcrumb paths.
+	for _, cat := range cats {
+		path, err := categoryPath(cat, cats)
+		if err != nil {
+			return nil, err
+		}
+		cat.Path = path
+	}
+
 	// Determine which categories are leaf categories.
 
 	for _, cat := range cats {
@@ -154,6 +312,22 @@ func (st *TOMLMetadataStore) populateCategories(s schema) (map[string]*model.Cat
 	return cats, nil
 }
 
+// categoryPath returns the path from the top-level ancestor of cat down to cat itself.
+func categoryPath(cat *model.Category, cats map[string]*model.Category) ([]*model.Breadcrumb, error) {
+	var path []*model.Breadcrumb
+	seen := make(map[string]bool)
+
+	for c := cat; c != nil; c = cats[c.Parent] {
+		if seen[c.Slug] {
+			return nil, fmt.Errorf("category %q is its own ancestor", cat.Slug)
+		}
+		seen[c.Slug] = true
+
+		path = append([]*model.Breadcrumb{{Slug: c.Slug, Name: c.Name}}, path...)
+	}
+	return path, nil
+}
+
 func (st *TOMLMetadataStore) inheritFields(slug string, cats map[string]*model.Category, schemaCats map[string]category) error {
 	cat := cats[slug]
 
@@ -247,6 +421,11 @@ func (st *TOMLMetadataStore) verifyFieldsetFieldExists(field string) bool {
 	return false
 }
 
+// SchemaVersion returns a short hash of the schema file, which changes whenever the schema does.
+func (s *TOMLMetadataStore) SchemaVersion() string {
+	return s.version
+}
+
 // TopLevelCategories returns all categories that have no parent.
 func (s *TOMLMetadataStore) TopLevelCategories() []*model.SubcategoryInfo {
 	return s.topLevelCategories
@@ -256,7 +435,7 @@ func (s *TOMLMetadataStore) TopLevelCategories() []*model.SubcategoryInfo {
 func (s *TOMLMetadataStore) CategoryBySlug(slug string) (*model.Category, error) {
 	cat, ok := s.categories[slug]
 	if !ok {
-		return nil, &model.UserFacingError{
+		return nil, model.UserFacingError{
 			HTTPStatusCode:    http.StatusNotFound,
 			UserFacingMessage: fmt.Sprintf("no such category: %q", slug),
 		}
@@ -266,19 +445,16 @@ func (s *TOMLMetadataStore) CategoryBySlug(slug string) (*model.Category, error)
 }
 
 // AllCategories returns all defined categories.
+// Every category is immediately followed by its subcategories, in the order they're listed in.
 func (s *TOMLMetadataStore) AllCategories() ([]*model.Category, error) {
-	cats := make([]*model.Category, 0, len(s.categories))
-	for _, cat := range s.categories {
-		cats = append(cats, cat)
-	}
-	return cats, nil
+	return s.allCategories, nil
 }
 
 // FieldsetBySlug returns the fieldset with the given slug.
 func (s *TOMLMetadataStore) FieldsetBySlug(slug string) (*model.Fieldset, error) {
 	fs, ok := s.fieldsets[slug]
 	if !ok {
-		return nil, &model.UserFacingError{
+		return nil, model.UserFacingError{
 			HTTPStatusCode:    http.StatusNotFound,
 			UserFacingMessage: fmt.Sprintf("no such fieldset: %q", slug),
 		}
@@ -287,12 +463,7 @@ func (s *TOMLMetadataStore) FieldsetBySlug(slug string) (*model.Fieldset, error)
 	return fs, nil
 }
 
-// AllFieldsets returns all defined fieldsets.
+// AllFieldsets returns all defined fieldsets, in order.
 func (s *TOMLMetadataStore) AllFieldsets() ([]*model.Fieldset, error) {
-	fsets := make([]*model.Fieldset, 0, len(s.fieldsets))
-	for _, fset := range s.fieldsets {
-		fsets = append(fsets, fset)
-	}
-
-	return fsets, nil
+	return s.allFieldsets, nil
 }
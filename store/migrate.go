@@ -0,0 +1,310 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // registers the "pgx" driver.
+)
+
+// migrationFileRegexp matches the names of migration files, e.g. "20230102185230_create_products_table.up.sql".
+// This is the naming scheme used by golang-migrate.
+var migrationFileRegexp = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// migrationsLockID is the key of the Postgres advisory lock taken while migrating,
+// so that several instances starting at once don't apply the same migrations twice.
+const migrationsLockID = 7365127849
+
+// Migration is a single change to the database schema.
+type Migration struct {
+	Version int64
+	Name    string
+
+	up, down string
+}
+
+// MigrationStatus tells whether a migration has been applied.
+type MigrationStatus struct {
+	Migration
+	Applied bool
+}
+
+// migrationDialect contains the parts of migrating which differ between databases.
+type migrationDialect struct {
+	createTable string
+	lock        string // may be empty if the database doesn't need a lock.
+	unlock      string
+}
+
+var postgresMigrationDialect = migrationDialect{
+	createTable: "CREATE TABLE IF NOT EXISTS schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)",
+	lock:        fmt.Sprintf("SELECT pg_advisory_lock(%d)", migrationsLockID),
+	unlock:      fmt.Sprintf("SELECT pg_advisory_unlock(%d)", migrationsLockID),
+}
+
+// SQLite only allows one writer at a time anyway, so no lock is needed.
+var sqliteMigrationDialect = migrationDialect{
+	createTable: "CREATE TABLE IF NOT EXISTS schema_migrations (version uint64, dirty bool); CREATE UNIQUE INDEX IF NOT EXISTS version_unique ON schema_migrations (version);",
+}
+
+// Migrator applies and reverts migrations.
+// It keeps track of the current version in the schema_migrations table, in the same way as golang-migrate,
+// so databases migrated with either tool can be used with the other.
+type Migrator struct {
+	db         *sql.DB
+	dialect    migrationDialect
+	migrations []Migration // ordered by version.
+}
+
+// OpenPostgresDB opens a database/sql connection to a Postgres database.
+// It's only used for migrations, the stores use pgx directly.
+func OpenPostgresDB(connString string) (*sql.DB, error) {
+	db, err := sql.Open("pgx", connString)
+	if err != nil {
+		return nil, fmt.Errorf("error when opening Postgres database: %s", err)
+	}
+	return db, nil
+}
+
+// NewPostgresMigrator returns a Migrator applying the migrations in the given directory to a Postgres database.
+func NewPostgresMigrator(db *sql.DB, files fs.FS) (*Migrator, error) {
+	return newMigrator(db, postgresMigrationDialect, files)
+}
+
+// NewSQLiteMigrator returns a Migrator applying the migrations in the given directory to an SQLite database.
+func NewSQLiteMigrator(db *sql.DB, files fs.FS) (*Migrator, error) {
+	return newMigrator(db, sqliteMigrationDialect, files)
+}
+
+func newMigrator(db *sql.DB, dialect migrationDialect, files fs.FS) (*Migrator, error) {
+	migrations, err := readMigrations(files)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+// readMigrations reads all migrations in the root of the given directory, ordered by version.
+func readMigrations(files fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, fmt.Errorf("error when listing migrations: %s", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, e := range entries {
+		matches := migrationFileRegexp.FindStringSubmatch(e.Name())
+		if e.IsDir() || matches == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %s", e.Name(), err)
+		}
+
+		contents, err := fs.ReadFile(files, e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("error when reading migration %s: %s", e.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = m
+		}
+
+		if m.Name != matches[2] {
+			return nil, fmt.Errorf("migrations %s and %s have the same version", m.Name, matches[2])
+		}
+
+		if matches[3] == "up" {
+			m.up = string(contents)
+		} else {
+			m.down = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up migration", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Status returns all known migrations, and whether each of them has been applied.
+func (m *Migrator) Status(c context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.withConn(c, false, func(conn *sql.Conn) error {
+		current, err := m.currentIndex(c, conn)
+		if err != nil {
+			return err
+		}
+
+		for i, migration := range m.migrations {
+			statuses = append(statuses, MigrationStatus{Migration: migration, Applied: i <= current})
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// Pending returns the migrations which haven't been applied yet.
+func (m *Migrator) Pending(c context.Context) ([]Migration, error) {
+	var pending []Migration
+	err := m.withConn(c, false, func(conn *sql.Conn) error {
+		current, err := m.currentIndex(c, conn)
+		if err != nil {
+			return err
+		}
+
+		pending = m.migrations[current+1:]
+		return nil
+	})
+	return pending, err
+}
+
+// Up applies all pending migrations, and returns the applied ones.
+func (m *Migrator) Up(c context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.withConn(c, true, func(conn *sql.Conn) error {
+		current, err := m.currentIndex(c, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := current + 1; i < len(m.migrations); i++ {
+			migration := m.migrations[i]
+			if err := m.apply(c, conn, migration.up, migration.Version); err != nil {
+				return fmt.Errorf("error when applying migration %d_%s: %s", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the given number of most recently applied migrations, and returns the reverted ones.
+func (m *Migrator) Down(c context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.withConn(c, true, func(conn *sql.Conn) error {
+		current, err := m.currentIndex(c, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := current; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if migration.down == "" {
+				return fmt.Errorf("migration %d_%s can't be reverted", migration.Version, migration.Name)
+			}
+
+			// A version of 0 means that no migrations have been applied.
+			var previous int64
+			if i > 0 {
+				previous = m.migrations[i-1].Version
+			}
+
+			if err := m.apply(c, conn, migration.down, previous); err != nil {
+				return fmt.Errorf("error when reverting migration %d_%s: %s", migration.Version, migration.Name, err)
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// withConn calls fn with a single connection, on which the schema_migrations table is guaranteed to exist.
+// If lock is true, the migrations lock is held while fn runs.
+func (m *Migrator) withConn(c context.Context, lock bool, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(c)
+	if err != nil {
+		return fmt.Errorf("error when connecting to database: %s", err)
+	}
+	defer conn.Close()
+
+	if lock && m.dialect.lock != "" {
+		if _, err := conn.ExecContext(c, m.dialect.lock); err != nil {
+			return fmt.Errorf("error when acquiring migrations lock: %s", err)
+		}
+		defer conn.ExecContext(context.Background(), m.dialect.unlock)
+	}
+
+	if _, err := conn.ExecContext(c, m.dialect.createTable); err != nil {
+		return fmt.Errorf("error when creating schema_migrations table: %s", err)
+	}
+
+	return fn(conn)
+}
+
+// currentIndex returns the index of the last applied migration, or -1 if none have been applied.
+func (m *Migrator) currentIndex(c context.Context, conn *sql.Conn) (int, error) {
+	var (
+		version int64
+		dirty   bool
+	)
+	err := conn.QueryRowContext(c, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return -1, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error when retrieving schema version: %s", err)
+	}
+
+	if dirty {
+		return 0, fmt.Errorf("migration %d failed halfway through, the database needs to be fixed manually", version)
+	}
+
+	for i, migration := range m.migrations {
+		if migration.Version == version {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("the database is at version %d, which is unknown to this version of Enably", version)
+}
+
+// apply runs the given migration SQL and records the new version in a single transaction.
+// A version of 0 means that no migrations are applied.
+func (m *Migrator) apply(c context.Context, conn *sql.Conn, query string, newVersion int64) error {
+	tx, err := conn.BeginTx(c, nil)
+	if err != nil {
+		return fmt.Errorf("error when starting transaction: %s", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(c, query); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(c, "DELETE FROM schema_migrations"); err != nil {
+		return fmt.Errorf("error when updating schema version: %s", err)
+	}
+
+	if newVersion != 0 {
+		// The version is an integer, so it's safe to include it in the query directly,
+		// which saves us from dealing with placeholders differing between databases.
+		insert := fmt.Sprintf("INSERT INTO schema_migrations(version, dirty) VALUES(%d, false)", newVersion)
+		if _, err := tx.ExecContext(c, insert); err != nil {
+			return fmt.Errorf("error when updating schema version: %s", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error when committing migration: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mikolysz/enably/model"
+)
+
+func TestMigrations(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s testStores) {
+		if s.migrator == nil {
+			t.Skip("the store doesn't use migrations")
+		}
+		c := context.Background()
+		m := s.migrator
+
+		// The stores come migrated, so there must be nothing left to apply.
+		assertPendingMigrations(t, m, 0)
+		if applied, err := m.Up(c); err != nil || len(applied) != 0 {
+			t.Fatalf("Up applied %d migrations to a migrated database, with error %v", len(applied), err)
+		}
+
+		reverted, err := m.Down(c, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(reverted) != 1 || reverted[0].Version != m.migrations[len(m.migrations)-1].Version {
+			t.Errorf("Down(1) reverted %+v, want only the latest migration", reverted)
+		}
+		assertPendingMigrations(t, m, 1)
+
+		// Reverting every migration checks that all the down migrations work, and that the up ones can be applied again afterwards.
+		if _, err := m.Down(c, len(m.migrations)); err != nil {
+			t.Fatal(err)
+		}
+		assertPendingMigrations(t, m, len(m.migrations))
+
+		applied, err := m.Up(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(applied) != len(m.migrations) {
+			t.Errorf("Up applied %d migrations to an empty database, want %d", len(applied), len(m.migrations))
+		}
+		assertPendingMigrations(t, m, 0)
+
+		// The schema must be usable after going down and up again.
+		addTestProduct(t, s, "screen_readers", nil)
+	})
+}
+
+func assertPendingMigrations(t *testing.T, m *Migrator, want int) {
+	t.Helper()
+	pending, err := m.Pending(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != want {
+		t.Errorf("%d migrations are pending, want %d", len(pending), want)
+	}
+}
+
+func TestRemoveHiddenPricesMigration(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s testStores) {
+		if s.migrator == nil {
+			t.Skip("the store doesn't use migrations")
+		}
+		c := context.Background()
+		m := s.migrator
+
+		// Go back to just before the migration, when products could have prices whatever free_or_paid said.
+		var steps int
+		for _, migration := range m.migrations {
+			if migration.Version >= 20261020010000 {
+				steps++
+			}
+		}
+		if _, err := m.Down(c, steps); err != nil {
+			t.Fatal(err)
+		}
+
+		var ids []int
+		for _, software := range []map[string]any{
+			{"name": "NVDA", "free_or_paid": "Free", "price": "$0"},
+			{"name": "JAWS", "free_or_paid": "Paid", "price": "$95"},
+			{"name": "Narrator", "price": "Included in Windows"},
+		} {
+			p, err := s.products.AddProduct(c, model.Product{CategorySlug: "screen_readers", Data: map[string]map[string]any{"software": software}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, p.ID)
+		}
+		tr, err := s.products.AddTranslation(c, model.Translation{
+			ProductID: ids[0],
+			Locale:    "pl",
+			Data:      map[string]map[string]string{"software": {"name": "NVDA", "price": "0 zł"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := m.Up(c); err != nil {
+			t.Fatal(err)
+		}
+
+		for i, wantPrice := range []any{nil, "$95", nil} {
+			p, err := s.products.GetProductByID(c, ids[i])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if price := p.Data["software"]["price"]; price != wantPrice {
+				t.Errorf("%s has price %v after the migration, want %v", p.Data["software"]["name"], price, wantPrice)
+			}
+		}
+
+		got, err := s.products.GetTranslationByID(c, tr.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if price, ok := got.Data["software"]["price"]; ok || got.Data["software"]["name"] != "NVDA" {
+			t.Errorf("the translation has price %q after the migration, want it removed and the name kept", price)
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/mikolysz/enably/model"
+)
+
+func TestModerationActions(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s testStores) {
+		c := context.Background()
+		moderator := addTestUser(t, s, "moderator@example.com")
+
+		p := addTestProduct(t, s, "screen_readers", nil)
+		tr, err := s.products.AddTranslation(c, model.Translation{
+			ProductID: p.ID,
+			Locale:    "pl",
+			Data:      map[string]map[string]string{"software": {"description": "Czytnik ekranu"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.products.ApproveProduct(c, p.ID, model.Moderator{UserID: moderator.ID}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.products.RejectTranslation(c, tr.ID, model.Moderator{}); err != nil {
+			t.Fatal(err)
+		}
+
+		actions, err := s.products.ListModerationActions(c, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(actions) != 2 {
+			t.Fatalf("ListModerationActions returned %d actions, want 2", len(actions))
+		}
+
+		// The most recent actions are listed first, and ones taken with the moderation API key have no moderator.
+		rejected, approved := actions[0], actions[1]
+		if rejected.ItemType != model.ItemTranslation || rejected.ItemID != tr.ID || rejected.Action != model.ActionReject || rejected.ModeratorID != nil {
+			t.Errorf("the latest moderation action is %+v, want the translation rejected with the API key", rejected)
+		}
+		if approved.ItemType != model.ItemProduct || approved.ItemID != p.ID || approved.Action != model.ActionApprove ||
+			approved.ModeratorID == nil || *approved.ModeratorID != moderator.ID {
+			t.Errorf("the first moderation action is %+v, want the product approved by user %d", approved, moderator.ID)
+		}
+
+		if actions, err := s.products.ListModerationActions(c, 1); err != nil || len(actions) != 1 {
+			t.Errorf("ListModerationActions(1) returned %d actions and error %v, want one action", len(actions), err)
+		}
+	})
+}
+
+func TestModeratorEdits(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s testStores) {
+		c := context.Background()
+		moderator := addTestUser(t, s, "moderator@example.com")
+		p := addTestProduct(t, s, "screen_readers", nil)
+
+		data := testProductData("NVDA")
+		data["software"]["platform"] = "Windows"
+		edit := model.ModeratorEdit{
+			Original: p.Data,
+			Changes:  map[string]map[string]any{"software": {"platform": "Windows"}},
+		}
+		if err := s.products.EditAndApproveProduct(c, p.ID, data, edit, model.Moderator{UserID: moderator.ID}); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := s.products.GetProductByID(c, p.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != model.StatusPublished || !reflect.DeepEqual(got.Data, data) {
+			t.Errorf("GetProductByID returned %+v after EditAndApproveProduct, want the edited data published", got)
+		}
+
+		edits, err := s.products.GetModeratorEdits(c, p.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(edits) != 1 {
+			t.Fatalf("GetModeratorEdits returned %d edits, want 1", len(edits))
+		}
+		if e := edits[0]; e.ProductID != p.ID || e.ModeratorID == nil || *e.ModeratorID != moderator.ID ||
+			!reflect.DeepEqual(e.Original, edit.Original) || !reflect.DeepEqual(e.Changes, edit.Changes) {
+			t.Errorf("GetModeratorEdits returned %+v, want %+v by user %d", e, edit, moderator.ID)
+		}
+
+		actions, err := s.products.ListModerationActions(c, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(actions) != 1 || actions[0].ItemID != p.ID || actions[0].Action != model.ActionApprove {
+			t.Errorf("ListModerationActions returned %+v, want the approval", actions)
+		}
+
+		if err := s.products.EditAndApproveProduct(c, 1000, data, edit, model.Moderator{}); !errors.Is(err, model.ErrProductNotFound) {
+			t.Errorf("editing a missing product returned %v, want %v", err, model.ErrProductNotFound)
+		}
+	})
+}
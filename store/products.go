@@ -2,10 +2,13 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/mikolysz/enably/model"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -19,58 +22,118 @@ func NewPostgresProductsStore(pool *pgxpool.Pool) *PostgresProductsStore {
 	return &PostgresProductsStore{pool}
 }
 
+// productColumns are the columns scanned by scanProduct and sqliteScanProduct.
+const productColumns = `id, category_slug, data, status, submitted_by, created_at, resubmitted_at, published_at,
+	rejected_at, changes_requested_at, withdrawn_at, discontinued_at, rejection_reason`
+
+// scanProduct scans the productColumns, followed by any extra columns into the given destinations.
+func scanProduct(row rowScanner, extra ...any) (model.Product, error) {
+	var p model.Product
+	dest := []any{&p.ID, &p.CategorySlug, &p.Data, &p.Status, &p.SubmittedBy, &p.SubmittedAt, &p.ResubmittedAt, &p.PublishedAt,
+		&p.RejectedAt, &p.ChangesRequestedAt, &p.WithdrawnAt, &p.DiscontinuedAt, &p.RejectionReason}
+	err := row.Scan(append(dest, extra...)...)
+	return p, err
+}
+
+// streamPageSize is how many products StreamPublishedProducts reads at a time.
+// It's a variable so that tests can make pages small.
+var streamPageSize = 500
+
+const insertProductQuery = "INSERT INTO products(category_slug, data, submitted_by) VALUES($1, $2, $3) RETURNING id, status, created_at"
+
 // AddProduct inserts a product into the database.
-// The returned product will have the "id" field filled in with the ID of the new product.
+// The returned product will have the "id", "status" and "submitted_at" fields filled in.
 func (s PostgresProductsStore) AddProduct(c context.Context, p model.Product) (model.Product, error) {
-	query := "INSERT INTO products(category_slug, data) VALUES($1, $2) RETURNING id"
-	row := s.db.QueryRow(c, query, p.CategorySlug, p.Data)
-	if err := row.Scan(&p.ID); err != nil {
+	row := s.db.QueryRow(c, insertProductQuery, p.CategorySlug, p.Data, p.SubmittedBy)
+	if err := row.Scan(&p.ID, &p.Status, &p.SubmittedAt); err != nil {
 		return model.Product{}, fmt.Errorf("error when inserting product: %s", err)
 	}
 	return p, nil
 }
 
+// AddProducts inserts several products into the database in a single transaction.
+// Either all of them are inserted, or none are.
+// The returned products will have the "id", "status" and "submitted_at" fields filled in.
+func (s PostgresProductsStore) AddProducts(c context.Context, ps []model.Product) ([]model.Product, error) {
+	tx, err := s.db.Begin(c)
+	if err != nil {
+		return nil, fmt.Errorf("error when starting transaction: %s", err)
+	}
+	defer tx.Rollback(c)
+
+	inserted := make([]model.Product, 0, len(ps))
+	for _, p := range ps {
+		if err := tx.QueryRow(c, insertProductQuery, p.CategorySlug, p.Data, p.SubmittedBy).Scan(&p.ID, &p.Status, &p.SubmittedAt); err != nil {
+			return nil, fmt.Errorf("error when inserting product: %s", err)
+		}
+		inserted = append(inserted, p)
+	}
+
+	if err := tx.Commit(c); err != nil {
+		return nil, fmt.Errorf("error when committing transaction: %s", err)
+	}
+	return inserted, nil
+}
+
 // GetProductsByCategory returns all products in the category with the given slug.
-// ONLY approved products are returned.
+// ONLY published products are returned.
 func (s PostgresProductsStore) GetProductsByCategory(c context.Context, slug string) ([]model.Product, error) {
-	query := "SELECT id, data, approved FROM products WHERE category_slug = $1 AND approved = true"
+	query := "SELECT " + productColumns + " FROM products WHERE category_slug = $1 AND status = 'published' ORDER BY id"
+	return s.queryProducts(c, query, slug)
+}
 
-	rows, err := s.db.Query(c, query, slug)
-	if err != nil {
-		return nil, fmt.Errorf("error when querying products: %s", err)
-	}
-	defer rows.Close()
+// StreamPublishedProducts calls fn for every published product in the category with the given slug,
+// or in all categories if the slug is empty, in order of their IDs.
+// Products are read a page at a time, and fn is only called between queries, so that slow callers don't hold a connection.
+func (s PostgresProductsStore) StreamPublishedProducts(c context.Context, categorySlug string, fn func(model.Product) error) error {
+	query := "SELECT " + productColumns + ` FROM products WHERE status = 'published' AND ($1 = '' OR category_slug = $1) AND id > $2
+		ORDER BY id LIMIT $3`
 
-	var products []model.Product
-	for rows.Next() {
-		var p model.Product
-		if err := rows.Scan(&p.ID, &p.Data, &p.Approved); err != nil {
-			return nil, fmt.Errorf("error when scanning product: %s", err)
+	lastID := 0
+	for {
+		page, err := s.queryProducts(c, query, categorySlug, lastID, streamPageSize)
+		if err != nil {
+			return err
 		}
-		p.CategorySlug = slug
-		products = append(products, p)
+
+		for _, p := range page {
+			if err := fn(p); err != nil {
+				return err
+			}
+		}
+
+		if len(page) < streamPageSize {
+			return nil
+		}
+		lastID = page[len(page)-1].ID
 	}
-	return products, nil
 }
 
 // GetProductByID returns the product with the given ID.
+// returns model.ErrProductNotFound if there's no such product.
 func (s PostgresProductsStore) GetProductByID(c context.Context, id int) (model.Product, error) {
-	query := "SELECT id, category_slug, data, approved FROM products WHERE id = $1"
+	query := "SELECT " + productColumns + " FROM products WHERE id = $1"
 
-	var p model.Product
-	row := s.db.QueryRow(c, query, id)
-	if err := row.Scan(&p.ID, &p.CategorySlug, &p.Data, &p.Approved); err != nil {
+	p, err := scanProduct(s.db.QueryRow(c, query, id))
+	if err == pgx.ErrNoRows {
+		return model.Product{}, model.ErrProductNotFound
+	}
+
+	if err != nil {
 		return model.Product{}, fmt.Errorf("error when querying product: %s", err)
 	}
 
 	return p, nil
 }
 
-// GetProductsRequiringApproval 		returns all products that need approval by the mod team.
-func (s PostgresProductsStore) GetProductsRequiringApproval(c context.Context) ([]model.Product, error) {
-	query := "SELECT id, category_slug, data, approved FROM products WHERE approved = false"
+// GetProductsBySubmitter returns all products submitted by the user with the given ID, newest first, whatever their status.
+func (s PostgresProductsStore) GetProductsBySubmitter(c context.Context, userID int64) ([]model.Product, error) {
+	query := "SELECT " + productColumns + " FROM products WHERE submitted_by = $1 ORDER BY id DESC"
+	return s.queryProducts(c, query, userID)
+}
 
-	rows, err := s.db.Query(c, query)
+func (s PostgresProductsStore) queryProducts(c context.Context, query string, args ...any) ([]model.Product, error) {
+	rows, err := s.db.Query(c, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("error when querying products: %s", err)
 	}
@@ -78,31 +141,121 @@ func (s PostgresProductsStore) GetProductsRequiringApproval(c context.Context) (
 
 	var products []model.Product
 	for rows.Next() {
-		var p model.Product
-		if err := rows.Scan(&p.ID, &p.CategorySlug, &p.Data, &p.Approved); err != nil {
+		p, err := scanProduct(rows)
+		if err != nil {
 			return nil, fmt.Errorf("error when scanning product: %s", err)
 		}
 		products = append(products, p)
 	}
-	return products, nil
+	return products, rows.Err()
+}
+
+// ApproveProduct publishes the product with the given ID, recording who did it.
+func (s PostgresProductsStore) ApproveProduct(c context.Context, id int, by model.Moderator) error {
+	query := `UPDATE products SET status = 'published', published_at = NOW(), claimed_by = NULL, claimed_at = NULL,
+		updated_at = NOW() WHERE id = $1 AND ` + statusCanBecome(model.StatusPublished) + " AND " + claimAllows(by, postgresClaimExpiry)
+	return s.transition(c, query, model.StatusPublished, model.NewModerationAction(model.ItemProduct, id, model.ActionApprove, by))
+}
+
+// RejectProduct rejects the product with the given ID for the given reason, recording who did it.
+// The product is kept, so that the submitter can see why it was rejected.
+func (s PostgresProductsStore) RejectProduct(c context.Context, id int, reason string, by model.Moderator) error {
+	query := `UPDATE products SET status = 'rejected', rejected_at = NOW(), rejection_reason = $2, claimed_by = NULL, claimed_at = NULL,
+		updated_at = NOW() WHERE id = $1 AND ` + statusCanBecome(model.StatusRejected) + " AND " + claimAllows(by, postgresClaimExpiry)
+	return s.transition(c, query, model.StatusRejected, model.NewModerationAction(model.ItemProduct, id, model.ActionReject, by), reason)
+}
+
+// DiscontinueProduct marks the product with the given ID as discontinued, recording who did it.
+func (s PostgresProductsStore) DiscontinueProduct(c context.Context, id int, by model.Moderator) error {
+	query := "UPDATE products SET status = 'discontinued', discontinued_at = NOW(), updated_at = NOW() WHERE id = $1 AND " +
+		statusCanBecome(model.StatusDiscontinued)
+	return s.transition(c, query, model.StatusDiscontinued, model.NewModerationAction(model.ItemProduct, id, model.ActionDiscontinue, by))
 }
 
-// ApproveProduct 		approves the product with the given ID.
-func (s PostgresProductsStore) ApproveProduct(c context.Context, id int) error {
-	query := "UPDATE products SET approved = true WHERE id = $1"
-	_, err := s.db.Exec(c, query, id)
+// WithdrawProduct marks the product with the given ID as withdrawn by its submitter.
+func (s PostgresProductsStore) WithdrawProduct(c context.Context, id int) error {
+	query := `UPDATE products SET status = 'withdrawn', withdrawn_at = NOW(), claimed_by = NULL, claimed_at = NULL,
+		updated_at = NOW() WHERE id = $1 AND ` + statusCanBecome(model.StatusWithdrawn)
+
+	tag, err := s.db.Exec(c, query, id)
 	if err != nil {
-		return fmt.Errorf("error when approving product: %s", err)
+		return fmt.Errorf("error when withdrawing product: %s", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return transitionError(c, s, id, model.StatusWithdrawn)
 	}
 	return nil
 }
 
-// RejectProduct 		rejects the product with the given ID.
-func (s PostgresProductsStore) RejectProduct(c context.Context, id int) error {
-	query := "DELETE FROM products WHERE id = $1"
-	_, err := s.db.Exec(c, query, id)
+// transition runs a statement changing the status of a product to the given status with moderate.
+// If it changes nothing, the error explains why.
+func (s PostgresProductsStore) transition(c context.Context, query string, to model.ProductStatus, a model.ModerationAction, args ...any) error {
+	err := s.moderate(c, query, a, args...)
+	if errors.Is(err, model.ErrItemNotFound) {
+		return transitionError(c, s, a.ItemID, to)
+	}
+	return err
+}
+
+// statusCanBecome returns an SQL condition which only holds for products whose status can change to the given status.
+// Statements changing the status include it, so that concurrent changes can't get past model.ProductStatus.CanBecome.
+func statusCanBecome(to model.ProductStatus) string {
+	from := model.StatusesBecoming(to)
+	quoted := make([]string, len(from))
+	for i, status := range from {
+		quoted[i] = "'" + string(status) + "'"
+	}
+	return "status IN (" + strings.Join(quoted, ", ") + ")"
+}
+
+// transitionError returns the error for a statement which was meant to change the status of the product with the given ID
+// to the given status, but didn't change anything: either the product doesn't exist, its status doesn't allow it,
+// or another moderator has claimed it, see claimAllows.
+func transitionError(c context.Context, s interface {
+	GetProductByID(c context.Context, id int) (model.Product, error)
+}, id int, to model.ProductStatus) error {
+	p, err := s.GetProductByID(c, id)
 	if err != nil {
-		return fmt.Errorf("error when deleting product: %s", err)
+		return err
 	}
-	return nil
+
+	if !p.Status.CanBecome(to) {
+		return model.ErrInvalidTransition(p.Status, to)
+	}
+	return model.ErrProductClaimed
+}
+
+// CountProductsByCategory returns the number of published and pending products in each category.
+// Products with other statuses aren't counted, and categories without any products are omitted.
+func (s PostgresProductsStore) CountProductsByCategory(c context.Context) (map[string]model.ProductCounts, error) {
+	query := `SELECT category_slug, status, COUNT(*) FROM products
+		WHERE status IN ('published', 'pending') GROUP BY category_slug, status`
+
+	rows, err := s.db.Query(c, query)
+	if err != nil {
+		return nil, fmt.Errorf("error when counting products: %s", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]model.ProductCounts)
+	for rows.Next() {
+		var (
+			slug   string
+			status model.ProductStatus
+			count  int
+		)
+		if err := rows.Scan(&slug, &status, &count); err != nil {
+			return nil, fmt.Errorf("error when scanning product count: %s", err)
+		}
+
+		cnt := counts[slug]
+		if status == model.StatusPublished {
+			cnt.Approved = count
+		} else {
+			cnt.Pending = count
+		}
+		counts[slug] = cnt
+	}
+	return counts, rows.Err()
 }
@@ -0,0 +1,310 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/mikolysz/enably/model"
+)
+
+func TestProducts(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s testStores) {
+		c := context.Background()
+		submitter := addTestUser(t, s, "submitter@example.com")
+
+		first := addTestProduct(t, s, "screen_readers", &submitter.ID)
+		if first.ID == 0 || first.Status != model.StatusPending || first.SubmittedAt.IsZero() {
+			t.Fatalf("AddProduct returned %+v, want a pending product with an ID and submission time", first)
+		}
+
+		added, err := s.products.AddProducts(c, []model.Product{
+			{CategorySlug: "screen_readers", Data: testProductData("JAWS")},
+			{CategorySlug: "games", Data: testProductData("A Hero's Call")},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(added) != 2 || added[0].ID == 0 || added[1].ID == 0 {
+			t.Fatalf("AddProducts returned %+v, want two products with IDs", added)
+		}
+
+		got, err := s.products.GetProductByID(c, first.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.CategorySlug != "screen_readers" || got.Data["software"]["name"] != "NVDA" ||
+			got.SubmittedBy == nil || *got.SubmittedBy != submitter.ID {
+			t.Errorf("GetProductByID returned %+v, want the first product", got)
+		}
+		if _, err := s.products.GetProductByID(c, 1000); !errors.Is(err, model.ErrProductNotFound) {
+			t.Errorf("GetProductByID of a missing product returned %v, want %v", err, model.ErrProductNotFound)
+		}
+
+		if err := s.products.ApproveProduct(c, added[0].ID, model.Moderator{}); err != nil {
+			t.Fatal(err)
+		}
+		inCategory, err := s.products.GetProductsByCategory(c, "screen_readers")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := productIDs(inCategory); !reflect.DeepEqual(ids, []int{added[0].ID}) {
+			t.Errorf("GetProductsByCategory returned products %v, want only the published one, %d", ids, added[0].ID)
+		}
+
+		var streamed []int
+		err = s.products.StreamPublishedProducts(c, "", func(p model.Product) error {
+			streamed = append(streamed, p.ID)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(streamed, []int{added[0].ID}) {
+			t.Errorf("StreamPublishedProducts streamed products %v, want only %d", streamed, added[0].ID)
+		}
+
+		// Rejected products are kept, with the reason, but they don't wait for approval or count as pending.
+		if err := s.products.RejectProduct(c, added[1].ID, "Not a real game", model.Moderator{}); err != nil {
+			t.Fatal(err)
+		}
+		got, err = s.products.GetProductByID(c, added[1].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != model.StatusRejected || got.RejectedAt == nil || got.RejectionReason != "Not a real game" {
+			t.Errorf("GetProductByID returned %+v for a rejected product, want the rejection and its reason", got)
+		}
+
+		assertQueuedProducts(t, s, first.ID)
+
+		counts, err := s.products.CountProductsByCategory(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]model.ProductCounts{"screen_readers": {Approved: 1, Pending: 1}}
+		if !reflect.DeepEqual(counts, want) {
+			t.Errorf("CountProductsByCategory returned %v, want %v", counts, want)
+		}
+
+		// Withdrawn and discontinued products aren't listed or counted either, but they keep the time they got their status.
+		if err := s.products.WithdrawProduct(c, first.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.products.DiscontinueProduct(c, added[0].ID, model.Moderator{}); err != nil {
+			t.Fatal(err)
+		}
+		for _, tt := range []struct {
+			id     int
+			status model.ProductStatus
+		}{{first.ID, model.StatusWithdrawn}, {added[0].ID, model.StatusDiscontinued}} {
+			got, err := s.products.GetProductByID(c, tt.id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Status != tt.status || (got.WithdrawnAt == nil && got.DiscontinuedAt == nil) {
+				t.Errorf("GetProductByID returned %+v, want a %s product with the time it became %[2]s", got, tt.status)
+			}
+		}
+		assertQueuedProducts(t, s)
+		counts, err = s.products.CountProductsByCategory(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(counts) != 0 {
+			t.Errorf("CountProductsByCategory returned %v, want no products", counts)
+		}
+
+		// A submitter's products are listed newest first, whatever their status.
+		second := addTestProduct(t, s, "games", &submitter.ID)
+		submitted, err := s.products.GetProductsBySubmitter(c, submitter.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := productIDs(submitted); !reflect.DeepEqual(ids, []int{second.ID, first.ID}) {
+			t.Errorf("GetProductsBySubmitter returned products %v, want [%d %d]", ids, second.ID, first.ID)
+		}
+	})
+}
+
+func TestStreamPublishedProducts(t *testing.T) {
+	defaultPageSize := streamPageSize
+	streamPageSize = 2
+	t.Cleanup(func() { streamPageSize = defaultPageSize })
+
+	forEachBackend(t, func(t *testing.T, s testStores) {
+		c := context.Background()
+		var published, screenReaders []int
+		for i, category := range []string{"screen_readers", "games", "screen_readers", "games", "screen_readers", "games"} {
+			p := addTestProduct(t, s, category, nil)
+			if i == 3 {
+				continue // left pending.
+			}
+			if err := s.products.ApproveProduct(c, p.ID, model.Moderator{}); err != nil {
+				t.Fatal(err)
+			}
+			published = append(published, p.ID)
+			if category == "screen_readers" {
+				screenReaders = append(screenReaders, p.ID)
+			}
+		}
+
+		for _, tt := range []struct {
+			category string
+			want     []int
+		}{{"", published}, {"screen_readers", screenReaders}, {"keyboards", nil}} {
+			var streamed []int
+			err := s.products.StreamPublishedProducts(c, tt.category, func(p model.Product) error {
+				// The SQLite test database only has one connection, so this can't work while a query is still open.
+				if _, err := s.products.GetProductByID(c, p.ID); err != nil {
+					return err
+				}
+				streamed = append(streamed, p.ID)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(streamed, tt.want) {
+				t.Errorf("StreamPublishedProducts streamed products %v in category %q, want %v", streamed, tt.category, tt.want)
+			}
+		}
+
+		errStop := errors.New("stop")
+		var calls int
+		err := s.products.StreamPublishedProducts(c, "", func(p model.Product) error {
+			calls++
+			return errStop
+		})
+		if !errors.Is(err, errStop) || calls != 1 {
+			t.Errorf("StreamPublishedProducts returned %v after %d calls, want it to stop at the first error", err, calls)
+		}
+	})
+}
+
+// statusTransitions lists the statuses every status can change to. All other changes must be refused.
+var statusTransitions = []struct {
+	from    model.ProductStatus
+	allowed []model.ProductStatus
+}{
+	{model.StatusPending, []model.ProductStatus{model.StatusPublished, model.StatusRejected, model.StatusChangesRequested, model.StatusWithdrawn}},
+	{model.StatusChangesRequested, []model.ProductStatus{model.StatusPending, model.StatusRejected, model.StatusWithdrawn}},
+	{model.StatusRejected, []model.ProductStatus{model.StatusPublished}},
+	{model.StatusPublished, []model.ProductStatus{model.StatusRejected, model.StatusDiscontinued}},
+	{model.StatusWithdrawn, nil},
+	{model.StatusDiscontinued, []model.ProductStatus{model.StatusPublished}},
+}
+
+// transitionPaths lists the statuses a new product goes through to get the given one.
+var transitionPaths = map[model.ProductStatus][]model.ProductStatus{
+	model.StatusPending:          nil,
+	model.StatusChangesRequested: {model.StatusChangesRequested},
+	model.StatusRejected:         {model.StatusRejected},
+	model.StatusPublished:        {model.StatusPublished},
+	model.StatusWithdrawn:        {model.StatusWithdrawn},
+	model.StatusDiscontinued:     {model.StatusPublished, model.StatusDiscontinued},
+}
+
+var allStatuses = []model.ProductStatus{
+	model.StatusPending,
+	model.StatusPublished,
+	model.StatusRejected,
+	model.StatusChangesRequested,
+	model.StatusWithdrawn,
+	model.StatusDiscontinued,
+}
+
+func TestProductStatusTransitions(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s testStores) {
+		for _, tt := range statusTransitions {
+			for _, to := range allStatuses {
+				var wantErr error
+				if !containsStatus(tt.allowed, to) {
+					wantErr = model.ErrInvalidTransition(tt.from, to)
+					if to == model.StatusPending {
+						wantErr = model.ErrNoChangesRequested
+					}
+				}
+
+				p := addTestProduct(t, s, "screen_readers", nil)
+				for _, status := range transitionPaths[tt.from] {
+					if err := changeTestProductStatus(s, p.ID, status, model.Moderator{}); err != nil {
+						t.Fatalf("error when making product %s: %s", tt.from, err)
+					}
+				}
+
+				err := changeTestProductStatus(s, p.ID, to, model.Moderator{})
+				if !errors.Is(err, wantErr) {
+					t.Errorf("changing a %s product to %s returned %v, want %v", tt.from, to, err, wantErr)
+				}
+
+				want := to
+				if wantErr != nil {
+					want = tt.from
+				}
+				got, err := s.products.GetProductByID(context.Background(), p.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.Status != want {
+					t.Errorf("after changing a %s product to %s, its status is %s, want %s", tt.from, to, got.Status, want)
+				}
+			}
+		}
+	})
+}
+
+// changeTestProductStatus calls the store method which changes the status of the product to the given one.
+func changeTestProductStatus(s testStores, id int, to model.ProductStatus, by model.Moderator) error {
+	c := context.Background()
+	switch to {
+	case model.StatusPending:
+		return s.products.ResubmitProduct(c, id, testProductData("NVDA"))
+	case model.StatusPublished:
+		return s.products.ApproveProduct(c, id, by)
+	case model.StatusRejected:
+		return s.products.RejectProduct(c, id, "Not a real product", by)
+	case model.StatusChangesRequested:
+		comments := []model.Comment{{ProductID: id, FromModerator: true, Text: "Please add a download link"}}
+		return s.products.RequestProductChanges(c, id, comments, by)
+	case model.StatusWithdrawn:
+		return s.products.WithdrawProduct(c, id)
+	case model.StatusDiscontinued:
+		return s.products.DiscontinueProduct(c, id, by)
+	}
+	panic("unknown product status: " + string(to))
+}
+
+func addTestProduct(t *testing.T, s testStores, category string, submittedBy *int64) model.Product {
+	p, err := s.products.AddProduct(context.Background(), model.Product{
+		CategorySlug: category,
+		SubmittedBy:  submittedBy,
+		Data:         testProductData("NVDA"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func testProductData(name string) map[string]map[string]any {
+	return map[string]map[string]any{"software": {"name": name}}
+}
+
+func productIDs(ps []model.Product) []int {
+	var ids []int
+	for _, p := range ps {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+func containsStatus(statuses []model.ProductStatus, status model.ProductStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mikolysz/enably/model"
+)
+
+// queueCondition selects the products waiting for a moderator's decision.
+const queueCondition = "status = 'pending'"
+
+// queueColumns are selected after the productColumns when listing the moderation queue, see queueRow.
+const queueColumns = `claimed_by, COALESCE((SELECT email_address FROM users WHERE users.id = products.claimed_by), ''), claimed_at,
+	(SELECT COUNT(*) FROM moderator_notes WHERE moderator_notes.product_id = products.id)`
+
+// queueRow holds the queueColumns of a product.
+type queueRow struct {
+	claimedBy      *int64
+	claimedByEmail string
+	claimedAt      *time.Time
+	noteCount      int
+}
+
+// dest returns the destinations for scanning the queueColumns.
+func (r *queueRow) dest() []any {
+	return []any{&r.claimedBy, &r.claimedByEmail, &r.claimedAt, &r.noteCount}
+}
+
+// item returns the queue item for the given product.
+func (r *queueRow) item(p model.Product) model.QueueItem {
+	item := model.QueueItem{
+		Product:        p,
+		SubmissionType: model.SubmissionNew,
+		QueuedAt:       p.SubmittedAt,
+		NoteCount:      r.noteCount,
+	}
+
+	if p.ResubmittedAt != nil {
+		item.SubmissionType = model.SubmissionResubmission
+		item.QueuedAt = *p.ResubmittedAt
+	}
+
+	item.Claim = r.claim()
+	return item
+}
+
+// claim returns the claim on the product, or nil if it isn't claimed.
+func (r *queueRow) claim() *model.Claim {
+	if r.claimedBy == nil || r.claimedAt == nil {
+		return nil
+	}
+	return &model.Claim{ModeratorID: *r.claimedBy, ModeratorEmail: r.claimedByEmail, ClaimedAt: *r.claimedAt}
+}
+
+// queueFilter returns the WHERE and ORDER BY clauses selecting the products in the moderation queue which match the query,
+// along with their arguments. placeholder returns the placeholder for the n-th argument, counting from 1.
+func queueFilter(q model.QueueQuery, placeholder func(n int) string) (where, orderBy string, args []any) {
+	conditions := []string{queueCondition}
+
+	if len(q.CategorySlugs) > 0 {
+		placeholders := make([]string, 0, len(q.CategorySlugs))
+		for _, slug := range q.CategorySlugs {
+			args = append(args, slug)
+			placeholders = append(placeholders, placeholder(len(args)))
+		}
+		conditions = append(conditions, "category_slug IN ("+strings.Join(placeholders, ", ")+")")
+	}
+
+	switch q.SubmissionType {
+	case model.SubmissionNew:
+		conditions = append(conditions, "resubmitted_at IS NULL")
+	case model.SubmissionResubmission:
+		conditions = append(conditions, "resubmitted_at IS NOT NULL")
+	}
+
+	direction := "ASC"
+	if q.NewestFirst {
+		direction = "DESC"
+	}
+
+	where = " WHERE " + strings.Join(conditions, " AND ")
+	orderBy = fmt.Sprintf(" ORDER BY COALESCE(resubmitted_at, created_at) %s, id %s", direction, direction)
+	return where, orderBy, args
+}
+
+// GetModerationQueue returns a page of the products waiting for a moderator's decision which match the given query.
+func (s PostgresProductsStore) GetModerationQueue(c context.Context, q model.QueueQuery) (model.QueuePage, error) {
+	placeholder := func(n int) string { return fmt.Sprintf("$%d", n) }
+	where, orderBy, args := queueFilter(q, placeholder)
+
+	var page model.QueuePage
+	if err := s.db.QueryRow(c, "SELECT COUNT(*) FROM products"+where, args...).Scan(&page.Total); err != nil {
+		return model.QueuePage{}, fmt.Errorf("error when counting products in the moderation queue: %s", err)
+	}
+
+	query := "SELECT " + productColumns + ", " + queueColumns + " FROM products" + where + orderBy +
+		fmt.Sprintf(" LIMIT %s OFFSET %s", placeholder(len(args)+1), placeholder(len(args)+2))
+
+	rows, err := s.db.Query(c, query, append(args, q.Limit, q.Offset)...)
+	if err != nil {
+		return model.QueuePage{}, fmt.Errorf("error when querying the moderation queue: %s", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r queueRow
+		p, err := scanProduct(rows, r.dest()...)
+		if err != nil {
+			return model.QueuePage{}, fmt.Errorf("error when scanning product: %s", err)
+		}
+		page.Items = append(page.Items, r.item(p))
+	}
+	return page, rows.Err()
+}
+
+// ClaimProduct marks the product with the given ID as being reviewed by the user with the given ID.
+// Claims made before expiredBefore are overridden, other claims by other moderators result in model.ErrProductClaimed.
+func (s PostgresProductsStore) ClaimProduct(c context.Context, id int, userID int64, expiredBefore time.Time) error {
+	query := `UPDATE products SET claimed_by = $2, claimed_at = NOW()
+		WHERE id = $1 AND (claimed_by IS NULL OR claimed_by = $2 OR claimed_at < $3)`
+
+	tag, err := s.db.Exec(c, query, id, userID, expiredBefore)
+	if err != nil {
+		return fmt.Errorf("error when claiming product: %s", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return model.ErrProductClaimed
+	}
+	return nil
+}
+
+// ReleaseProductClaim removes the claim of the user with the given ID on the product with the given ID, if they have one.
+func (s PostgresProductsStore) ReleaseProductClaim(c context.Context, id int, userID int64) error {
+	query := "UPDATE products SET claimed_by = NULL, claimed_at = NULL WHERE id = $1 AND claimed_by = $2"
+	if _, err := s.db.Exec(c, query, id, userID); err != nil {
+		return fmt.Errorf("error when releasing claim: %s", err)
+	}
+	return nil
+}
+
+// postgresClaimExpiry and sqliteClaimExpiry are SQL expressions for the time before which claims have expired.
+var (
+	postgresClaimExpiry = fmt.Sprintf("NOW() - INTERVAL '%d seconds'", int(model.ClaimDuration.Seconds()))
+	sqliteClaimExpiry   = fmt.Sprintf("datetime('now', '-%d seconds')", int(model.ClaimDuration.Seconds()))
+)
+
+// claimAllows returns an SQL condition which only holds for products the given moderator can decide on:
+// ones nobody has claimed, ones they claimed, and ones whose claim was made before expiredBefore, an SQL expression.
+// Statements recording decisions include it, so that two moderators can't decide on the same product at once.
+// The moderation API key isn't bound by claims, as it's meant for emergencies.
+func claimAllows(by model.Moderator, expiredBefore string) string {
+	if by.UserID == 0 {
+		return "TRUE"
+	}
+	return fmt.Sprintf("(claimed_by IS NULL OR claimed_by = %d OR claimed_at < %s)", by.UserID, expiredBefore)
+}
+
+// AddModeratorNote adds an internal note about a product.
+// The returned note will have the "id" and "created_at" fields filled in.
+func (s PostgresProductsStore) AddModeratorNote(c context.Context, n model.ModeratorNote) (model.ModeratorNote, error) {
+	query := "INSERT INTO moderator_notes(product_id, author_id, text) VALUES($1, $2, $3) RETURNING id, created_at"
+	if err := s.db.QueryRow(c, query, n.ProductID, n.AuthorID, n.Text).Scan(&n.ID, &n.CreatedAt); err != nil {
+		return model.ModeratorNote{}, fmt.Errorf("error when inserting moderator note: %s", err)
+	}
+	return n, nil
+}
+
+// GetModeratorNotes returns the internal notes about the product with the given ID, oldest first.
+func (s PostgresProductsStore) GetModeratorNotes(c context.Context, productID int) ([]model.ModeratorNote, error) {
+	query := `SELECT n.id, n.product_id, n.author_id, COALESCE(u.email_address, ''), n.text, n.created_at
+		FROM moderator_notes n LEFT JOIN users u ON u.id = n.author_id
+		WHERE n.product_id = $1 ORDER BY n.id`
+
+	rows, err := s.db.Query(c, query, productID)
+	if err != nil {
+		return nil, fmt.Errorf("error when querying moderator notes: %s", err)
+	}
+	defer rows.Close()
+
+	var notes []model.ModeratorNote
+	for rows.Next() {
+		var n model.ModeratorNote
+		if err := rows.Scan(&n.ID, &n.ProductID, &n.AuthorID, &n.AuthorEmail, &n.Text, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error when scanning moderator note: %s", err)
+		}
+		notes = append(notes, n)
+	}
+	return notes, rows.Err()
+}
@@ -0,0 +1,227 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mikolysz/enably/model"
+)
+
+func TestModerationQueue(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s testStores) {
+		c := context.Background()
+		var ids []int
+		for _, category := range []string{"screen_readers", "games", "screen_readers", "games"} {
+			ids = append(ids, addTestProduct(t, s, category, nil).ID)
+		}
+
+		// Decided products leave the queue, and resubmitted ones come back.
+		if err := s.products.ApproveProduct(c, ids[3], model.Moderator{}); err != nil {
+			t.Fatal(err)
+		}
+		resubmitted := addTestProduct(t, s, "games", nil).ID
+		comments := []model.Comment{{ProductID: resubmitted, FromModerator: true, Text: "Please add a download link"}}
+		if err := s.products.RequestProductChanges(c, resubmitted, comments, model.Moderator{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.products.ResubmitProduct(c, resubmitted, testProductData("NVDA")); err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			name      string
+			query     model.QueueQuery
+			wantIDs   []int
+			wantTotal int
+		}{
+			{"new", model.QueueQuery{SubmissionType: model.SubmissionNew}, ids[:3], 3},
+			{"newest first", model.QueueQuery{SubmissionType: model.SubmissionNew, NewestFirst: true}, []int{ids[2], ids[1], ids[0]}, 3},
+			{"resubmissions", model.QueueQuery{SubmissionType: model.SubmissionResubmission}, []int{resubmitted}, 1},
+			{"category", model.QueueQuery{CategorySlugs: []string{"screen_readers"}}, []int{ids[0], ids[2]}, 2},
+			{"categories", model.QueueQuery{CategorySlugs: []string{"screen_readers", "games"}, SubmissionType: model.SubmissionNew}, ids[:3], 3},
+			{"page", model.QueueQuery{SubmissionType: model.SubmissionNew, Offset: 1, Limit: 1}, []int{ids[1]}, 3},
+			{"past the end", model.QueueQuery{Offset: 10}, nil, 4},
+		}
+		for _, tt := range tests {
+			if tt.query.Limit == 0 {
+				tt.query.Limit = model.MaxQueuePageSize
+			}
+			page, err := s.products.GetModerationQueue(c, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := queueItemIDs(page); !reflect.DeepEqual(got, tt.wantIDs) || page.Total != tt.wantTotal {
+				t.Errorf("%s: GetModerationQueue returned products %v out of %d, want %v out of %d", tt.name, got, page.Total, tt.wantIDs, tt.wantTotal)
+			}
+		}
+
+		page, err := s.products.GetModerationQueue(c, model.QueueQuery{SubmissionType: model.SubmissionResubmission, Limit: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Items) != 1 || page.Items[0].SubmissionType != model.SubmissionResubmission || page.Items[0].QueuedAt.IsZero() {
+			t.Errorf("GetModerationQueue returned %+v, want the resubmitted product with the time it was queued", page.Items)
+		}
+	})
+}
+
+func TestProductClaims(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s testStores) {
+		c := context.Background()
+		first := addTestUser(t, s, "first@example.com").ID
+		second := addTestUser(t, s, "second@example.com").ID
+		p := addTestProduct(t, s, "screen_readers", nil)
+		notExpired := time.Now().Add(-model.ClaimDuration)
+
+		if err := s.products.ClaimProduct(c, p.ID, first, notExpired); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.products.ClaimProduct(c, p.ID, first, notExpired); err != nil {
+			t.Errorf("claiming a product again returned %v", err)
+		}
+		if err := s.products.ClaimProduct(c, p.ID, second, notExpired); !errors.Is(err, model.ErrProductClaimed) {
+			t.Errorf("claiming a claimed product returned %v, want %v", err, model.ErrProductClaimed)
+		}
+		assertProductClaim(t, s, p.ID, first)
+
+		// Claims made before expiredBefore can be taken over.
+		if err := s.products.ClaimProduct(c, p.ID, second, time.Now().Add(time.Minute)); err != nil {
+			t.Errorf("claiming a product with an expired claim returned %v", err)
+		}
+
+		// Only the moderator with the claim can release it.
+		if err := s.products.ReleaseProductClaim(c, p.ID, first); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.products.ClaimProduct(c, p.ID, first, notExpired); !errors.Is(err, model.ErrProductClaimed) {
+			t.Errorf("claiming a product after another moderator's release returned %v, want %v", err, model.ErrProductClaimed)
+		}
+		if err := s.products.ReleaseProductClaim(c, p.ID, second); err != nil {
+			t.Fatal(err)
+		}
+		assertProductClaim(t, s, p.ID, 0)
+	})
+}
+
+func TestClaimedProductDecisions(t *testing.T) {
+	tests := []struct {
+		name string
+
+		// claimedBy and decidedBy are indexes into the moderators, with 0 standing for the moderation API key.
+		claimedBy int
+		decidedBy int
+		wantErr   error
+	}{
+		{"unclaimed", 0, 1, nil},
+		{"claimed by the same moderator", 1, 1, nil},
+		{"claimed by another moderator", 2, 1, model.ErrProductClaimed},
+		{"API key", 2, 0, nil},
+	}
+
+	forEachBackend(t, func(t *testing.T, s testStores) {
+		moderators := []model.Moderator{
+			{},
+			{UserID: addTestUser(t, s, "first@example.com").ID},
+			{UserID: addTestUser(t, s, "second@example.com").ID},
+		}
+
+		for _, tt := range tests {
+			for _, to := range []model.ProductStatus{model.StatusPublished, model.StatusRejected, model.StatusChangesRequested} {
+				p := addTestProduct(t, s, "screen_readers", nil)
+				if tt.claimedBy != 0 {
+					if err := s.products.ClaimProduct(context.Background(), p.ID, moderators[tt.claimedBy].UserID, time.Now().Add(-model.ClaimDuration)); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				err := changeTestProductStatus(s, p.ID, to, moderators[tt.decidedBy])
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("%s: changing the product to %s returned %v, want %v", tt.name, to, err, tt.wantErr)
+				}
+			}
+		}
+	})
+}
+
+func TestModeratorNotes(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s testStores) {
+		c := context.Background()
+		moderator := addTestUser(t, s, "moderator@example.com")
+		p := addTestProduct(t, s, "screen_readers", nil)
+
+		for _, n := range []model.ModeratorNote{
+			{ProductID: p.ID, AuthorID: &moderator.ID, Text: "Checking with the developer"},
+			{ProductID: p.ID, Text: "Imported from the old list"},
+		} {
+			added, err := s.products.AddModeratorNote(c, n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if added.ID == 0 || added.CreatedAt.IsZero() {
+				t.Errorf("AddModeratorNote returned %+v, want a note with an ID and creation time", added)
+			}
+		}
+
+		notes, err := s.products.GetModeratorNotes(c, p.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(notes) != 2 || notes[0].Text != "Checking with the developer" || notes[0].AuthorID == nil ||
+			*notes[0].AuthorID != moderator.ID || notes[1].AuthorID != nil {
+			t.Errorf("GetModeratorNotes returned %+v, want both notes, oldest first", notes)
+		}
+
+		page, err := s.products.GetModerationQueue(c, model.QueueQuery{Limit: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Items) != 1 || page.Items[0].NoteCount != 2 {
+			t.Errorf("GetModerationQueue returned %+v, want the product with 2 notes", page.Items)
+		}
+	})
+}
+
+// assertQueuedProducts checks that the products with the given IDs, and no others, are in the moderation queue, oldest first.
+func assertQueuedProducts(t *testing.T, s testStores, want ...int) {
+	t.Helper()
+	page, err := s.products.GetModerationQueue(context.Background(), model.QueueQuery{Limit: model.MaxQueuePageSize})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := queueItemIDs(page); !reflect.DeepEqual(ids, want) {
+		t.Errorf("GetModerationQueue returned products %v, want %v", ids, want)
+	}
+}
+
+func queueItemIDs(page model.QueuePage) []int {
+	var ids []int
+	for _, item := range page.Items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+// assertProductClaim checks that the product with the given ID is in the moderation queue, claimed by the given user, or unclaimed if moderatorID is 0.
+func assertProductClaim(t *testing.T, s testStores, id int, moderatorID int64) {
+	t.Helper()
+	page, err := s.products.GetModerationQueue(context.Background(), model.QueueQuery{Limit: model.MaxQueuePageSize})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range page.Items {
+		if item.ID != id {
+			continue
+		}
+		if moderatorID == 0 && item.Claim != nil {
+			t.Errorf("product %d is claimed by user %d, want it unclaimed", id, item.Claim.ModeratorID)
+		}
+		if moderatorID != 0 && (item.Claim == nil || item.Claim.ModeratorID != moderatorID || item.Claim.ClaimedAt.IsZero()) {
+			t.Errorf("product %d has claim %+v, want a claim by user %d", id, item.Claim, moderatorID)
+		}
+		return
+	}
+	t.Errorf("product %d isn't in the moderation queue", id)
+}
@@ -0,0 +1,39 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresRateLimitStore keeps rate limit counts in the database, so that they're shared by all instances of the server.
+type PostgresRateLimitStore struct {
+	DB *pgxpool.Pool
+}
+
+// Hit counts a request under the given key, starting a new window which ends at resetsAt if the current one is over.
+func (s PostgresRateLimitStore) Hit(c context.Context, key string, now, resetsAt time.Time) (int, time.Time, error) {
+	// The upsert is atomic, so concurrent requests can't all slip in under the limit.
+	query := `INSERT INTO rate_limits(key, hits, resets_at) VALUES($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			hits = CASE WHEN rate_limits.resets_at <= $3 THEN 1 ELSE rate_limits.hits + 1 END,
+			resets_at = CASE WHEN rate_limits.resets_at <= $3 THEN EXCLUDED.resets_at ELSE rate_limits.resets_at END
+		RETURNING hits, resets_at`
+
+	var hits int
+	var windowEnd time.Time
+	if err := s.DB.QueryRow(c, query, key, resetsAt, now).Scan(&hits, &windowEnd); err != nil {
+		return 0, time.Time{}, fmt.Errorf("error when counting request: %s", err)
+	}
+	return hits, windowEnd, nil
+}
+
+// DeleteExpiredRateLimits deletes the counts of the windows which ended before the given time.
+func (s PostgresRateLimitStore) DeleteExpiredRateLimits(c context.Context, now time.Time) error {
+	if _, err := s.DB.Exec(c, "DELETE FROM rate_limits WHERE resets_at <= $1", now); err != nil {
+		return fmt.Errorf("error when deleting expired rate limits: %s", err)
+	}
+	return nil
+}
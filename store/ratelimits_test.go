@@ -0,0 +1,56 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimitHit(t *testing.T) {
+	start := time.Now().Truncate(time.Second)
+	windowEnd := start.Add(time.Minute)
+	nextWindowEnd := windowEnd.Add(time.Minute)
+
+	hits := []struct {
+		key           string
+		now           time.Time
+		wantHits      int
+		wantWindowEnd time.Time
+	}{
+		{"login:user@example.com", start, 1, windowEnd},
+		{"login:user@example.com", start.Add(time.Second), 2, windowEnd},
+		{"login:other@example.com", start.Add(time.Second), 1, start.Add(time.Second + time.Minute)},
+		{"login:user@example.com", windowEnd.Add(-time.Second), 3, windowEnd},
+
+		// The window is over once the time it resets at comes.
+		{"login:user@example.com", windowEnd, 1, nextWindowEnd},
+		{"login:user@example.com", windowEnd.Add(time.Second), 2, nextWindowEnd},
+	}
+
+	forEachBackend(t, func(t *testing.T, s testStores) {
+		c := context.Background()
+		for i, h := range hits {
+			gotHits, gotWindowEnd, err := s.rateLimits.Hit(c, h.key, h.now, h.now.Add(time.Minute))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gotHits != h.wantHits || !gotWindowEnd.Equal(h.wantWindowEnd) {
+				t.Errorf("hit %d returned %d hits in a window ending at %s, want %d hits in one ending at %s",
+					i, gotHits, gotWindowEnd, h.wantHits, h.wantWindowEnd)
+			}
+		}
+
+		// Deleting expired windows keeps the ones still in progress.
+		now := windowEnd.Add(2 * time.Second)
+		if err := s.rateLimits.DeleteExpiredRateLimits(c, now); err != nil {
+			t.Fatal(err)
+		}
+		gotHits, _, err := s.rateLimits.Hit(c, "login:user@example.com", now, now.Add(time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gotHits != 3 {
+			t.Errorf("Hit returned %d hits after deleting expired windows, want 3", gotHits)
+		}
+	})
+}
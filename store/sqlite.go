@@ -0,0 +1,58 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// OpenSQLiteDB opens the SQLite database at the given path.
+// Foreign keys are enforced, and writers wait for each other instead of failing immediately.
+func OpenSQLiteDB(path string) (*sql.DB, error) {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	dsn := path + sep + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error when opening SQLite database: %s", err)
+	}
+	return db, nil
+}
+
+// jsonColumn scans a JSON document stored as text into dest.
+type jsonColumn struct {
+	dest any
+}
+
+func (j jsonColumn) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("cannot scan %T as JSON", src)
+	}
+	return json.Unmarshal(data, j.dest)
+}
+
+// toJSONText encodes v as JSON, for storing in a text column.
+func toJSONText(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// sqliteTime formats t in UTC in the same format as CURRENT_TIMESTAMP,
+// so that times can be compared as strings and read back by the driver.
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
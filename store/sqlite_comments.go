@@ -0,0 +1,112 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mikolysz/enably/model"
+)
+
+const sqliteInsertCommentQuery = `INSERT INTO product_comments(product_id, author_id, from_moderator, field, text)
+	VALUES(?, ?, ?, ?, ?) RETURNING id, created_at`
+
+// RequestProductChanges sends the product with the given ID back to its submitter with the given comments,
+// recording who did it. The product stays out of the moderation queue until it's resubmitted.
+func (s SQLiteProductsStore) RequestProductChanges(c context.Context, id int, comments []model.Comment, by model.Moderator) error {
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return fmt.Errorf("error when starting transaction: %s", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE products SET status = 'changes_requested', changes_requested_at = CURRENT_TIMESTAMP, claimed_by = NULL, claimed_at = NULL,
+		updated_at = CURRENT_TIMESTAMP WHERE id = ? AND ` + statusCanBecome(model.StatusChangesRequested) + " AND " + claimAllows(by, sqliteClaimExpiry)
+	res, err := tx.ExecContext(c, query, id)
+	if err != nil {
+		return fmt.Errorf("error when requesting changes: %s", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error when requesting changes: %w", err)
+	}
+	if n == 0 {
+		// The product is read outside the transaction, which ends first in case the database allows only one connection.
+		tx.Rollback()
+		return transitionError(c, s, id, model.StatusChangesRequested)
+	}
+
+	for _, cm := range comments {
+		if _, err := tx.ExecContext(c, sqliteInsertCommentQuery, id, cm.AuthorID, cm.FromModerator, cm.Field, cm.Text); err != nil {
+			return fmt.Errorf("error when inserting comment: %s", err)
+		}
+	}
+
+	if err := sqliteRecordModerationAction(c, tx, model.NewModerationAction(model.ItemProduct, id, model.ActionRequestChanges, by)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error when committing transaction: %s", err)
+	}
+	return nil
+}
+
+// ResubmitProduct replaces the data of the product with the given ID and returns it to the moderation queue.
+// returns model.ErrNoChangesRequested if no changes were requested to the product.
+func (s SQLiteProductsStore) ResubmitProduct(c context.Context, id int, data map[string]map[string]any) error {
+	encoded, err := toJSONText(data)
+	if err != nil {
+		return fmt.Errorf("error when encoding product data: %s", err)
+	}
+
+	query := `UPDATE products SET data = json(?), status = 'pending', resubmitted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND ` + statusCanBecome(model.StatusPending)
+
+	res, err := s.db.ExecContext(c, query, encoded, id)
+	if err != nil {
+		return fmt.Errorf("error when resubmitting product: %s", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error when resubmitting product: %w", err)
+	}
+	if n == 0 {
+		return model.ErrNoChangesRequested
+	}
+	return nil
+}
+
+// AddProductComment adds a comment to the conversation about a product.
+// The returned comment will have the "id" and "created_at" fields filled in.
+func (s SQLiteProductsStore) AddProductComment(c context.Context, cm model.Comment) (model.Comment, error) {
+	row := s.db.QueryRowContext(c, sqliteInsertCommentQuery, cm.ProductID, cm.AuthorID, cm.FromModerator, cm.Field, cm.Text)
+	if err := row.Scan(&cm.ID, &cm.CreatedAt); err != nil {
+		return model.Comment{}, fmt.Errorf("error when inserting comment: %s", err)
+	}
+	return cm, nil
+}
+
+// GetProductComments returns the conversation about the product with the given ID, oldest first.
+func (s SQLiteProductsStore) GetProductComments(c context.Context, productID int) ([]model.Comment, error) {
+	query := `SELECT c.id, c.product_id, c.author_id, COALESCE(u.display_name, ''), c.from_moderator, c.field, c.text, c.created_at
+		FROM product_comments c LEFT JOIN users u ON u.id = c.author_id
+		WHERE c.product_id = ? ORDER BY c.id`
+
+	rows, err := s.db.QueryContext(c, query, productID)
+	if err != nil {
+		return nil, fmt.Errorf("error when querying comments: %s", err)
+	}
+	defer rows.Close()
+
+	var comments []model.Comment
+	for rows.Next() {
+		var cm model.Comment
+		if err := rows.Scan(&cm.ID, &cm.ProductID, &cm.AuthorID, &cm.AuthorName, &cm.FromModerator, &cm.Field, &cm.Text, &cm.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error when scanning comment: %s", err)
+		}
+		comments = append(comments, cm)
+	}
+	return comments, rows.Err()
+}
@@ -0,0 +1,154 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/mikolysz/enably/model"
+)
+
+// moderate runs the given statement, which changes the item with the ID passed as its first parameter, followed by the given args,
+// and records the moderation action in the same transaction.
+// returns model.ErrItemNotFound if the statement didn't change anything.
+func (s SQLiteProductsStore) moderate(c context.Context, query string, a model.ModerationAction, args ...any) error {
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return fmt.Errorf("error when starting transaction: %s", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(c, query, append([]any{a.ItemID}, args...)...)
+	if err != nil {
+		return fmt.Errorf("error when moderating %s: %s", a.ItemType, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error when moderating %s: %w", a.ItemType, err)
+	}
+	if n == 0 {
+		return model.ErrItemNotFound
+	}
+
+	if err := sqliteRecordModerationAction(c, tx, a); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error when committing transaction: %s", err)
+	}
+	return nil
+}
+
+// sqliteRecordModerationAction inserts the given moderation action as part of the given transaction.
+func sqliteRecordModerationAction(c context.Context, tx *sql.Tx, a model.ModerationAction) error {
+	insert := "INSERT INTO moderation_actions(item_type, item_id, action, moderator_id) VALUES(?, ?, ?, ?)"
+	if _, err := tx.ExecContext(c, insert, a.ItemType, a.ItemID, a.Action, a.ModeratorID); err != nil {
+		return fmt.Errorf("error when recording moderation action: %s", err)
+	}
+	return nil
+}
+
+// ListModerationActions returns the most recent moderation actions, newest first.
+func (s SQLiteProductsStore) ListModerationActions(c context.Context, limit int) ([]model.ModerationAction, error) {
+	query := `SELECT a.id, a.item_type, a.item_id, a.action, a.moderator_id, COALESCE(u.email_address, ''), a.created_at
+		FROM moderation_actions a LEFT JOIN users u ON u.id = a.moderator_id
+		ORDER BY a.id DESC LIMIT ?`
+
+	rows, err := s.db.QueryContext(c, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error when querying moderation actions: %s", err)
+	}
+	defer rows.Close()
+
+	var actions []model.ModerationAction
+	for rows.Next() {
+		var a model.ModerationAction
+		if err := rows.Scan(&a.ID, &a.ItemType, &a.ItemID, &a.Action, &a.ModeratorID, &a.ModeratorEmail, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error when scanning moderation action: %s", err)
+		}
+		actions = append(actions, a)
+	}
+	return actions, rows.Err()
+}
+
+// EditAndApproveProduct replaces the data of the product with the given ID and approves it, recording who did it.
+// The edit is kept along with the product's original data.
+func (s SQLiteProductsStore) EditAndApproveProduct(c context.Context, id int, data map[string]map[string]any, edit model.ModeratorEdit, by model.Moderator) error {
+	encoded, err := toJSONText(data)
+	if err != nil {
+		return fmt.Errorf("error when encoding product data: %s", err)
+	}
+
+	original, err := toJSONText(edit.Original)
+	if err != nil {
+		return fmt.Errorf("error when encoding original product data: %s", err)
+	}
+
+	changes, err := toJSONText(edit.Changes)
+	if err != nil {
+		return fmt.Errorf("error when encoding moderator's changes: %s", err)
+	}
+
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return fmt.Errorf("error when starting transaction: %s", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE products SET data = json(?2), status = 'published', published_at = CURRENT_TIMESTAMP, claimed_by = NULL, claimed_at = NULL,
+		updated_at = CURRENT_TIMESTAMP WHERE id = ?1 AND ` + statusCanBecome(model.StatusPublished) + " AND " + claimAllows(by, sqliteClaimExpiry)
+	res, err := tx.ExecContext(c, query, id, encoded)
+	if err != nil {
+		return fmt.Errorf("error when approving product: %s", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error when approving product: %w", err)
+	}
+	if n == 0 {
+		// The product is read outside the transaction, which ends first in case the database allows only one connection.
+		tx.Rollback()
+		return transitionError(c, s, id, model.StatusPublished)
+	}
+
+	a := model.NewModerationAction(model.ItemProduct, id, model.ActionApprove, by)
+	insert := "INSERT INTO moderator_edits(product_id, moderator_id, original_data, changes) VALUES(?, ?, json(?), json(?))"
+	if _, err := tx.ExecContext(c, insert, id, a.ModeratorID, original, changes); err != nil {
+		return fmt.Errorf("error when recording moderator edit: %s", err)
+	}
+
+	if err := sqliteRecordModerationAction(c, tx, a); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error when committing transaction: %s", err)
+	}
+	return nil
+}
+
+// GetModeratorEdits returns the edits moderators made to the product with the given ID, oldest first.
+func (s SQLiteProductsStore) GetModeratorEdits(c context.Context, productID int) ([]model.ModeratorEdit, error) {
+	query := `SELECT e.id, e.product_id, e.moderator_id, COALESCE(u.email_address, ''), e.original_data, e.changes, e.created_at
+		FROM moderator_edits e LEFT JOIN users u ON u.id = e.moderator_id
+		WHERE e.product_id = ? ORDER BY e.id`
+
+	rows, err := s.db.QueryContext(c, query, productID)
+	if err != nil {
+		return nil, fmt.Errorf("error when querying moderator edits: %s", err)
+	}
+	defer rows.Close()
+
+	var edits []model.ModeratorEdit
+	for rows.Next() {
+		var e model.ModeratorEdit
+		if err := rows.Scan(&e.ID, &e.ProductID, &e.ModeratorID, &e.ModeratorEmail, jsonColumn{&e.Original}, jsonColumn{&e.Changes}, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error when scanning moderator edit: %s", err)
+		}
+		edits = append(edits, e)
+	}
+	return edits, rows.Err()
+}
@@ -0,0 +1,237 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/mikolysz/enably/model"
+)
+
+// SQLiteProductsStore is a ProductsStore that uses an SQLite database.
+// Product data is stored as JSON text.
+type SQLiteProductsStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteProductsStore returns an SQLiteProductsStore using the given database.
+func NewSQLiteProductsStore(db *sql.DB) *SQLiteProductsStore {
+	return &SQLiteProductsStore{db}
+}
+
+// sqliteScanProduct scans the productColumns, followed by any extra columns into the given destinations.
+func sqliteScanProduct(row rowScanner, extra ...any) (model.Product, error) {
+	var p model.Product
+	dest := []any{&p.ID, &p.CategorySlug, jsonColumn{&p.Data}, &p.Status, &p.SubmittedBy, &p.SubmittedAt, &p.ResubmittedAt, &p.PublishedAt,
+		&p.RejectedAt, &p.ChangesRequestedAt, &p.WithdrawnAt, &p.DiscontinuedAt, &p.RejectionReason}
+	err := row.Scan(append(dest, extra...)...)
+	return p, err
+}
+
+const sqliteInsertProductQuery = "INSERT INTO products(category_slug, data, submitted_by) VALUES(?, json(?), ?) RETURNING id, status, created_at"
+
+// AddProduct inserts a product into the database.
+// The returned product will have the "id", "status" and "submitted_at" fields filled in.
+func (s SQLiteProductsStore) AddProduct(c context.Context, p model.Product) (model.Product, error) {
+	data, err := toJSONText(p.Data)
+	if err != nil {
+		return model.Product{}, fmt.Errorf("error when encoding product data: %s", err)
+	}
+
+	row := s.db.QueryRowContext(c, sqliteInsertProductQuery, p.CategorySlug, data, p.SubmittedBy)
+	if err := row.Scan(&p.ID, &p.Status, &p.SubmittedAt); err != nil {
+		return model.Product{}, fmt.Errorf("error when inserting product: %s", err)
+	}
+	return p, nil
+}
+
+// AddProducts inserts several products into the database in a single transaction.
+// Either all of them are inserted, or none are.
+// The returned products will have the "id", "status" and "submitted_at" fields filled in.
+func (s SQLiteProductsStore) AddProducts(c context.Context, ps []model.Product) ([]model.Product, error) {
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error when starting transaction: %s", err)
+	}
+	defer tx.Rollback()
+
+	inserted := make([]model.Product, 0, len(ps))
+	for _, p := range ps {
+		data, err := toJSONText(p.Data)
+		if err != nil {
+			return nil, fmt.Errorf("error when encoding product data: %s", err)
+		}
+
+		if err := tx.QueryRowContext(c, sqliteInsertProductQuery, p.CategorySlug, data, p.SubmittedBy).Scan(&p.ID, &p.Status, &p.SubmittedAt); err != nil {
+			return nil, fmt.Errorf("error when inserting product: %s", err)
+		}
+		inserted = append(inserted, p)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error when committing transaction: %s", err)
+	}
+	return inserted, nil
+}
+
+// GetProductsByCategory returns all products in the category with the given slug.
+// ONLY published products are returned.
+func (s SQLiteProductsStore) GetProductsByCategory(c context.Context, slug string) ([]model.Product, error) {
+	query := "SELECT " + productColumns + " FROM products WHERE category_slug = ? AND status = 'published' ORDER BY id"
+	return s.queryProducts(c, query, slug)
+}
+
+// StreamPublishedProducts calls fn for every published product in the category with the given slug,
+// or in all categories if the slug is empty, in order of their IDs.
+// Products are read a page at a time, and fn is only called between queries, so that slow callers don't hold a connection.
+func (s SQLiteProductsStore) StreamPublishedProducts(c context.Context, categorySlug string, fn func(model.Product) error) error {
+	query := "SELECT " + productColumns + ` FROM products WHERE status = 'published' AND (?1 = '' OR category_slug = ?1) AND id > ?2
+		ORDER BY id LIMIT ?3`
+
+	lastID := 0
+	for {
+		page, err := s.queryProducts(c, query, categorySlug, lastID, streamPageSize)
+		if err != nil {
+			return err
+		}
+
+		for _, p := range page {
+			if err := fn(p); err != nil {
+				return err
+			}
+		}
+
+		if len(page) < streamPageSize {
+			return nil
+		}
+		lastID = page[len(page)-1].ID
+	}
+}
+
+// GetProductByID returns the product with the given ID.
+// returns model.ErrProductNotFound if there's no such product.
+func (s SQLiteProductsStore) GetProductByID(c context.Context, id int) (model.Product, error) {
+	query := "SELECT " + productColumns + " FROM products WHERE id = ?"
+
+	p, err := sqliteScanProduct(s.db.QueryRowContext(c, query, id))
+	if err == sql.ErrNoRows {
+		return model.Product{}, model.ErrProductNotFound
+	}
+
+	if err != nil {
+		return model.Product{}, fmt.Errorf("error when querying product: %s", err)
+	}
+
+	return p, nil
+}
+
+// GetProductsBySubmitter returns all products submitted by the user with the given ID, newest first, whatever their status.
+func (s SQLiteProductsStore) GetProductsBySubmitter(c context.Context, userID int64) ([]model.Product, error) {
+	query := "SELECT " + productColumns + " FROM products WHERE submitted_by = ? ORDER BY id DESC"
+	return s.queryProducts(c, query, userID)
+}
+
+func (s SQLiteProductsStore) queryProducts(c context.Context, query string, args ...any) ([]model.Product, error) {
+	rows, err := s.db.QueryContext(c, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error when querying products: %s", err)
+	}
+	defer rows.Close()
+
+	var products []model.Product
+	for rows.Next() {
+		p, err := sqliteScanProduct(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error when scanning product: %s", err)
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
+
+// ApproveProduct publishes the product with the given ID, recording who did it.
+func (s SQLiteProductsStore) ApproveProduct(c context.Context, id int, by model.Moderator) error {
+	query := `UPDATE products SET status = 'published', published_at = CURRENT_TIMESTAMP, claimed_by = NULL, claimed_at = NULL,
+		updated_at = CURRENT_TIMESTAMP WHERE id = ? AND ` + statusCanBecome(model.StatusPublished) + " AND " + claimAllows(by, sqliteClaimExpiry)
+	return s.transition(c, query, model.StatusPublished, model.NewModerationAction(model.ItemProduct, id, model.ActionApprove, by))
+}
+
+// RejectProduct rejects the product with the given ID for the given reason, recording who did it.
+// The product is kept, so that the submitter can see why it was rejected.
+func (s SQLiteProductsStore) RejectProduct(c context.Context, id int, reason string, by model.Moderator) error {
+	query := `UPDATE products SET status = 'rejected', rejected_at = CURRENT_TIMESTAMP, rejection_reason = ?2, claimed_by = NULL, claimed_at = NULL,
+		updated_at = CURRENT_TIMESTAMP WHERE id = ?1 AND ` + statusCanBecome(model.StatusRejected) + " AND " + claimAllows(by, sqliteClaimExpiry)
+	return s.transition(c, query, model.StatusRejected, model.NewModerationAction(model.ItemProduct, id, model.ActionReject, by), reason)
+}
+
+// DiscontinueProduct marks the product with the given ID as discontinued, recording who did it.
+func (s SQLiteProductsStore) DiscontinueProduct(c context.Context, id int, by model.Moderator) error {
+	query := "UPDATE products SET status = 'discontinued', discontinued_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND " +
+		statusCanBecome(model.StatusDiscontinued)
+	return s.transition(c, query, model.StatusDiscontinued, model.NewModerationAction(model.ItemProduct, id, model.ActionDiscontinue, by))
+}
+
+// WithdrawProduct marks the product with the given ID as withdrawn by its submitter.
+func (s SQLiteProductsStore) WithdrawProduct(c context.Context, id int) error {
+	query := `UPDATE products SET status = 'withdrawn', withdrawn_at = CURRENT_TIMESTAMP, claimed_by = NULL, claimed_at = NULL,
+		updated_at = CURRENT_TIMESTAMP WHERE id = ? AND ` + statusCanBecome(model.StatusWithdrawn)
+
+	res, err := s.db.ExecContext(c, query, id)
+	if err != nil {
+		return fmt.Errorf("error when withdrawing product: %s", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error when withdrawing product: %w", err)
+	}
+	if n == 0 {
+		return transitionError(c, s, id, model.StatusWithdrawn)
+	}
+	return nil
+}
+
+// transition runs a statement changing the status of a product to the given status with moderate.
+// If it changes nothing, the error explains why.
+func (s SQLiteProductsStore) transition(c context.Context, query string, to model.ProductStatus, a model.ModerationAction, args ...any) error {
+	err := s.moderate(c, query, a, args...)
+	if errors.Is(err, model.ErrItemNotFound) {
+		return transitionError(c, s, a.ItemID, to)
+	}
+	return err
+}
+
+// CountProductsByCategory returns the number of published and pending products in each category.
+// Products with other statuses aren't counted, and categories without any products are omitted.
+func (s SQLiteProductsStore) CountProductsByCategory(c context.Context) (map[string]model.ProductCounts, error) {
+	query := `SELECT category_slug, status, COUNT(*) FROM products
+		WHERE status IN ('published', 'pending') GROUP BY category_slug, status`
+
+	rows, err := s.db.QueryContext(c, query)
+	if err != nil {
+		return nil, fmt.Errorf("error when counting products: %s", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]model.ProductCounts)
+	for rows.Next() {
+		var (
+			slug   string
+			status model.ProductStatus
+			count  int
+		)
+		if err := rows.Scan(&slug, &status, &count); err != nil {
+			return nil, fmt.Errorf("error when scanning product count: %s", err)
+		}
+
+		cnt := counts[slug]
+		if status == model.StatusPublished {
+			cnt.Approved = count
+		} else {
+			cnt.Pending = count
+		}
+		counts[slug] = cnt
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,99 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mikolysz/enably/model"
+)
+
+// GetModerationQueue returns a page of the products waiting for a moderator's decision which match the given query.
+func (s SQLiteProductsStore) GetModerationQueue(c context.Context, q model.QueueQuery) (model.QueuePage, error) {
+	where, orderBy, args := queueFilter(q, func(n int) string { return "?" })
+
+	var page model.QueuePage
+	if err := s.db.QueryRowContext(c, "SELECT COUNT(*) FROM products"+where, args...).Scan(&page.Total); err != nil {
+		return model.QueuePage{}, fmt.Errorf("error when counting products in the moderation queue: %s", err)
+	}
+
+	query := "SELECT " + productColumns + ", " + queueColumns + " FROM products" + where + orderBy + " LIMIT ? OFFSET ?"
+	rows, err := s.db.QueryContext(c, query, append(args, q.Limit, q.Offset)...)
+	if err != nil {
+		return model.QueuePage{}, fmt.Errorf("error when querying the moderation queue: %s", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r queueRow
+		p, err := sqliteScanProduct(rows, r.dest()...)
+		if err != nil {
+			return model.QueuePage{}, fmt.Errorf("error when scanning product: %s", err)
+		}
+		page.Items = append(page.Items, r.item(p))
+	}
+	return page, rows.Err()
+}
+
+// ClaimProduct marks the product with the given ID as being reviewed by the user with the given ID.
+// Claims made before expiredBefore are overridden, other claims by other moderators result in model.ErrProductClaimed.
+func (s SQLiteProductsStore) ClaimProduct(c context.Context, id int, userID int64, expiredBefore time.Time) error {
+	query := `UPDATE products SET claimed_by = ?2, claimed_at = CURRENT_TIMESTAMP
+		WHERE id = ?1 AND (claimed_by IS NULL OR claimed_by = ?2 OR claimed_at < ?3)`
+
+	res, err := s.db.ExecContext(c, query, id, userID, sqliteTime(expiredBefore))
+	if err != nil {
+		return fmt.Errorf("error when claiming product: %s", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error when claiming product: %w", err)
+	}
+	if n == 0 {
+		return model.ErrProductClaimed
+	}
+	return nil
+}
+
+// ReleaseProductClaim removes the claim of the user with the given ID on the product with the given ID, if they have one.
+func (s SQLiteProductsStore) ReleaseProductClaim(c context.Context, id int, userID int64) error {
+	query := "UPDATE products SET claimed_by = NULL, claimed_at = NULL WHERE id = ? AND claimed_by = ?"
+	if _, err := s.db.ExecContext(c, query, id, userID); err != nil {
+		return fmt.Errorf("error when releasing claim: %s", err)
+	}
+	return nil
+}
+
+// AddModeratorNote adds an internal note about a product.
+// The returned note will have the "id" and "created_at" fields filled in.
+func (s SQLiteProductsStore) AddModeratorNote(c context.Context, n model.ModeratorNote) (model.ModeratorNote, error) {
+	query := "INSERT INTO moderator_notes(product_id, author_id, text) VALUES(?, ?, ?) RETURNING id, created_at"
+	if err := s.db.QueryRowContext(c, query, n.ProductID, n.AuthorID, n.Text).Scan(&n.ID, &n.CreatedAt); err != nil {
+		return model.ModeratorNote{}, fmt.Errorf("error when inserting moderator note: %s", err)
+	}
+	return n, nil
+}
+
+// GetModeratorNotes returns the internal notes about the product with the given ID, oldest first.
+func (s SQLiteProductsStore) GetModeratorNotes(c context.Context, productID int) ([]model.ModeratorNote, error) {
+	query := `SELECT n.id, n.product_id, n.author_id, COALESCE(u.email_address, ''), n.text, n.created_at
+		FROM moderator_notes n LEFT JOIN users u ON u.id = n.author_id
+		WHERE n.product_id = ? ORDER BY n.id`
+
+	rows, err := s.db.QueryContext(c, query, productID)
+	if err != nil {
+		return nil, fmt.Errorf("error when querying moderator notes: %s", err)
+	}
+	defer rows.Close()
+
+	var notes []model.ModeratorNote
+	for rows.Next() {
+		var n model.ModeratorNote
+		if err := rows.Scan(&n.ID, &n.ProductID, &n.AuthorID, &n.AuthorEmail, &n.Text, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error when scanning moderator note: %s", err)
+		}
+		notes = append(notes, n)
+	}
+	return notes, rows.Err()
+}
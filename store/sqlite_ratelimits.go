@@ -0,0 +1,37 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SQLiteRateLimitStore keeps rate limit counts in an SQLite database.
+type SQLiteRateLimitStore struct {
+	DB *sql.DB
+}
+
+// Hit counts a request under the given key, starting a new window which ends at resetsAt if the current one is over.
+func (s SQLiteRateLimitStore) Hit(c context.Context, key string, now, resetsAt time.Time) (int, time.Time, error) {
+	query := `INSERT INTO rate_limits(key, hits, resets_at) VALUES(?1, 1, ?2)
+		ON CONFLICT (key) DO UPDATE SET
+			hits = CASE WHEN rate_limits.resets_at <= ?3 THEN 1 ELSE rate_limits.hits + 1 END,
+			resets_at = CASE WHEN rate_limits.resets_at <= ?3 THEN excluded.resets_at ELSE rate_limits.resets_at END
+		RETURNING hits, resets_at`
+
+	var hits int
+	var windowEnd time.Time
+	if err := s.DB.QueryRowContext(c, query, key, sqliteTime(resetsAt), sqliteTime(now)).Scan(&hits, &windowEnd); err != nil {
+		return 0, time.Time{}, fmt.Errorf("error when counting request: %s", err)
+	}
+	return hits, windowEnd, nil
+}
+
+// DeleteExpiredRateLimits deletes the counts of the windows which ended before the given time.
+func (s SQLiteRateLimitStore) DeleteExpiredRateLimits(c context.Context, now time.Time) error {
+	if _, err := s.DB.ExecContext(c, "DELETE FROM rate_limits WHERE resets_at <= ?", sqliteTime(now)); err != nil {
+		return fmt.Errorf("error when deleting expired rate limits: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/mikolysz/enably/model"
+)
+
+// SQLiteTokenStore lets you store and retrieve tokens in an SQLite database.
+type SQLiteTokenStore struct {
+	DB *sql.DB
+}
+
+// AddLoginToken inserts a login token into the database.
+// The returned token will have the "id" and "created_at" fields filled in.
+func (s SQLiteTokenStore) AddLoginToken(c context.Context, t model.LoginToken) (model.LoginToken, error) {
+	query := "INSERT INTO login_tokens(email_address, token, expires_at, redirect_uri) VALUES(?, ?, ?, ?) RETURNING id, created_at"
+	row := s.DB.QueryRowContext(c, query, t.Email, t.Token, sqliteTime(t.ExpiresAt), t.RedirectURI)
+	if err := row.Scan(&t.ID, &t.CreatedAt); err != nil {
+		return model.LoginToken{}, fmt.Errorf("error when inserting login token: %s", err)
+	}
+	return t, nil
+}
+
+// TakeLoginToken deletes the login token with the given contents and returns it.
+// returns model.ErrExpiredLoginToken if the token does not exist.
+func (s SQLiteTokenStore) TakeLoginToken(c context.Context, token string) (model.LoginToken, error) {
+	query := "DELETE FROM login_tokens WHERE token = ? RETURNING id, email_address, token, created_at, expires_at, redirect_uri"
+
+	var t model.LoginToken
+	row := s.DB.QueryRowContext(c, query, token)
+	err := row.Scan(&t.ID, &t.Email, &t.Token, &t.CreatedAt, &t.ExpiresAt, &t.RedirectURI)
+
+	if err == sql.ErrNoRows {
+		return model.LoginToken{}, model.ErrExpiredLoginToken
+	}
+
+	if err != nil {
+		return model.LoginToken{}, fmt.Errorf("error when deleting login token: %s", err)
+	}
+
+	return t, nil
+}
+
+// AddSession inserts a session into the database.
+// The returned session will have the "id" and "created_at" fields filled in.
+func (s SQLiteTokenStore) AddSession(c context.Context, session model.Session) (model.Session, error) {
+	query := "INSERT INTO session_tokens(email_address, token, expires_at, last_used_at, user_agent) VALUES(?, ?, ?, ?, ?) RETURNING id, created_at"
+	row := s.DB.QueryRowContext(c, query, session.Email, session.Token, sqliteTime(session.ExpiresAt), sqliteTime(session.LastUsedAt), session.UserAgent)
+	if err := row.Scan(&session.ID, &session.CreatedAt); err != nil {
+		return model.Session{}, fmt.Errorf("error when inserting session: %s", err)
+	}
+	return session, nil
+}
+
+// GetSessionByToken returns the session with the given token.
+// returns model.ErrInvalidToken if the session does not exist.
+func (s SQLiteTokenStore) GetSessionByToken(c context.Context, token string) (model.Session, error) {
+	query := "SELECT id, email_address, token, created_at, expires_at, last_used_at, user_agent, revoked_at FROM session_tokens WHERE token = ?"
+
+	var session model.Session
+	row := s.DB.QueryRowContext(c, query, token)
+	err := row.Scan(&session.ID, &session.Email, &session.Token, &session.CreatedAt, &session.ExpiresAt, &session.LastUsedAt, &session.UserAgent, &session.RevokedAt)
+
+	if err == sql.ErrNoRows {
+		return model.Session{}, model.ErrInvalidToken
+	}
+
+	if err != nil {
+		return model.Session{}, fmt.Errorf("error when scanning session: %s", err)
+	}
+
+	return session, nil
+}
+
+// ExtendSession records that the session with the given ID was used, and pushes back its expiry.
+func (s SQLiteTokenStore) ExtendSession(c context.Context, id int64, lastUsedAt, expiresAt time.Time) error {
+	query := "UPDATE session_tokens SET last_used_at = ?, expires_at = ? WHERE id = ?"
+	if _, err := s.DB.ExecContext(c, query, sqliteTime(lastUsedAt), sqliteTime(expiresAt), id); err != nil {
+		return fmt.Errorf("error when extending session: %s", err)
+	}
+	return nil
+}
+
+// ListSessions returns the sessions of the given user which are neither expired nor revoked, most recently used first.
+func (s SQLiteTokenStore) ListSessions(c context.Context, email string, now time.Time) ([]model.Session, error) {
+	query := `SELECT id, email_address, created_at, expires_at, last_used_at, user_agent FROM session_tokens
+		WHERE email_address = ? AND expires_at >= ? AND revoked_at IS NULL ORDER BY last_used_at DESC, id DESC`
+
+	rows, err := s.DB.QueryContext(c, query, email, sqliteTime(now))
+	if err != nil {
+		return nil, fmt.Errorf("error when querying sessions: %s", err)
+	}
+	defer rows.Close()
+
+	var sessions []model.Session
+	for rows.Next() {
+		var session model.Session
+		if err := rows.Scan(&session.ID, &session.Email, &session.CreatedAt, &session.ExpiresAt, &session.LastUsedAt, &session.UserAgent); err != nil {
+			return nil, fmt.Errorf("error when scanning session: %s", err)
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// RevokeSession ends the session with the given ID, as long as it belongs to the given user.
+// returns model.ErrSessionNotFound if there's no such session, or it has already been revoked.
+func (s SQLiteTokenStore) RevokeSession(c context.Context, id int64, email string, now time.Time) error {
+	query := "UPDATE session_tokens SET revoked_at = ? WHERE id = ? AND email_address = ? AND revoked_at IS NULL"
+	res, err := s.DB.ExecContext(c, query, sqliteTime(now), id, email)
+	if err != nil {
+		return fmt.Errorf("error when revoking session: %s", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error when revoking session: %w", err)
+	}
+	if n == 0 {
+		return model.ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeOtherSessions ends all sessions of the given user, except the one with the given ID.
+func (s SQLiteTokenStore) RevokeOtherSessions(c context.Context, email string, exceptID int64, now time.Time) error {
+	query := "UPDATE session_tokens SET revoked_at = ? WHERE email_address = ? AND id <> ? AND revoked_at IS NULL"
+	if _, err := s.DB.ExecContext(c, query, sqliteTime(now), email, exceptID); err != nil {
+		return fmt.Errorf("error when revoking sessions: %s", err)
+	}
+	return nil
+}
+
+// DeleteExpiredTokens deletes all login tokens and sessions which expired before the given time, and all revoked sessions.
+func (s SQLiteTokenStore) DeleteExpiredTokens(c context.Context, now time.Time) error {
+	if _, err := s.DB.ExecContext(c, "DELETE FROM login_tokens WHERE expires_at < ?", sqliteTime(now)); err != nil {
+		return fmt.Errorf("error when deleting expired login tokens: %s", err)
+	}
+
+	if _, err := s.DB.ExecContext(c, "DELETE FROM session_tokens WHERE expires_at < ? OR revoked_at IS NOT NULL", sqliteTime(now)); err != nil {
+		return fmt.Errorf("error when deleting expired sessions: %s", err)
+	}
+	return nil
+}
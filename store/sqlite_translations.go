@@ -0,0 +1,88 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mikolysz/enably/model"
+)
+
+// AddTranslation inserts a product translation into the database.
+// The returned translation will have the "id" and "created_at" fields filled in.
+func (s SQLiteProductsStore) AddTranslation(c context.Context, t model.Translation) (model.Translation, error) {
+	data, err := toJSONText(t.Data)
+	if err != nil {
+		return model.Translation{}, fmt.Errorf("error when encoding translation data: %s", err)
+	}
+
+	query := "INSERT INTO product_translations(product_id, locale, data, submitted_by) VALUES(?, ?, json(?), ?) RETURNING id, created_at"
+	row := s.db.QueryRowContext(c, query, t.ProductID, t.Locale, data, t.SubmittedBy)
+	if err := row.Scan(&t.ID, &t.CreatedAt); err != nil {
+		return model.Translation{}, fmt.Errorf("error when inserting translation: %s", err)
+	}
+	return t, nil
+}
+
+// GetApprovedTranslations returns the approved translations of the given products into the given locale.
+// Translations are ordered from oldest to newest.
+func (s SQLiteProductsStore) GetApprovedTranslations(c context.Context, productIDs []int, locale string) ([]model.Translation, error) {
+	ids, err := toJSONText(productIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error when encoding product IDs: %s", err)
+	}
+
+	// SQLite can't bind arrays, so the IDs are passed as a JSON array instead.
+	query := "SELECT " + translationColumns + ` FROM product_translations
+		WHERE product_id IN (SELECT value FROM json_each(?)) AND locale = ? AND approved = true ORDER BY id`
+	return s.queryTranslations(c, query, ids, locale)
+}
+
+// GetTranslationsRequiringApproval returns all translations that need approval by the mod team.
+func (s SQLiteProductsStore) GetTranslationsRequiringApproval(c context.Context) ([]model.Translation, error) {
+	query := "SELECT " + translationColumns + " FROM product_translations WHERE approved = false ORDER BY id"
+	return s.queryTranslations(c, query)
+}
+
+// GetTranslationByID returns the translation with the given ID, or model.ErrItemNotFound if there's none.
+func (s SQLiteProductsStore) GetTranslationByID(c context.Context, id int) (model.Translation, error) {
+	query := "SELECT " + translationColumns + " FROM product_translations WHERE id = ?"
+	ts, err := s.queryTranslations(c, query, id)
+	if err != nil {
+		return model.Translation{}, err
+	}
+
+	if len(ts) == 0 {
+		return model.Translation{}, model.ErrItemNotFound
+	}
+	return ts[0], nil
+}
+
+func (s SQLiteProductsStore) queryTranslations(c context.Context, query string, args ...any) ([]model.Translation, error) {
+	rows, err := s.db.QueryContext(c, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error when querying translations: %s", err)
+	}
+	defer rows.Close()
+
+	var translations []model.Translation
+	for rows.Next() {
+		var t model.Translation
+		if err := rows.Scan(&t.ID, &t.ProductID, &t.Locale, jsonColumn{&t.Data}, &t.Approved, &t.CreatedAt, &t.SubmittedBy); err != nil {
+			return nil, fmt.Errorf("error when scanning translation: %s", err)
+		}
+		translations = append(translations, t)
+	}
+	return translations, rows.Err()
+}
+
+// ApproveTranslation approves the translation with the given ID, recording who did it.
+func (s SQLiteProductsStore) ApproveTranslation(c context.Context, id int, by model.Moderator) error {
+	query := "UPDATE product_translations SET approved = true WHERE id = ?"
+	return s.moderate(c, query, model.NewModerationAction(model.ItemTranslation, id, model.ActionApprove, by))
+}
+
+// RejectTranslation rejects the translation with the given ID, recording who did it.
+func (s SQLiteProductsStore) RejectTranslation(c context.Context, id int, by model.Moderator) error {
+	query := "DELETE FROM product_translations WHERE id = ?"
+	return s.moderate(c, query, model.NewModerationAction(model.ItemTranslation, id, model.ActionReject, by))
+}
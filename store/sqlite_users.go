@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/mikolysz/enably/model"
+)
+
+// SQLiteUsersStore lets you store and retrieve user accounts in an SQLite database.
+type SQLiteUsersStore struct {
+	DB *sql.DB
+}
+
+// GetOrCreateUser returns the user with the given email address, creating one if there's none.
+func (s SQLiteUsersStore) GetOrCreateUser(c context.Context, email string) (model.User, error) {
+	// The no-op update makes RETURNING work for existing users too.
+	query := `INSERT INTO users(email_address) VALUES(?)
+		ON CONFLICT (email_address) DO UPDATE SET email_address = excluded.email_address
+		RETURNING ` + userColumns
+
+	u, err := scanUser(s.DB.QueryRowContext(c, query, email))
+	if err != nil {
+		return model.User{}, fmt.Errorf("error when inserting user: %s", err)
+	}
+	return u, nil
+}
+
+// GetUserByEmail returns the user with the given email address.
+// returns model.ErrUserNotFound if there's no such user.
+func (s SQLiteUsersStore) GetUserByEmail(c context.Context, email string) (model.User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE email_address = ?"
+
+	u, err := scanUser(s.DB.QueryRowContext(c, query, email))
+	if err == sql.ErrNoRows {
+		return model.User{}, model.ErrUserNotFound
+	}
+
+	if err != nil {
+		return model.User{}, fmt.Errorf("error when querying user: %s", err)
+	}
+	return u, nil
+}
+
+// GetUserByID returns the user with the given ID.
+// returns model.ErrUserNotFound if there's no such user.
+func (s SQLiteUsersStore) GetUserByID(c context.Context, id int64) (model.User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE id = ?"
+
+	u, err := scanUser(s.DB.QueryRowContext(c, query, id))
+	if err == sql.ErrNoRows {
+		return model.User{}, model.ErrUserNotFound
+	}
+
+	if err != nil {
+		return model.User{}, fmt.Errorf("error when querying user: %s", err)
+	}
+	return u, nil
+}
+
+// UpdateUser saves the profile of the given user.
+func (s SQLiteUsersStore) UpdateUser(c context.Context, u model.User) (model.User, error) {
+	var onboardedAt *string
+	if u.OnboardedAt != nil {
+		t := sqliteTime(*u.OnboardedAt)
+		onboardedAt = &t
+	}
+
+	query := "UPDATE users SET display_name = ?, email_consent = ?, moderation_emails = ?, onboarded_at = ? WHERE id = ? RETURNING " + userColumns
+
+	u, err := scanUser(s.DB.QueryRowContext(c, query, u.DisplayName, u.EmailConsent, u.ModerationEmails, onboardedAt, u.ID))
+	if err != nil {
+		return model.User{}, fmt.Errorf("error when updating user: %s", err)
+	}
+	return u, nil
+}
+
+// SetUserRole changes the role of the user with the given email address.
+// returns model.ErrUserNotFound if there's no such user.
+func (s SQLiteUsersStore) SetUserRole(c context.Context, email string, role model.Role) (model.User, error) {
+	query := "UPDATE users SET role = ? WHERE email_address = ? RETURNING " + userColumns
+
+	u, err := scanUser(s.DB.QueryRowContext(c, query, role, email))
+	if err == sql.ErrNoRows {
+		return model.User{}, model.ErrUserNotFound
+	}
+
+	if err != nil {
+		return model.User{}, fmt.Errorf("error when updating user role: %s", err)
+	}
+	return u, nil
+}
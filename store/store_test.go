@@ -0,0 +1,133 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/mikolysz/enably"
+	"github.com/mikolysz/enably/app"
+)
+
+// The tests in this package are run against every backend, so that all of them behave the same way.
+
+// postgresTestEnv names the environment variable with the connection string of the Postgres database used by the tests.
+// The tests drop everything in it, so it mustn't be used for anything else. The Postgres tests are skipped if it isn't set.
+const postgresTestEnv = "TEST_DB_CONNECTION_STRING"
+
+// testStores are the stores of a single, empty database.
+type testStores struct {
+	products   app.ProductsStore
+	tokens     app.TokenStore
+	users      app.UsersStore
+	rateLimits app.RateLimitStore
+
+	// migrator is nil for the memory stores, which don't need migrating.
+	migrator *Migrator
+}
+
+var testBackends = []struct {
+	name string
+	open func(t *testing.T) testStores
+}{
+	{"sqlite", openSQLiteTestStores},
+	{"postgres", openPostgresTestStores},
+	{"memory", openMemoryTestStores},
+}
+
+// forEachBackend runs fn as a subtest for every backend, giving it a fresh database each time.
+func forEachBackend(t *testing.T, fn func(t *testing.T, s testStores)) {
+	for _, b := range testBackends {
+		open := b.open
+		t.Run(b.name, func(t *testing.T) {
+			fn(t, open(t))
+		})
+	}
+}
+
+func openSQLiteTestStores(t *testing.T) testStores {
+	db, err := OpenSQLiteDB(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	// Every connection to ":memory:" opens a separate database.
+	db.SetMaxOpenConns(1)
+
+	m := newTestMigrator(t, db, "sqlite", NewSQLiteMigrator)
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return testStores{
+		products:   NewSQLiteProductsStore(db),
+		tokens:     SQLiteTokenStore{DB: db},
+		users:      SQLiteUsersStore{DB: db},
+		rateLimits: SQLiteRateLimitStore{DB: db},
+		migrator:   m,
+	}
+}
+
+func openPostgresTestStores(t *testing.T) testStores {
+	connString := os.Getenv(postgresTestEnv)
+	if connString == "" {
+		t.Skipf("%s is not set", postgresTestEnv)
+	}
+
+	db, err := OpenPostgresDB(connString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	// Start from an empty database, whatever the previous test left in it.
+	c := context.Background()
+	if _, err := db.ExecContext(c, "DROP SCHEMA public CASCADE; CREATE SCHEMA public"); err != nil {
+		t.Fatal(err)
+	}
+	m := newTestMigrator(t, db, "postgres", NewPostgresMigrator)
+	if _, err := m.Up(c); err != nil {
+		t.Fatal(err)
+	}
+
+	pool, err := pgxpool.New(c, connString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+
+	return testStores{
+		products:   NewPostgresProductsStore(pool),
+		tokens:     PostgresTokenStore{DB: pool},
+		users:      PostgresUsersStore{DB: pool},
+		rateLimits: PostgresRateLimitStore{DB: pool},
+		migrator:   m,
+	}
+}
+
+func openMemoryTestStores(t *testing.T) testStores {
+	return testStores{
+		products:   NewMemoryProductsStore(),
+		tokens:     NewMemoryTokenStore(),
+		users:      NewMemoryUsersStore(),
+		rateLimits: NewMemoryRateLimitStore(),
+	}
+}
+
+func newTestMigrator(t *testing.T, db *sql.DB, dir string, newMigrator func(*sql.DB, fs.FS) (*Migrator, error)) *Migrator {
+	files, err := fs.Sub(enably.Migrations, "migrations/"+dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := newMigrator(db, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -14,33 +15,131 @@ type PostgresTokenStore struct {
 	DB *pgxpool.Pool
 }
 
-// AddToken 		inserts a token into the database.
-// The returned token will have the "id" field filled in with the ID of the new token.
-func (s PostgresTokenStore) AddToken(c context.Context, t model.Token) (model.Token, error) {
-	query := "INSERT INTO session_tokens(email_address, token) VALUES($1, $2) RETURNING id"
-	row := s.DB.QueryRow(c, query, t.Email, t.Token)
-	if err := row.Scan(&t.ID); err != nil {
-		return model.Token{}, fmt.Errorf("error when inserting token: %s", err)
+// AddLoginToken inserts a login token into the database.
+// The returned token will have the "id" and "created_at" fields filled in.
+func (s PostgresTokenStore) AddLoginToken(c context.Context, t model.LoginToken) (model.LoginToken, error) {
+	query := "INSERT INTO login_tokens(email_address, token, expires_at, redirect_uri) VALUES($1, $2, $3, $4) RETURNING id, created_at"
+	row := s.DB.QueryRow(c, query, t.Email, t.Token, t.ExpiresAt, t.RedirectURI)
+	if err := row.Scan(&t.ID, &t.CreatedAt); err != nil {
+		return model.LoginToken{}, fmt.Errorf("error when inserting login token: %s", err)
 	}
 	return t, nil
 }
 
-// GetByTokenContents 		returns the token with the given contents.
-// returns model.ErrInvalidToken if the token does not exist.
-func (s PostgresTokenStore) GetByTokenContents(c context.Context, token string) (model.Token, error) {
-	query := "SELECT id, email_address, token, created_at FROM session_tokens WHERE token = $1"
+// TakeLoginToken deletes the login token with the given contents and returns it.
+// returns model.ErrExpiredLoginToken if the token does not exist.
+func (s PostgresTokenStore) TakeLoginToken(c context.Context, token string) (model.LoginToken, error) {
+	query := "DELETE FROM login_tokens WHERE token = $1 RETURNING id, email_address, token, created_at, expires_at, redirect_uri"
 
-	var t model.Token
+	var t model.LoginToken
 	row := s.DB.QueryRow(c, query, token)
-	err := row.Scan(&t.ID, &t.Email, &t.Token, &t.CreatedAt)
+	err := row.Scan(&t.ID, &t.Email, &t.Token, &t.CreatedAt, &t.ExpiresAt, &t.RedirectURI)
 
 	if err == pgx.ErrNoRows {
-		return model.Token{}, model.ErrInvalidToken
+		return model.LoginToken{}, model.ErrExpiredLoginToken
 	}
 
 	if err != nil {
-		return model.Token{}, fmt.Errorf("error when scanning token: %s", err)
+		return model.LoginToken{}, fmt.Errorf("error when deleting login token: %s", err)
 	}
 
 	return t, nil
 }
+
+// AddSession inserts a session into the database.
+// The returned session will have the "id" and "created_at" fields filled in.
+func (s PostgresTokenStore) AddSession(c context.Context, session model.Session) (model.Session, error) {
+	query := "INSERT INTO session_tokens(email_address, token, expires_at, last_used_at, user_agent) VALUES($1, $2, $3, $4, $5) RETURNING id, created_at"
+	row := s.DB.QueryRow(c, query, session.Email, session.Token, session.ExpiresAt, session.LastUsedAt, session.UserAgent)
+	if err := row.Scan(&session.ID, &session.CreatedAt); err != nil {
+		return model.Session{}, fmt.Errorf("error when inserting session: %s", err)
+	}
+	return session, nil
+}
+
+// GetSessionByToken returns the session with the given token.
+// returns model.ErrInvalidToken if the session does not exist.
+func (s PostgresTokenStore) GetSessionByToken(c context.Context, token string) (model.Session, error) {
+	query := "SELECT id, email_address, token, created_at, expires_at, last_used_at, user_agent, revoked_at FROM session_tokens WHERE token = $1"
+
+	var session model.Session
+	row := s.DB.QueryRow(c, query, token)
+	err := row.Scan(&session.ID, &session.Email, &session.Token, &session.CreatedAt, &session.ExpiresAt, &session.LastUsedAt, &session.UserAgent, &session.RevokedAt)
+
+	if err == pgx.ErrNoRows {
+		return model.Session{}, model.ErrInvalidToken
+	}
+
+	if err != nil {
+		return model.Session{}, fmt.Errorf("error when scanning session: %s", err)
+	}
+
+	return session, nil
+}
+
+// ExtendSession records that the session with the given ID was used, and pushes back its expiry.
+func (s PostgresTokenStore) ExtendSession(c context.Context, id int64, lastUsedAt, expiresAt time.Time) error {
+	query := "UPDATE session_tokens SET last_used_at = $2, expires_at = $3 WHERE id = $1"
+	if _, err := s.DB.Exec(c, query, id, lastUsedAt, expiresAt); err != nil {
+		return fmt.Errorf("error when extending session: %s", err)
+	}
+	return nil
+}
+
+// ListSessions returns the sessions of the given user which are neither expired nor revoked, most recently used first.
+func (s PostgresTokenStore) ListSessions(c context.Context, email string, now time.Time) ([]model.Session, error) {
+	query := `SELECT id, email_address, created_at, expires_at, last_used_at, user_agent FROM session_tokens
+		WHERE email_address = $1 AND expires_at >= $2 AND revoked_at IS NULL ORDER BY last_used_at DESC, id DESC`
+
+	rows, err := s.DB.Query(c, query, email, now)
+	if err != nil {
+		return nil, fmt.Errorf("error when querying sessions: %s", err)
+	}
+	defer rows.Close()
+
+	var sessions []model.Session
+	for rows.Next() {
+		var session model.Session
+		if err := rows.Scan(&session.ID, &session.Email, &session.CreatedAt, &session.ExpiresAt, &session.LastUsedAt, &session.UserAgent); err != nil {
+			return nil, fmt.Errorf("error when scanning session: %s", err)
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// RevokeSession ends the session with the given ID, as long as it belongs to the given user.
+// returns model.ErrSessionNotFound if there's no such session, or it has already been revoked.
+func (s PostgresTokenStore) RevokeSession(c context.Context, id int64, email string, now time.Time) error {
+	query := "UPDATE session_tokens SET revoked_at = $3 WHERE id = $1 AND email_address = $2 AND revoked_at IS NULL"
+	tag, err := s.DB.Exec(c, query, id, email, now)
+	if err != nil {
+		return fmt.Errorf("error when revoking session: %s", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return model.ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeOtherSessions ends all sessions of the given user, except the one with the given ID.
+func (s PostgresTokenStore) RevokeOtherSessions(c context.Context, email string, exceptID int64, now time.Time) error {
+	query := "UPDATE session_tokens SET revoked_at = $3 WHERE email_address = $1 AND id <> $2 AND revoked_at IS NULL"
+	if _, err := s.DB.Exec(c, query, email, exceptID, now); err != nil {
+		return fmt.Errorf("error when revoking sessions: %s", err)
+	}
+	return nil
+}
+
+// DeleteExpiredTokens deletes all login tokens and sessions which expired before the given time, and all revoked sessions.
+func (s PostgresTokenStore) DeleteExpiredTokens(c context.Context, now time.Time) error {
+	if _, err := s.DB.Exec(c, "DELETE FROM login_tokens WHERE expires_at < $1", now); err != nil {
+		return fmt.Errorf("error when deleting expired login tokens: %s", err)
+	}
+
+	if _, err := s.DB.Exec(c, "DELETE FROM session_tokens WHERE expires_at < $1 OR revoked_at IS NOT NULL", now); err != nil {
+		return fmt.Errorf("error when deleting expired sessions: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mikolysz/enably/model"
+)
+
+func TestLoginTokens(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s testStores) {
+		c := context.Background()
+
+		token := model.NewLoginToken("user@example.com", time.Hour)
+		token.RedirectURI = "/submit"
+		added, err := s.tokens.AddLoginToken(c, *token)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		taken, err := s.tokens.TakeLoginToken(c, added.Token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if taken.Email != token.Email || taken.RedirectURI != token.RedirectURI {
+			t.Errorf("TakeLoginToken returned %+v, want %+v", taken, token)
+		}
+
+		if _, err := s.tokens.TakeLoginToken(c, added.Token); !errors.Is(err, model.ErrExpiredLoginToken) {
+			t.Errorf("taking a login token twice returned %v, want %v", err, model.ErrExpiredLoginToken)
+		}
+
+		expiring, err := s.tokens.AddLoginToken(c, *model.NewLoginToken("user@example.com", time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.tokens.DeleteExpiredTokens(c, time.Now().Add(2*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.tokens.TakeLoginToken(c, expiring.Token); !errors.Is(err, model.ErrExpiredLoginToken) {
+			t.Errorf("taking a deleted login token returned %v, want %v", err, model.ErrExpiredLoginToken)
+		}
+	})
+}
+
+func TestSessions(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s testStores) {
+		c := context.Background()
+		email := "user@example.com"
+		now := time.Now().Truncate(time.Second)
+
+		var sessions []model.Session
+		for _, userAgent := range []string{"Firefox", "Chrome", "Safari"} {
+			session, err := s.tokens.AddSession(c, *model.NewSession(email, userAgent, time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			sessions = append(sessions, session)
+		}
+		firefox, chrome, safari := sessions[0], sessions[1], sessions[2]
+
+		got, err := s.tokens.GetSessionByToken(c, chrome.Token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != chrome.ID || got.Email != email || got.UserAgent != "Chrome" {
+			t.Errorf("GetSessionByToken returned %+v, want %+v", got, chrome)
+		}
+		if _, err := s.tokens.GetSessionByToken(c, "no-such-token"); !errors.Is(err, model.ErrInvalidToken) {
+			t.Errorf("GetSessionByToken of a missing session returned %v, want %v", err, model.ErrInvalidToken)
+		}
+
+		lastUsedAt, expiresAt := now.Add(time.Minute), now.Add(2*time.Hour)
+		if err := s.tokens.ExtendSession(c, firefox.ID, lastUsedAt, expiresAt); err != nil {
+			t.Fatal(err)
+		}
+		got, err = s.tokens.GetSessionByToken(c, firefox.Token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.LastUsedAt.Equal(lastUsedAt) || !got.ExpiresAt.Equal(expiresAt) {
+			t.Errorf("after ExtendSession, the session was last used at %s and expires at %s, want %s and %s",
+				got.LastUsedAt, got.ExpiresAt, lastUsedAt, expiresAt)
+		}
+
+		if err := s.tokens.RevokeSession(c, chrome.ID, "other@example.com", now); !errors.Is(err, model.ErrSessionNotFound) {
+			t.Errorf("revoking another user's session returned %v, want %v", err, model.ErrSessionNotFound)
+		}
+		if err := s.tokens.RevokeSession(c, chrome.ID, email, now); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.tokens.RevokeSession(c, chrome.ID, email, now); !errors.Is(err, model.ErrSessionNotFound) {
+			t.Errorf("revoking a session twice returned %v, want %v", err, model.ErrSessionNotFound)
+		}
+
+		// The most recently used sessions are listed first.
+		listed, err := s.tokens.ListSessions(c, email, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := sessionIDs(listed); !reflect.DeepEqual(ids, []int64{firefox.ID, safari.ID}) {
+			t.Errorf("ListSessions returned sessions %v, want [%d %d]", ids, firefox.ID, safari.ID)
+		}
+
+		if err := s.tokens.RevokeOtherSessions(c, email, firefox.ID, now); err != nil {
+			t.Fatal(err)
+		}
+		listed, err = s.tokens.ListSessions(c, email, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := sessionIDs(listed); !reflect.DeepEqual(ids, []int64{firefox.ID}) {
+			t.Errorf("ListSessions returned sessions %v after revoking the others, want [%d]", ids, firefox.ID)
+		}
+
+		// Only the extended session is left after the others expire, and revoked ones are deleted as well.
+		if err := s.tokens.DeleteExpiredTokens(c, now.Add(90*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+		for _, session := range []model.Session{chrome, safari} {
+			if _, err := s.tokens.GetSessionByToken(c, session.Token); !errors.Is(err, model.ErrInvalidToken) {
+				t.Errorf("GetSessionByToken of session %d returned %v after deleting expired tokens, want %v", session.ID, err, model.ErrInvalidToken)
+			}
+		}
+		if _, err := s.tokens.GetSessionByToken(c, firefox.Token); err != nil {
+			t.Errorf("GetSessionByToken of an extended session returned %v after deleting expired tokens", err)
+		}
+	})
+}
+
+func sessionIDs(ss []model.Session) []int64 {
+	var ids []int64
+	for _, s := range ss {
+		ids = append(ids, s.ID)
+	}
+	return ids
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mikolysz/enably/model"
+)
+
+const translationColumns = "id, product_id, locale, data, approved, created_at, submitted_by"
+
+// AddTranslation inserts a product translation into the database.
+// The returned translation will have the "id" and "created_at" fields filled in.
+func (s PostgresProductsStore) AddTranslation(c context.Context, t model.Translation) (model.Translation, error) {
+	query := "INSERT INTO product_translations(product_id, locale, data, submitted_by) VALUES($1, $2, $3, $4) RETURNING id, created_at"
+	row := s.db.QueryRow(c, query, t.ProductID, t.Locale, t.Data, t.SubmittedBy)
+	if err := row.Scan(&t.ID, &t.CreatedAt); err != nil {
+		return model.Translation{}, fmt.Errorf("error when inserting translation: %s", err)
+	}
+	return t, nil
+}
+
+// GetApprovedTranslations returns the approved translations of the given products into the given locale.
+// Translations are ordered from oldest to newest.
+func (s PostgresProductsStore) GetApprovedTranslations(c context.Context, productIDs []int, locale string) ([]model.Translation, error) {
+	query := "SELECT " + translationColumns + ` FROM product_translations
+		WHERE product_id = ANY($1) AND locale = $2 AND approved = true ORDER BY id`
+	return s.queryTranslations(c, query, productIDs, locale)
+}
+
+// GetTranslationsRequiringApproval returns all translations that need approval by the mod team.
+func (s PostgresProductsStore) GetTranslationsRequiringApproval(c context.Context) ([]model.Translation, error) {
+	query := "SELECT " + translationColumns + " FROM product_translations WHERE approved = false ORDER BY id"
+	return s.queryTranslations(c, query)
+}
+
+// GetTranslationByID returns the translation with the given ID, or model.ErrItemNotFound if there's none.
+func (s PostgresProductsStore) GetTranslationByID(c context.Context, id int) (model.Translation, error) {
+	query := "SELECT " + translationColumns + " FROM product_translations WHERE id = $1"
+	ts, err := s.queryTranslations(c, query, id)
+	if err != nil {
+		return model.Translation{}, err
+	}
+
+	if len(ts) == 0 {
+		return model.Translation{}, model.ErrItemNotFound
+	}
+	return ts[0], nil
+}
+
+func (s PostgresProductsStore) queryTranslations(c context.Context, query string, args ...any) ([]model.Translation, error) {
+	rows, err := s.db.Query(c, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error when querying translations: %s", err)
+	}
+	defer rows.Close()
+
+	var translations []model.Translation
+	for rows.Next() {
+		var t model.Translation
+		if err := rows.Scan(&t.ID, &t.ProductID, &t.Locale, &t.Data, &t.Approved, &t.CreatedAt, &t.SubmittedBy); err != nil {
+			return nil, fmt.Errorf("error when scanning translation: %s", err)
+		}
+		translations = append(translations, t)
+	}
+	return translations, rows.Err()
+}
+
+// ApproveTranslation approves the translation with the given ID, recording who did it.
+func (s PostgresProductsStore) ApproveTranslation(c context.Context, id int, by model.Moderator) error {
+	query := "UPDATE product_translations SET approved = true WHERE id = $1"
+	return s.moderate(c, query, model.NewModerationAction(model.ItemTranslation, id, model.ActionApprove, by))
+}
+
+// RejectTranslation rejects the translation with the given ID, recording who did it.
+func (s PostgresProductsStore) RejectTranslation(c context.Context, id int, by model.Moderator) error {
+	query := "DELETE FROM product_translations WHERE id = $1"
+	return s.moderate(c, query, model.NewModerationAction(model.ItemTranslation, id, model.ActionReject, by))
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/mikolysz/enably/model"
+)
+
+func TestTranslations(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s testStores) {
+		c := context.Background()
+		p := addTestProduct(t, s, "screen_readers", nil)
+		translator := addTestUser(t, s, "translator@example.com")
+
+		var added []model.Translation
+		for _, description := range []string{"Darmowy czytnik ekranu", "Czytnik ekranu"} {
+			tr, err := s.products.AddTranslation(c, model.Translation{
+				ProductID:   p.ID,
+				Locale:      "pl",
+				SubmittedBy: &translator.ID,
+				Data:        map[string]map[string]string{"software": {"description": description}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tr.ID == 0 || tr.Approved {
+				t.Fatalf("AddTranslation returned %+v, want an unapproved translation with an ID", tr)
+			}
+			added = append(added, tr)
+		}
+
+		waiting, err := s.products.GetTranslationsRequiringApproval(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := translationIDs(waiting); !reflect.DeepEqual(ids, []int{added[0].ID, added[1].ID}) {
+			t.Errorf("GetTranslationsRequiringApproval returned translations %v, want both", ids)
+		}
+
+		got, err := s.products.GetTranslationByID(c, added[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ProductID != p.ID || got.Locale != "pl" || got.SubmittedBy == nil || *got.SubmittedBy != translator.ID ||
+			!reflect.DeepEqual(got.Data, added[0].Data) {
+			t.Errorf("GetTranslationByID returned %+v, want %+v", got, added[0])
+		}
+
+		if err := s.products.ApproveTranslation(c, added[0].ID, model.Moderator{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.products.RejectTranslation(c, added[1].ID, model.Moderator{}); err != nil {
+			t.Fatal(err)
+		}
+
+		approved, err := s.products.GetApprovedTranslations(c, []int{p.ID}, "pl")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(approved) != 1 || approved[0].ID != added[0].ID || !reflect.DeepEqual(approved[0].Data, added[0].Data) {
+			t.Errorf("GetApprovedTranslations returned %+v, want only %+v", approved, added[0])
+		}
+
+		approved, err = s.products.GetApprovedTranslations(c, []int{p.ID}, "de")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(approved) != 0 {
+			t.Errorf("GetApprovedTranslations returned %+v for a locale without translations", approved)
+		}
+
+		waiting, err = s.products.GetTranslationsRequiringApproval(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(waiting) != 0 {
+			t.Errorf("GetTranslationsRequiringApproval returned %+v after all translations were moderated", waiting)
+		}
+
+		if _, err := s.products.GetTranslationByID(c, added[1].ID); !errors.Is(err, model.ErrItemNotFound) {
+			t.Errorf("GetTranslationByID of a rejected translation returned %v, want %v", err, model.ErrItemNotFound)
+		}
+	})
+}
+
+func translationIDs(ts []model.Translation) []int {
+	var ids []int
+	for _, t := range ts {
+		ids = append(ids, t.ID)
+	}
+	return ids
+}
@@ -0,0 +1,101 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mikolysz/enably/model"
+)
+
+// PostgresUsersStore lets you store and retrieve user accounts.
+type PostgresUsersStore struct {
+	DB *pgxpool.Pool
+}
+
+const userColumns = "id, email_address, display_name, role, email_consent, moderation_emails, created_at, onboarded_at"
+
+// GetOrCreateUser returns the user with the given email address, creating one if there's none.
+func (s PostgresUsersStore) GetOrCreateUser(c context.Context, email string) (model.User, error) {
+	// The no-op update makes RETURNING work for existing users too.
+	query := `INSERT INTO users(email_address) VALUES($1)
+		ON CONFLICT (email_address) DO UPDATE SET email_address = EXCLUDED.email_address
+		RETURNING ` + userColumns
+
+	u, err := scanUser(s.DB.QueryRow(c, query, email))
+	if err != nil {
+		return model.User{}, fmt.Errorf("error when inserting user: %s", err)
+	}
+	return u, nil
+}
+
+// GetUserByEmail returns the user with the given email address.
+// returns model.ErrUserNotFound if there's no such user.
+func (s PostgresUsersStore) GetUserByEmail(c context.Context, email string) (model.User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE email_address = $1"
+
+	u, err := scanUser(s.DB.QueryRow(c, query, email))
+	if err == pgx.ErrNoRows {
+		return model.User{}, model.ErrUserNotFound
+	}
+
+	if err != nil {
+		return model.User{}, fmt.Errorf("error when querying user: %s", err)
+	}
+	return u, nil
+}
+
+// GetUserByID returns the user with the given ID.
+// returns model.ErrUserNotFound if there's no such user.
+func (s PostgresUsersStore) GetUserByID(c context.Context, id int64) (model.User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE id = $1"
+
+	u, err := scanUser(s.DB.QueryRow(c, query, id))
+	if err == pgx.ErrNoRows {
+		return model.User{}, model.ErrUserNotFound
+	}
+
+	if err != nil {
+		return model.User{}, fmt.Errorf("error when querying user: %s", err)
+	}
+	return u, nil
+}
+
+// UpdateUser saves the profile of the given user.
+func (s PostgresUsersStore) UpdateUser(c context.Context, u model.User) (model.User, error) {
+	query := "UPDATE users SET display_name = $2, email_consent = $3, moderation_emails = $4, onboarded_at = $5 WHERE id = $1 RETURNING " + userColumns
+
+	u, err := scanUser(s.DB.QueryRow(c, query, u.ID, u.DisplayName, u.EmailConsent, u.ModerationEmails, u.OnboardedAt))
+	if err != nil {
+		return model.User{}, fmt.Errorf("error when updating user: %s", err)
+	}
+	return u, nil
+}
+
+// SetUserRole changes the role of the user with the given email address.
+// returns model.ErrUserNotFound if there's no such user.
+func (s PostgresUsersStore) SetUserRole(c context.Context, email string, role model.Role) (model.User, error) {
+	query := "UPDATE users SET role = $2 WHERE email_address = $1 RETURNING " + userColumns
+
+	u, err := scanUser(s.DB.QueryRow(c, query, email, role))
+	if err == pgx.ErrNoRows {
+		return model.User{}, model.ErrUserNotFound
+	}
+
+	if err != nil {
+		return model.User{}, fmt.Errorf("error when updating user role: %s", err)
+	}
+	return u, nil
+}
+
+// rowScanner is implemented by both pgx.Row and *sql.Row.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row rowScanner) (model.User, error) {
+	var u model.User
+	err := row.Scan(&u.ID, &u.Email, &u.DisplayName, &u.Role, &u.EmailConsent, &u.ModerationEmails, &u.CreatedAt, &u.OnboardedAt)
+	return u, err
+}
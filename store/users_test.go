@@ -0,0 +1,69 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mikolysz/enably/model"
+)
+
+func TestUsers(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s testStores) {
+		c := context.Background()
+
+		u := addTestUser(t, s, "user@example.com")
+		if u.ID == 0 || u.Role != model.RoleContributor || !u.ModerationEmails || u.OnboardedAt != nil {
+			t.Fatalf("GetOrCreateUser returned %+v, want a new contributor", u)
+		}
+		if again := addTestUser(t, s, "user@example.com"); again.ID != u.ID {
+			t.Errorf("GetOrCreateUser created user %d for an existing email address, want %d", again.ID, u.ID)
+		}
+
+		if _, err := s.users.GetUserByEmail(c, "nobody@example.com"); !errors.Is(err, model.ErrUserNotFound) {
+			t.Errorf("GetUserByEmail of a missing user returned %v, want %v", err, model.ErrUserNotFound)
+		}
+		if _, err := s.users.GetUserByID(c, u.ID+1000); !errors.Is(err, model.ErrUserNotFound) {
+			t.Errorf("GetUserByID of a missing user returned %v, want %v", err, model.ErrUserNotFound)
+		}
+
+		onboardedAt := time.Now().Truncate(time.Second)
+		u.DisplayName = "Ada"
+		u.EmailConsent = true
+		u.ModerationEmails = false
+		u.OnboardedAt = &onboardedAt
+		if _, err := s.users.UpdateUser(c, u); err != nil {
+			t.Fatal(err)
+		}
+		got, err := s.users.GetUserByID(c, u.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.DisplayName != "Ada" || !got.EmailConsent || got.ModerationEmails || got.OnboardedAt == nil || !got.OnboardedAt.Equal(onboardedAt) {
+			t.Errorf("GetUserByID returned %+v after UpdateUser, want the changes applied", got)
+		}
+
+		if _, err := s.users.SetUserRole(c, u.Email, model.RoleModerator); err != nil {
+			t.Fatal(err)
+		}
+		got, err = s.users.GetUserByEmail(c, u.Email)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Role != model.RoleModerator || got.DisplayName != "Ada" {
+			t.Errorf("GetUserByEmail returned %+v after SetUserRole, want a moderator named Ada", got)
+		}
+		if _, err := s.users.SetUserRole(c, "nobody@example.com", model.RoleModerator); !errors.Is(err, model.ErrUserNotFound) {
+			t.Errorf("SetUserRole of a missing user returned %v, want %v", err, model.ErrUserNotFound)
+		}
+	})
+}
+
+func addTestUser(t *testing.T, s testStores, email string) model.User {
+	u, err := s.users.GetOrCreateUser(context.Background(), email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}